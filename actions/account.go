@@ -0,0 +1,219 @@
+package actions
+
+import (
+	"archive/zip"
+	"blog/models"
+	"blog/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+)
+
+// DeleteAccountPayload - Request body to schedule the deletion of the own account
+type DeleteAccountPayload struct {
+	Password string `json:"password"`
+	Posts    string `json:"posts"`
+}
+
+// AccountDeletionResponse - When the account will be deleted
+type AccountDeletionResponse struct {
+	Code                string    `json:"code"`
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+	Posts               string    `json:"posts"`
+}
+
+// accountExport - every record owned by the user, written into the export archive
+type accountExport struct {
+	User                 models.User
+	Posts                models.Posts
+	PostRevisions        models.PostRevisions
+	PersonalAccessTokens models.PersonalAccessTokens
+	RefreshTokens        models.RefreshTokens
+	Sessions             models.UserSessions
+	Identities           models.UserIdentities
+	AuditEvents          models.AuditEvents
+}
+
+// ExportAccount - Download a ZIP archive with the profile, the posts and the other records of the authenticated user
+func ExportAccount(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	export := &accountExport{User: authUser}
+	if err := tx.Eager("Tags").Where("user_id = ?", authUser.ID).Order("created_at asc").All(&export.Posts); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	if err := tx.Where("user_id = ?", authUser.ID).Order("created_at asc").All(&export.PersonalAccessTokens); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	if err := tx.Where("user_id = ?", authUser.ID).Order("created_at asc").All(&export.RefreshTokens); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	if err := tx.Where("user_id = ?", authUser.ID).Order("created_at asc").All(&export.Sessions); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	if err := tx.Where("user_id = ?", authUser.ID).Order("created_at asc").All(&export.Identities); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	// the history of the posts of the user, whoever edited them
+	if err := tx.Where("post_id IN (SELECT id FROM posts WHERE user_id = ?)", authUser.ID).Order("post_id asc, number asc").All(&export.PostRevisions); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	// what the user did and what was done to the account
+	if err := tx.Where("(actor_id = ? OR (target_type = ? AND target_id = ?))", authUser.ID, "user", authUser.ID.String()).Order("created_at asc").All(&export.AuditEvents); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	fileName := fmt.Sprintf("account-export-%s.zip", time.Now().Format("20060102"))
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.Render(http.StatusOK, r.Func("application/zip", func(w io.Writer, d render.Data) error {
+		return export.write(w)
+	}))
+}
+
+// write - the profile and the records as JSON, every post as Markdown with front matter
+func (e *accountExport) write(w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	files := map[string]interface{}{
+		"profile.json":                e.User,
+		"post_revisions.json":         e.PostRevisions,
+		"personal_access_tokens.json": e.PersonalAccessTokens,
+		"refresh_tokens.json":         e.RefreshTokens,
+		"sessions.json":               e.Sessions,
+		"identities.json":             e.Identities,
+		"audit_events.json":           e.AuditEvents,
+	}
+	for name, records := range files {
+		file, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(records); err != nil {
+			return err
+		}
+	}
+
+	for _, post := range e.Posts {
+		file, err := archive.Create(fmt.Sprintf("posts/%s.md", post.ID))
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(file, postMarkdown(post)); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// postMarkdown - the post body preceded by YAML front matter, quoted strings stay valid YAML
+func postMarkdown(post models.Post) string {
+	return fmt.Sprintf(
+		"---\nid: %s\ntitle: %s\nstatus: %s\ntags: %s\npublished_at: %s\ncreated_at: %s\nupdated_at: %s\n---\n\n%s\n",
+		post.ID,
+		strconv.Quote(post.Title),
+		post.Status,
+		tagsFrontMatter(post),
+		publishedAtFrontMatter(post),
+		post.CreatedAt.Format(time.RFC3339),
+		post.UpdatedAt.Format(time.RFC3339),
+		post.Description,
+	)
+}
+
+// tagsFrontMatter - the tag names of the post as a YAML flow sequence
+func tagsFrontMatter(post models.Post) string {
+	names := make([]string, 0, len(post.Tags))
+	for _, name := range post.Tags.Names() {
+		names = append(names, strconv.Quote(name))
+	}
+
+	return "[" + strings.Join(names, ", ") + "]"
+}
+
+// publishedAtFrontMatter - the publication time of the post, null for drafts
+func publishedAtFrontMatter(post models.Post) string {
+	if !post.PublishedAt.Valid {
+		return "null"
+	}
+
+	return post.PublishedAt.Time.Format(time.RFC3339)
+}
+
+// DeleteAccount - Schedule the deletion of the authenticated user after the grace period of
+// ACCOUNT_DELETION_GRACE_PERIOD, the posts are either deleted or reassigned to a placeholder
+func DeleteAccount(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	request := &DeleteAccountPayload{}
+	c.Bind(request)
+	// the password of an account created through a provider is set through the password reset
+	if !authUser.HasPassword() {
+		return renderNoPasswordSet(c, "password")
+	}
+
+	verrs := validate.Validate(
+		&validators.StringIsPresent{Field: request.Password, Name: "password"},
+		&validators.StringInclusion{Field: request.Posts, Name: "posts", List: []string{models.PostsStrategyDelete, models.PostsStrategyReassign}},
+	)
+	if verrs.HasAny() {
+		errorResponse := utils.NewValidationErrorResponse(http.StatusUnprocessableEntity, verrs.Errors)
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	user := &authUser
+	passwordMatched, checkErr := user.CheckPassword(tx, request.Password)
+	if checkErr != nil {
+		return c.Error(http.StatusInternalServerError, checkErr)
+	}
+	if !passwordMatched {
+		errorResponse := utils.NewErrorResponse(http.StatusUnprocessableEntity, "password", "The password is incorrect")
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	gracePeriod := utils.DurationFromEnv("ACCOUNT_DELETION_GRACE_PERIOD", 7*24*time.Hour)
+	if err := user.ScheduleDeletion(tx, request.Posts, time.Now().Add(gracePeriod)); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusAccepted, r.JSON(AccountDeletionResponse{
+		Code:                fmt.Sprintf("%d", http.StatusAccepted),
+		DeletionScheduledAt: user.DeletionScheduledAt.Time,
+		Posts:               user.DeletionPostsStrategy,
+	}))
+}
+
+// CancelAccountDeletion - Keep the account of the authenticated user during the grace period
+func CancelAccountDeletion(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	if !authUser.IsDeletionScheduled() {
+		errorResponse := utils.NewErrorResponse(http.StatusUnprocessableEntity, "user", "The account is not scheduled for deletion")
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	if err := authUser.CancelDeletion(tx); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(MessageResponse{
+		Code:    fmt.Sprintf("%d", http.StatusOK),
+		Message: "The deletion of your account has been cancelled",
+	}))
+}
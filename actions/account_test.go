@@ -0,0 +1,109 @@
+package actions
+
+import (
+	"archive/zip"
+	"blog/models"
+	"blog/utils"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gofrs/uuid"
+)
+
+func (as *ActionSuite) Test_ExportAccount() {
+	user, accessToken := as.logInAs("export@example.com")
+	post := &models.Post{Title: "Exported", Description: "Body of the post", UserID: user.ID}
+	as.NoError(models.DB.Create(post))
+	_, err := models.CreateUserSession(models.DB, user.ID, uuid.Must(uuid.NewV4()), "export-agent", "10.0.0.1")
+	as.NoError(err)
+	as.NoError(models.RecordAuditEvent(models.DB, &models.AuditEvent{Action: models.AuditLoginSucceeded, ActorID: nulls.NewUUID(user.ID), TargetType: "user", TargetID: user.ID.String()}))
+
+	req := as.JSON("/api/v1/auth/user/export")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	res := req.Get()
+	as.Equal(http.StatusOK, res.Code)
+	as.Equal("application/zip", res.Header().Get("Content-Type"))
+
+	body := res.Body.Bytes()
+	var archive *zip.Reader
+	archive, err = zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	as.NoError(err)
+
+	files := map[string]string{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		as.NoError(err)
+		content, err := ioutil.ReadAll(reader)
+		as.NoError(err)
+		files[file.Name] = string(content)
+	}
+	as.Contains(files["profile.json"], "export@example.com")
+	as.Contains(files[fmt.Sprintf("posts/%s.md", post.ID)], "title: \"Exported\"")
+	as.Contains(files["sessions.json"], "export-agent")
+	as.Contains(files["audit_events.json"], models.AuditLoginSucceeded)
+	for _, name := range []string{"refresh_tokens.json", "identities.json", "post_revisions.json"} {
+		as.Contains(files, name)
+	}
+}
+
+// logInAsProviderUser - an account created through an identity provider, without a password
+func (as *ActionSuite) logInAsProviderUser(email string) (*models.User, string) {
+	user, err := models.FindOrCreateUserForIdentity(models.DB, "company", email, email, true, "Provider User")
+	as.NoError(err)
+
+	accessToken, err := utils.NewAccessToken(user.ID.String(), "", utils.AllScopes)
+	as.NoError(err)
+
+	return user, accessToken
+}
+
+func (as *ActionSuite) Test_DeleteAccount_ProviderAccount() {
+	user, accessToken := as.logInAsProviderUser("provider-delete@example.com")
+
+	req := as.JSON("/api/v1/auth/user")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	res, err := req.Do(http.MethodDelete, DeleteAccountPayload{Password: models.NoPassword, Posts: models.PostsStrategyReassign})
+	as.NoError(err)
+	as.Equal(http.StatusUnprocessableEntity, res.Code)
+	as.Contains(res.Body.String(), "/api/v1/auth/password/forgot")
+
+	// the password reset gives the account a password
+	plainToken, err := models.IssuePasswordResetToken(models.DB, user.ID, time.Hour)
+	as.NoError(err)
+	res = as.JSON("/api/v1/auth/password/reset").Post(ResetPasswordPayload{Token: plainToken, Password: "correct horse battery"})
+	as.Equal(http.StatusOK, res.Code)
+
+	as.NoError(models.DB.Reload(user))
+	as.True(user.HasPassword())
+	matched, err := user.CheckPassword(models.DB, "correct horse battery")
+	as.NoError(err)
+	as.True(matched)
+}
+
+func (as *ActionSuite) Test_DeleteAccount_Schedules() {
+	user, accessToken := as.logInAs("delete-me@example.com")
+
+	req := as.JSON("/api/v1/auth/user")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	res, err := req.Do(http.MethodDelete, DeleteAccountPayload{Password: "wrong", Posts: models.PostsStrategyReassign})
+	as.NoError(err)
+	as.Equal(http.StatusUnprocessableEntity, res.Code)
+
+	req = as.JSON("/api/v1/auth/user")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	res, err = req.Do(http.MethodDelete, DeleteAccountPayload{Password: "secret", Posts: models.PostsStrategyReassign})
+	as.NoError(err)
+	as.Equal(http.StatusAccepted, res.Code)
+
+	scheduled := &models.User{}
+	as.NoError(models.DB.Find(scheduled, user.ID))
+	as.True(scheduled.IsDeletionScheduled())
+
+	req = as.JSON("/api/v1/auth/user/deletion/cancel")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	as.Equal(http.StatusOK, req.Post(nil).Code)
+}
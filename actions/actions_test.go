@@ -1,8 +1,10 @@
 package actions
 
 import (
+	"io/ioutil"
 	"testing"
 
+	"github.com/gobuffalo/envy"
 	"github.com/gobuffalo/packr/v2"
 	"github.com/gobuffalo/suite"
 )
@@ -11,6 +13,23 @@ type ActionSuite struct {
 	*suite.Action
 }
 
+// SetupSuite - point JWT_KEY_PATH to a throwaway signing key
+// runs before the suite assertions exist, failures go through T directly
+func (as *ActionSuite) SetupSuite() {
+	keyFile, err := ioutil.TempFile("", "jwt-key")
+	if err != nil {
+		as.T().Fatal(err)
+	}
+	if _, err := keyFile.WriteString("action-suite-secret"); err != nil {
+		as.T().Fatal(err)
+	}
+	if err := keyFile.Close(); err != nil {
+		as.T().Fatal(err)
+	}
+
+	envy.Set("JWT_KEY_PATH", keyFile.Name())
+}
+
 func Test_ActionSuite(t *testing.T) {
 	action, err := suite.NewActionWithFixtures(App(), packr.New("Test_ActionSuite", "../fixtures"))
 	if err != nil {
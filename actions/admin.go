@@ -0,0 +1,159 @@
+package actions
+
+import (
+	"blog/models"
+	"blog/policies"
+	"blog/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+)
+
+// AssignRolePayload - Request body to change the role of a user
+type AssignRolePayload struct {
+	Role string `json:"role"`
+}
+
+// UsersResponse - Users collection response body
+type UsersResponse struct {
+	Code string        `json:"code"`
+	Data models.Users  `json:"data"`
+	Meta pop.Paginator `json:"meta"`
+}
+
+// UserResponse - Single user object response body
+type UserResponse struct {
+	Code string       `json:"code"`
+	Data *models.User `json:"data"`
+}
+
+// AdminListUsers - List every user with their role
+func AdminListUsers(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	if !policies.Can(authUser, policies.ActionRead, &models.User{}) {
+		return renderForbiddenRole(c)
+	}
+
+	users := &models.Users{}
+	query := tx.PaginateFromParams(c.Params())
+	if err := query.Order("created_at desc").All(users); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(UsersResponse{
+		Code: fmt.Sprintf("%d", http.StatusOK),
+		Data: *users,
+		Meta: *query.Paginator,
+	}))
+}
+
+// AdminAssignRole - Change the role of a user
+func AdminAssignRole(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		return renderUserNotFound(c)
+	}
+	if !policies.Can(authUser, policies.ActionAssignRole, user) {
+		return renderForbiddenRole(c)
+	}
+
+	request := &AssignRolePayload{}
+	c.Bind(request)
+	if !models.IsValidRole(request.Role) {
+		errorResponse := utils.NewErrorResponse(http.StatusUnprocessableEntity, "role", fmt.Sprintf("The role must be one of %v", models.Roles))
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+	// an admin demoting themselves could leave the blog without any admin
+	if user.ID == authUser.ID && request.Role != models.RoleAdmin {
+		errorResponse := utils.NewErrorResponse(http.StatusUnprocessableEntity, "role", "You can not change your own role")
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	if err := user.AssignRole(tx, request.Role); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(UserResponse{
+		Code: fmt.Sprintf("%d", http.StatusOK),
+		Data: user,
+	}))
+}
+
+// AdminDeleteUser - Remove a user together with their posts and tokens
+func AdminDeleteUser(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		return renderUserNotFound(c)
+	}
+	if !policies.Can(authUser, policies.ActionDelete, user) {
+		return renderForbiddenRole(c)
+	}
+	if user.ID == authUser.ID {
+		errorResponse := utils.NewErrorResponse(http.StatusUnprocessableEntity, "user_id", "You can not delete your own account here")
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	if err := tx.Destroy(user); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(UserResponse{
+		Code: fmt.Sprintf("%d", http.StatusOK),
+		Data: user,
+	}))
+}
+
+// AdminUnlockUser - Lift the log in lockout of a user and of the client IPs of their failures
+func AdminUnlockUser(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		return renderUserNotFound(c)
+	}
+	if !policies.Can(authUser, policies.ActionUnlock, user) {
+		return renderForbiddenRole(c)
+	}
+
+	// the IPs the account failed from stay locked otherwise, the lockout window covers their failures
+	ips, ipsErr := models.FailedLogInIPs(tx, user.ID, time.Now().Add(-loginGuard.LockoutDuration))
+	if ipsErr != nil {
+		return c.Error(http.StatusInternalServerError, ipsErr)
+	}
+	if err := loginGuard.Unlock(user.Email, ips...); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(MessageResponse{
+		Code:    fmt.Sprintf("%d", http.StatusOK),
+		Message: fmt.Sprintf("The account %s has been unlocked", user.Email),
+	}))
+}
+
+// renderUserNotFound - the user of the route does not exist
+func renderUserNotFound(c buffalo.Context) error {
+	notFoundResponse := utils.NewErrorResponse(
+		http.StatusNotFound,
+		"user_id",
+		fmt.Sprintf("The requested user %s does not exist.", c.Param("user_id")),
+	)
+	return c.Render(http.StatusNotFound, r.JSON(notFoundResponse))
+}
+
+// renderForbiddenRole - the role of the caller does not allow the action
+func renderForbiddenRole(c buffalo.Context) error {
+	errorResponse := utils.NewErrorResponse(http.StatusForbidden, "role", "Your role is not allowed to perform this action")
+	return c.Render(http.StatusForbidden, r.JSON(errorResponse))
+}
@@ -0,0 +1,47 @@
+package actions
+
+import (
+	"blog/models"
+	"fmt"
+	"net/http"
+)
+
+func (as *ActionSuite) Test_AdminAssignRole() {
+	admin, adminToken := as.logInAs("admin@example.com")
+	as.NoError(admin.AssignRole(models.DB, models.RoleAdmin))
+	author, authorToken := as.logInAs("author@example.com")
+
+	req := as.JSON("/api/v1/admin/users/%s/role", author.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	res := req.Put(AssignRolePayload{Role: models.RoleAdmin})
+	as.Equal(http.StatusForbidden, res.Code)
+
+	req = as.JSON("/api/v1/admin/users/%s/role", author.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", adminToken)
+	res = req.Put(AssignRolePayload{Role: models.RoleEditor})
+	as.Equal(http.StatusOK, res.Code)
+
+	updated := &models.User{}
+	as.NoError(models.DB.Find(updated, author.ID))
+	as.Equal(models.RoleEditor, updated.Role)
+}
+
+func (as *ActionSuite) Test_AdminDeleteUser() {
+	admin, adminToken := as.logInAs("admin-delete@example.com")
+	as.NoError(admin.AssignRole(models.DB, models.RoleAdmin))
+	author, _ := as.logInAs("deleted@example.com")
+
+	req := as.JSON("/api/v1/admin/users/%s", admin.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", adminToken)
+	res := req.Delete()
+	as.Equal(http.StatusUnprocessableEntity, res.Code)
+
+	req = as.JSON("/api/v1/admin/users/%s", author.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", adminToken)
+	res = req.Delete()
+	as.Equal(http.StatusOK, res.Code)
+
+	count, err := models.DB.Where("id = ?", author.ID).Count(&models.User{})
+	as.NoError(err)
+	as.Equal(0, count)
+}
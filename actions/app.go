@@ -3,6 +3,7 @@ package actions
 import (
 	"blog/middleware"
 	"blog/models"
+	"blog/utils"
 
 	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/buffalo-pop/v2/pop/popmw"
@@ -44,13 +45,21 @@ func App() *buffalo.App {
 		// Automatically redirect to SSL
 		app.Use(forceSSL())
 
-		// Log request parameters (filters apply).
+		// Log request parameters (filters apply), the OAuth and log in secrets are filtered as well.
+		paramlogger.ParameterExclusionList = append(
+			paramlogger.ParameterExclusionList,
+			"client_secret", "code", "code_verifier", "refresh_token", "token",
+			"current_password", "mfa_token", "recovery_code",
+		)
 		app.Use(paramlogger.ParameterLogger)
 
 		// Protect against CSRF attacks. https://www.owasp.org/index.php/Cross-Site_Request_Forgery_(CSRF)
 		// Remove to disable this.
 		// app.Use(csrf.New)
 
+		// Starts the background jobs of a request once its transaction committed.
+		app.Use(BackgroundJobs)
+
 		// Wraps each request in a transaction.
 		//  c.Value("tx").(*pop.Connection)
 		// Remove to disable this.
@@ -60,6 +69,14 @@ func App() *buffalo.App {
 		app.Use(translations())
 
 		app.GET("/", HomeHandler)
+		app.GET("/.well-known/jwks.json", JWKSHandler)
+
+		// OAuth authorization server for third party apps
+		app.GET("/oauth/authorize", OAuthAuthorize)
+		app.POST("/oauth/authorize", OAuthConsent)
+		app.POST("/oauth/token", OAuthToken)
+		app.POST("/oauth/introspect", OAuthIntrospect)
+		app.POST("/oauth/revoke", OAuthRevoke)
 
 		api := app.Group("/api")
 
@@ -67,16 +84,84 @@ func App() *buffalo.App {
 
 		apiv1Post := apiv1.Group("/posts")
 		apiv1Post.Use(middleware.JWTMiddleware)
-		apiv1Post.GET("/", ListPost)
-		apiv1Post.POST("/create", CreatePost)
-		apiv1Post.GET("/{post_id}", ShowPost).Name("showPost")
-		apiv1Post.PUT("/{post_id}", middleware.PostGuardMiddleware(UpdatePost)).Name("updatePost")
-		apiv1Post.DELETE("/{post_id}", middleware.PostGuardMiddleware(DeletePost))
+		readPosts := middleware.ScopeMiddleware(utils.ScopePostsRead)
+		writePosts := middleware.ScopeMiddleware(utils.ScopePostsWrite)
+		apiv1Post.GET("/", readPosts(ListPost))
+		// registered before /{post_id}, which would match it as well
+		apiv1Post.GET("/trash", writePosts(ListTrashedPosts))
+		apiv1Post.POST("/create", writePosts(middleware.VerifiedEmailMiddleware(CreatePost)))
+		apiv1Post.GET("/{post_id}", readPosts(ShowPost)).Name("showPost")
+		apiv1Post.PUT("/{post_id}", writePosts(middleware.PostGuardMiddleware(UpdatePost))).Name("updatePost")
+		apiv1Post.DELETE("/{post_id}", writePosts(middleware.PostGuardMiddleware(DeletePost)))
+		apiv1Post.POST("/{post_id}/publish", writePosts(PublishPost))
+		apiv1Post.POST("/{post_id}/unpublish", writePosts(UnpublishPost))
+		apiv1Post.POST("/{post_id}/restore", writePosts(RestorePost))
+		apiv1Post.GET("/{post_id}/revisions", readPosts(ListPostRevisions))
+		apiv1Post.GET("/{post_id}/revisions/diff", readPosts(DiffPostRevisions))
+		apiv1Post.POST("/{post_id}/revisions/{revision}/restore", writePosts(RestorePostRevision))
+
+		apiv1Tag := apiv1.Group("/tags")
+		apiv1Tag.Use(middleware.JWTMiddleware)
+		apiv1Tag.GET("/", readPosts(ListTags))
+		apiv1Tag.POST("/", writePosts(CreateTag))
+		apiv1Tag.PUT("/{tag_id}", writePosts(UpdateTag))
+		apiv1Tag.DELETE("/{tag_id}", writePosts(DeleteTag))
+		apiv1Tag.POST("/{tag_id}/merge", writePosts(MergeTag))
+
+		apiv1Category := apiv1.Group("/categories")
+		apiv1Category.Use(middleware.JWTMiddleware)
+		apiv1Category.GET("/", readPosts(ListCategories))
+		apiv1Category.POST("/", writePosts(CreateCategory))
+		apiv1Category.PUT("/{category_id}", writePosts(UpdateCategory))
+		apiv1Category.DELETE("/{category_id}", writePosts(DeleteCategory))
+
+		apiv1Admin := apiv1.Group("/admin")
+		apiv1Admin.Use(middleware.JWTMiddleware)
+		apiv1Admin.Use(middleware.ScopeMiddleware(utils.ScopeAccountAdmin))
+		apiv1Admin.GET("/users", AdminListUsers)
+		apiv1Admin.PUT("/users/{user_id}/role", AdminAssignRole)
+		apiv1Admin.DELETE("/users/{user_id}", AdminDeleteUser)
+		apiv1Admin.DELETE("/users/{user_id}/lockout", AdminUnlockUser)
+		apiv1Admin.GET("/audit", AdminListAuditEvents)
+
+		apiv1OAuth := apiv1.Group("/oauth")
+		apiv1OAuth.Use(middleware.JWTMiddleware)
+		apiv1OAuth.Use(middleware.ScopeMiddleware(utils.ScopeAccountAdmin))
+		apiv1OAuth.GET("/clients", ListOAuthClients)
+		apiv1OAuth.POST("/clients", CreateOAuthClient)
+		apiv1OAuth.DELETE("/clients/{client_id}", RevokeOAuthClient)
 
 		apiv1Auth := apiv1.Group("/auth")
+		adminAccount := middleware.ScopeMiddleware(utils.ScopeAccountAdmin)
 		apiv1Auth.POST("/login", JwtAuthLogIn)
 		apiv1Auth.POST("/register", RegisterUser)
+		apiv1Auth.POST("/refresh", RefreshAccessToken)
+		apiv1Auth.POST("/magic-link", RequestMagicLink)
+		apiv1Auth.POST("/magic-link/redeem", RedeemMagicLink)
+		apiv1Auth.GET("/oidc/{provider}/authorize", OIDCAuthorize)
+		apiv1Auth.GET("/oidc/{provider}/callback", OIDCCallback)
+		apiv1Auth.POST("/logout", middleware.JWTMiddleware(JwtAuthLogOut))
+		apiv1Auth.POST("/logout-all", middleware.JWTMiddleware(adminAccount(JwtAuthLogOutAll)))
 		apiv1Auth.GET("/user", middleware.JWTMiddleware(GetUser))
+		apiv1Auth.PATCH("/user", middleware.JWTMiddleware(adminAccount(UpdateProfile)))
+		apiv1Auth.PUT("/user/password", middleware.JWTMiddleware(adminAccount(ChangePassword)))
+		apiv1Auth.GET("/user/export", middleware.JWTMiddleware(adminAccount(ExportAccount)))
+		apiv1Auth.DELETE("/user", middleware.JWTMiddleware(adminAccount(DeleteAccount)))
+		apiv1Auth.POST("/user/deletion/cancel", middleware.JWTMiddleware(adminAccount(CancelAccountDeletion)))
+		apiv1Auth.POST("/password/forgot", ForgotPassword)
+		apiv1Auth.POST("/password/reset", ResetPassword)
+		apiv1Auth.GET("/email/verify", VerifyEmail)
+		apiv1Auth.POST("/email/resend", middleware.JWTMiddleware(ResendEmailVerification))
+		apiv1Auth.POST("/mfa/enroll", middleware.JWTMiddleware(adminAccount(EnrollMFA)))
+		apiv1Auth.GET("/mfa/enroll/qr.png", middleware.JWTMiddleware(adminAccount(MFAQRCode)))
+		apiv1Auth.POST("/mfa/confirm", middleware.JWTMiddleware(adminAccount(ConfirmMFA)))
+		apiv1Auth.POST("/mfa/disable", middleware.JWTMiddleware(adminAccount(DisableMFA)))
+		apiv1Auth.POST("/mfa/verify", VerifyMFA)
+		apiv1Auth.GET("/sessions", middleware.JWTMiddleware(adminAccount(ListSessions)))
+		apiv1Auth.DELETE("/sessions/{session_id}", middleware.JWTMiddleware(adminAccount(RevokeSession)))
+		apiv1Auth.GET("/tokens", middleware.JWTMiddleware(adminAccount(ListPersonalAccessTokens)))
+		apiv1Auth.POST("/tokens", middleware.JWTMiddleware(adminAccount(CreatePersonalAccessToken)))
+		apiv1Auth.DELETE("/tokens/{token_id}", middleware.JWTMiddleware(adminAccount(RevokePersonalAccessToken)))
 		app.ServeFiles("/", assetsBox) // serve files from the public directory
 	}
 
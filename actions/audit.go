@@ -0,0 +1,105 @@
+package actions
+
+import (
+	"blog/models"
+	"blog/policies"
+	"blog/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+)
+
+// AuditEventsResponse - Audit events collection response body
+type AuditEventsResponse struct {
+	Code string             `json:"code"`
+	Data models.AuditEvents `json:"data"`
+	Meta pop.Paginator      `json:"meta"`
+}
+
+// AdminListAuditEvents - List the audit trail, newest first, filtered by action, actor_id,
+// target_type, target_id, ip and a since / until range in RFC 3339
+func AdminListAuditEvents(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	if !policies.Can(authUser, policies.ActionRead, &models.AuditEvent{}) {
+		return renderForbiddenRole(c)
+	}
+
+	filter := models.AuditFilter{
+		Action:     c.Param("action"),
+		ActorID:    c.Param("actor_id"),
+		TargetType: c.Param("target_type"),
+		TargetID:   c.Param("target_id"),
+		IPAddress:  c.Param("ip"),
+	}
+	for field, bound := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if c.Param(field) == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, c.Param(field))
+		if err != nil {
+			errorResponse := utils.NewErrorResponse(http.StatusUnprocessableEntity, field, fmt.Sprintf("The %s must be a RFC 3339 time", field))
+			return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+		}
+		*bound = parsed
+	}
+
+	events := &models.AuditEvents{}
+	query := filter.Apply(tx.PaginateFromParams(c.Params()))
+	if err := query.Order("created_at desc, id desc").All(events); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(AuditEventsResponse{
+		Code: fmt.Sprintf("%d", http.StatusOK),
+		Data: *events,
+		Meta: *query.Paginator,
+	}))
+}
+
+// recordAudit - append the event with the client of the request, the actor defaults to the
+// authenticated user. Pass the request transaction for events of a change that must roll back
+// together, and models.DB for events that have to outlive a failure response.
+func recordAudit(c buffalo.Context, conn *pop.Connection, event *models.AuditEvent) error {
+	if !event.ActorID.Valid {
+		if authUser, ok := c.Value("authUser").(models.User); ok {
+			event.ActorID = nulls.NewUUID(authUser.ID)
+		}
+	}
+	event.IPAddress = clientIP(c)
+	event.UserAgent = c.Request().UserAgent()
+
+	return models.RecordAuditEvent(conn, event)
+}
+
+// recordLoginSuccess - a user received tokens, method tells which credentials they used
+func recordLoginSuccess(c buffalo.Context, tx *pop.Connection, user models.User, session *models.UserSession, method string) error {
+	return recordAudit(c, tx, &models.AuditEvent{
+		Action:     models.AuditLoginSucceeded,
+		ActorID:    nulls.NewUUID(user.ID),
+		TargetType: "user",
+		TargetID:   user.ID.String(),
+		Metadata:   models.AuditMetadata{"method": method, "session_id": session.ID.String()},
+	})
+}
+
+// recordLoginFailure - a log in was rejected, written outside the request transaction which
+// rolls back with the error response. The user is empty when the email is unknown.
+func recordLoginFailure(c buffalo.Context, email string, user *models.User, reason string) error {
+	event := &models.AuditEvent{
+		Action:     models.AuditLoginFailed,
+		TargetType: "user",
+		Metadata:   models.AuditMetadata{"email": email, "reason": reason},
+	}
+	if user != nil && user.ID != uuid.Nil {
+		event.TargetID = user.ID.String()
+	}
+
+	return recordAudit(c, models.DB, event)
+}
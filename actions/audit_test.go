@@ -0,0 +1,87 @@
+package actions
+
+import (
+	"blog/lockout"
+	"blog/models"
+	"blog/utils"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+func (as *ActionSuite) Test_AuditTrail_LogIn() {
+	// a guard without a delay, the failed attempt must not throttle the next one
+	previousGuard := loginGuard
+	defer func() { loginGuard = previousGuard }()
+	loginGuard = &lockout.Guard{Store: lockout.NewMemoryStore(), Clock: utils.FixedClock{At: time.Now()}, MaxAttempts: 5, IPMaxAttempts: 10, LockoutDuration: time.Minute}
+
+	user, _ := as.logInAs("audited@example.com")
+
+	res := as.JSON("/api/v1/auth/login").Post(LogInPayload{Email: "audited@example.com", Password: "wrong"})
+	as.Equal(http.StatusUnprocessableEntity, res.Code)
+	res = as.JSON("/api/v1/auth/login").Post(LogInPayload{Email: "audited@example.com", Password: "secret"})
+	as.Equal(http.StatusOK, res.Code)
+
+	// the failure survives the rollback of the request transaction
+	failed := &models.AuditEvent{}
+	as.NoError(models.DB.Where("action = ? AND target_id = ?", models.AuditLoginFailed, user.ID.String()).First(failed))
+	as.Equal("invalid_credentials", failed.Metadata["reason"])
+	as.Equal("audited@example.com", failed.Metadata["email"])
+
+	succeeded := &models.AuditEvent{}
+	as.NoError(models.DB.Where("action = ? AND actor_id = ?", models.AuditLoginSucceeded, user.ID).First(succeeded))
+	as.Equal("password", succeeded.Metadata["method"])
+}
+
+func (as *ActionSuite) Test_AuditTrail_Posts() {
+	user, token := as.logInAs("audit-author@example.com")
+	as.NoError(user.MarkEmailVerified(models.DB))
+
+	req := as.JSON("/api/v1/posts/create")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", token)
+	res := req.Post(map[string]string{"title": "Audited", "description": "First version"})
+	as.Equal(http.StatusCreated, res.Code)
+
+	post := &models.Post{}
+	as.NoError(models.DB.Where("user_id = ?", user.ID).First(post))
+
+	req = as.JSON("/api/v1/posts/%s", post.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", token)
+	res = req.Put(map[string]string{"title": "Audited", "description": "Second version"})
+	as.Equal(http.StatusOK, res.Code)
+
+	updated := &models.AuditEvent{}
+	as.NoError(models.DB.Where("action = ? AND target_id = ?", models.AuditPostUpdated, post.ID.String()).First(updated))
+	as.Equal(user.ID, updated.ActorID.UUID)
+	as.Len(updated.Changes, 1)
+	as.Equal("First version", updated.Changes["description"].Before)
+	as.Equal("Second version", updated.Changes["description"].After)
+}
+
+func (as *ActionSuite) Test_AdminListAuditEvents() {
+	admin, adminToken := as.logInAs("audit-admin@example.com")
+	as.NoError(admin.AssignRole(models.DB, models.RoleAdmin))
+	author, authorToken := as.logInAs("audit-reader@example.com")
+	res := as.JSON("/api/v1/auth/login").Post(LogInPayload{Email: "audit-reader@example.com", Password: "secret"})
+	as.Equal(http.StatusOK, res.Code)
+
+	req := as.JSON("/api/v1/admin/audit")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	as.Equal(http.StatusForbidden, req.Get().Code)
+
+	query := url.Values{"action": {models.AuditLoginSucceeded}, "actor_id": {author.ID.String()}}
+	req = as.JSON("/api/v1/admin/audit?%s", query.Encode())
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", adminToken)
+	res = req.Get()
+	as.Equal(http.StatusOK, res.Code)
+
+	response := &AuditEventsResponse{}
+	res.Bind(response)
+	as.Len(response.Data, 1)
+	as.Equal(author.ID, response.Data[0].ActorID.UUID)
+
+	req = as.JSON("/api/v1/admin/audit?since=yesterday")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", adminToken)
+	as.Equal(http.StatusUnprocessableEntity, req.Get().Code)
+}
@@ -0,0 +1,59 @@
+package actions
+
+import (
+	"sync"
+
+	"github.com/gobuffalo/buffalo"
+)
+
+// backgroundQueueKey - context key of the jobs the request queued with runInBackground
+const backgroundQueueKey = "backgroundQueue"
+
+// backgroundJobs - the jobs started by runInBackground that are still running, tests wait on it
+var backgroundJobs sync.WaitGroup
+
+// BackgroundJobs - Start the jobs queued by the request once it succeeded, use it before the
+// transaction middleware so the request transaction is committed by then. The jobs of a request
+// that failed or rolled back are dropped.
+func BackgroundJobs(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		queue := &[]func(){}
+		c.Set(backgroundQueueKey, queue)
+
+		if err := next(c); err != nil {
+			return err
+		}
+		if res, ok := c.Response().(*buffalo.Response); ok && (res.Status < 200 || res.Status >= 400) {
+			return nil
+		}
+		for _, start := range *queue {
+			start()
+		}
+
+		return nil
+	}
+}
+
+// runInBackground - Run the job once the request transaction committed, see BackgroundJobs. The
+// job must not use the request transaction nor the context, it works on models.DB so its writes
+// commit on their own. Failures are logged with the logger of the request that queued the job.
+func runInBackground(c buffalo.Context, failure string, job func() error) {
+	logger := c.Logger()
+	start := func() {
+		backgroundJobs.Add(1)
+		go func() {
+			defer backgroundJobs.Done()
+			if err := job(); err != nil {
+				logger.Errorf("%s: %v", failure, err)
+			}
+		}()
+	}
+
+	// outside of a request there is no transaction to wait for
+	queue, ok := c.Value(backgroundQueueKey).(*[]func())
+	if !ok {
+		start()
+		return
+	}
+	*queue = append(*queue, start)
+}
@@ -0,0 +1,37 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+
+	"github.com/gobuffalo/buffalo"
+)
+
+func (as *ActionSuite) Test_BackgroundJobs_StartAfterSuccess() {
+	var ran int32
+	queueJob := func(status int) buffalo.Handler {
+		return func(c buffalo.Context) error {
+			runInBackground(c, "test job failed", func() error {
+				atomic.AddInt32(&ran, 1)
+				return nil
+			})
+			// nothing runs before the handler returned
+			as.Equal(int32(0), atomic.LoadInt32(&ran))
+			return c.Render(status, r.String("done"))
+		}
+	}
+
+	jobsApp := buffalo.New(buffalo.Options{})
+	jobsApp.Use(BackgroundJobs)
+	jobsApp.GET("/succeeds", queueJob(http.StatusOK))
+	jobsApp.GET("/fails", queueJob(http.StatusUnprocessableEntity))
+
+	jobsApp.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fails", nil))
+	backgroundJobs.Wait()
+	as.Equal(int32(0), atomic.LoadInt32(&ran))
+
+	jobsApp.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/succeeds", nil))
+	backgroundJobs.Wait()
+	as.Equal(int32(1), atomic.LoadInt32(&ran))
+}
@@ -0,0 +1,138 @@
+package actions
+
+import (
+	"blog/models"
+	"blog/policies"
+	"blog/utils"
+	"fmt"
+	"net/http"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+)
+
+// CategoryPayload - Request body to create or update a category, a missing parent_id makes it a root
+type CategoryPayload struct {
+	Name     string     `json:"name"`
+	ParentID nulls.UUID `json:"parent_id"`
+}
+
+// CategoriesResponse - The category tree response body
+type CategoriesResponse struct {
+	Code string            `json:"code"`
+	Data models.Categories `json:"data"`
+}
+
+// CategoryResponse - Single category response body
+type CategoryResponse struct {
+	Code string           `json:"code"`
+	Data *models.Category `json:"data"`
+}
+
+// ListCategories - List the categories as a tree, siblings by name
+func ListCategories(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	categories := models.Categories{}
+	if err := tx.Order("name asc").All(&categories); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(CategoriesResponse{
+		Code: fmt.Sprintf("%d", http.StatusOK),
+		Data: models.CategoryTree(categories),
+	}))
+}
+
+// CreateCategory - Create a category, below the parent_id when one is given
+func CreateCategory(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	category := &models.Category{}
+	if !policies.Can(authUser, policies.ActionCreate, category) {
+		return renderForbiddenRole(c)
+	}
+
+	return saveCategory(c, tx, category, http.StatusCreated)
+}
+
+// UpdateCategory - Rename a category or move it below another parent
+func UpdateCategory(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	category, findErr := findManagedCategory(c, tx, policies.ActionUpdate)
+	if category == nil {
+		return findErr
+	}
+
+	return saveCategory(c, tx, category, http.StatusOK)
+}
+
+// DeleteCategory - Delete a category, its subcategories and posts move up to its parent
+func DeleteCategory(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	category, findErr := findManagedCategory(c, tx, policies.ActionDelete)
+	if category == nil {
+		return findErr
+	}
+
+	if err := models.DeleteCategory(tx, category); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(MessageResponse{
+		Code:    fmt.Sprintf("%d", http.StatusOK),
+		Message: "The category has been deleted",
+	}))
+}
+
+// saveCategory - bind the payload into the category, validate and save it
+func saveCategory(c buffalo.Context, tx *pop.Connection, category *models.Category, status int) error {
+	request := &CategoryPayload{}
+	if err := c.Bind(request); err != nil {
+		errorResponse := utils.NewErrorResponse(http.StatusBadRequest, "name", "The request body is invalid")
+		return c.Render(http.StatusBadRequest, r.JSON(errorResponse))
+	}
+
+	category.Name, category.ParentID = request.Name, request.ParentID
+	verrs, err := tx.ValidateAndSave(category)
+	if err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	if verrs.HasAny() {
+		errorResponse := utils.NewValidationErrorResponse(http.StatusUnprocessableEntity, verrs.Errors)
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	return c.Render(status, r.JSON(CategoryResponse{
+		Code: fmt.Sprintf("%d", status),
+		Data: category,
+	}))
+}
+
+// findManagedCategory - the category of the route when the caller may perform the action on it,
+// otherwise the error response is returned
+func findManagedCategory(c buffalo.Context, tx *pop.Connection, action string) (*models.Category, error) {
+	authUser := c.Value("authUser").(models.User)
+
+	category, err := models.FindCategoryByIDOrSlug(tx, c.Param("category_id"))
+	if err != nil {
+		return nil, renderCategoryNotFound(c, "category_id", c.Param("category_id"))
+	}
+	if !policies.Can(authUser, action, category) {
+		return nil, renderForbiddenRole(c)
+	}
+
+	return category, nil
+}
+
+// renderCategoryNotFound - respond that the category given by the param does not exist
+func renderCategoryNotFound(c buffalo.Context, param string, idOrSlug string) error {
+	notFoundResponse := utils.NewErrorResponse(
+		http.StatusNotFound,
+		param,
+		fmt.Sprintf("The requested category %s does not exist.", idOrSlug),
+	)
+	return c.Render(http.StatusNotFound, r.JSON(notFoundResponse))
+}
@@ -0,0 +1,61 @@
+package actions
+
+import (
+	"blog/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gobuffalo/nulls"
+)
+
+func (as *ActionSuite) Test_Categories_FilterIncludesSubcategories() {
+	author, authorToken := as.logInAs("categorizing@example.com")
+	editor, editorToken := as.logInAs("category-editor@example.com")
+	as.NoError(editor.AssignRole(models.DB, models.RoleEditor))
+
+	req := as.JSON("/api/v1/categories/")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	as.Equal(http.StatusForbidden, req.Post(CategoryPayload{Name: "Travel"}).Code)
+
+	req = as.JSON("/api/v1/categories/")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", editorToken)
+	res := req.Post(CategoryPayload{Name: "Travel"})
+	as.Equal(http.StatusCreated, res.Code)
+	travel := CategoryResponse{}
+	as.NoError(json.Unmarshal(res.Body.Bytes(), &travel))
+
+	req = as.JSON("/api/v1/categories/")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", editorToken)
+	res = req.Post(CategoryPayload{Name: "Asia", ParentID: nulls.NewUUID(travel.Data.ID)})
+	as.Equal(http.StatusCreated, res.Code)
+	asia := CategoryResponse{}
+	as.NoError(json.Unmarshal(res.Body.Bytes(), &asia))
+
+	post := &models.Post{Title: "Tokyo", Description: "Body", UserID: author.ID, Status: models.PostStatusPublished, CategoryID: nulls.NewUUID(asia.Data.ID)}
+	as.NoError(models.DB.Create(post))
+	other := &models.Post{Title: "Elsewhere", Description: "Body", UserID: author.ID, Status: models.PostStatusPublished}
+	as.NoError(models.DB.Create(other))
+
+	req = as.JSON("/api/v1/posts/?category=travel")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	res = req.Get()
+	as.Equal(http.StatusOK, res.Code)
+	listed := PostsResponse{}
+	as.NoError(json.Unmarshal(res.Body.Bytes(), &listed))
+	as.Len(listed.Data, 1)
+	as.Equal(post.ID, listed.Data[0].ID)
+
+	req = as.JSON("/api/v1/posts/?category=unknown")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	as.Equal(http.StatusNotFound, req.Get().Code)
+
+	req = as.JSON("/api/v1/categories/")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	res = req.Get()
+	as.Equal(http.StatusOK, res.Code)
+	tree := CategoriesResponse{}
+	as.NoError(json.Unmarshal(res.Body.Bytes(), &tree))
+	as.Len(tree.Data, 1)
+	as.Equal("Asia", tree.Data[0].Children[0].Name)
+}
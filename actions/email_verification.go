@@ -0,0 +1,114 @@
+package actions
+
+import (
+	"blog/mailers"
+	"blog/models"
+	"blog/utils"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+)
+
+// emailVerificationPurpose - audience of the signed verification link
+const emailVerificationPurpose = "email_verification"
+
+// VerifyEmail - Mark the email address as verified with the signed link sent at registration
+func VerifyEmail(c buffalo.Context) error {
+	invalidResponse := utils.NewErrorResponse(http.StatusUnprocessableEntity, "token", "The verification link is invalid or has expired")
+
+	claims, parseErr := utils.ParsePurposeToken(c.Param("token"), emailVerificationPurpose)
+	if parseErr != nil {
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(invalidResponse))
+	}
+
+	tx := c.Value("tx").(*pop.Connection)
+	user := &models.User{}
+	// the link is bound to the address it was sent to
+	if findErr := tx.Find(user, claims.Subject); findErr != nil || user.Email != claims.Email {
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(invalidResponse))
+	}
+
+	if err := user.MarkEmailVerified(tx); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(UserAuthResponse{
+		Code: fmt.Sprintf("%d", http.StatusOK),
+		Data: *user,
+	}))
+}
+
+// ResendEmailVerification - Send the verification link again, throttled per user
+func ResendEmailVerification(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	if authUser.IsEmailVerified() {
+		errorResponse := utils.NewErrorResponse(http.StatusUnprocessableEntity, "email", "The email address is already verified")
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	interval := utils.DurationFromEnv("EMAIL_VERIFICATION_RESEND_INTERVAL", time.Minute)
+	if retryIn := authUser.EmailVerificationRetryIn(interval, time.Now()); retryIn > 0 {
+		seconds := int(math.Ceil(retryIn.Seconds()))
+		c.Response().Header().Set("Retry-After", fmt.Sprintf("%d", seconds))
+		errorResponse := utils.NewErrorResponse(http.StatusTooManyRequests, "email", fmt.Sprintf("Please wait %d seconds before asking for another verification mail", seconds))
+		return c.Render(http.StatusTooManyRequests, r.JSON(errorResponse))
+	}
+
+	if err := sendEmailVerification(c, tx, &authUser); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusAccepted, r.JSON(MessageResponse{
+		Code:    fmt.Sprintf("%d", http.StatusAccepted),
+		Message: "A new verification link has been sent to your email address",
+	}))
+}
+
+// sendEmailVerification - Sign a verification link for the current email of the user and mail it
+// once the request committed
+func sendEmailVerification(c buffalo.Context, tx *pop.Connection, user *models.User) error {
+	ttl := utils.DurationFromEnv("EMAIL_VERIFICATION_TTL", 48*time.Hour)
+	token, signErr := utils.NewPurposeToken(emailVerificationPurpose, user.ID.String(), user.Email, ttl)
+	if signErr != nil {
+		return signErr
+	}
+
+	if err := user.MarkEmailVerificationSent(tx); err != nil {
+		return err
+	}
+
+	verificationURL := fmt.Sprintf(
+		"%s?token=%s",
+		envy.Get("EMAIL_VERIFICATION_URL", "http://127.0.0.1:3000/api/v1/auth/email/verify"),
+		url.QueryEscape(token),
+	)
+	userID, email := user.ID, user.Email
+	runInBackground(c, "unable to send the verification mail", func() error {
+		return sendEmailVerificationMail(userID, email, verificationURL)
+	})
+
+	return nil
+}
+
+// sendEmailVerificationMail - Mail the verification link to the committed account, nothing is sent
+// when the address changed again in the meantime
+func sendEmailVerificationMail(userID uuid.UUID, email string, verificationURL string) error {
+	user := &models.User{}
+	if err := models.DB.Find(user, userID); err != nil {
+		return err
+	}
+	if user.Email != email {
+		return nil
+	}
+
+	return mailers.SendEmailVerification(*user, verificationURL)
+}
@@ -0,0 +1,104 @@
+package actions
+
+import (
+	"blog/mailers"
+	"blog/models"
+	"blog/utils"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/buffalo/mail"
+)
+
+// capturingMailer - keeps the sent messages instead of delivering them
+type capturingMailer struct {
+	mutex    sync.Mutex
+	messages []mail.Message
+}
+
+func (m *capturingMailer) Send(message mail.Message) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.messages = append(m.messages, message)
+
+	return nil
+}
+
+// captureMails - deliver the mails of the test to a capturingMailer, the returned func restores
+// the configured mailer
+func (as *ActionSuite) captureMails() (*capturingMailer, func()) {
+	captured := &capturingMailer{}
+	mailers.SetMailer(captured)
+
+	return captured, func() {
+		configured, err := mailers.NewMailerFromEnv()
+		as.NoError(err)
+		mailers.SetMailer(configured)
+	}
+}
+
+func (as *ActionSuite) Test_VerifyEmail() {
+	res := as.JSON("/api/v1/auth/register").Post(RegisterPayload{Email: "verify@example.com", Password: "correct horse battery", Name: "Verify"})
+	as.Equal(http.StatusCreated, res.Code)
+
+	user := &models.User{}
+	as.NoError(models.DB.Where("email = ?", "verify@example.com").First(user))
+	as.False(user.IsEmailVerified())
+
+	token, err := utils.NewPurposeToken(emailVerificationPurpose, user.ID.String(), user.Email, time.Hour)
+	as.NoError(err)
+
+	res = as.JSON("/api/v1/auth/email/verify?token=%s", token).Get()
+	as.Equal(http.StatusOK, res.Code)
+
+	as.NoError(models.DB.Reload(user))
+	as.True(user.IsEmailVerified())
+}
+
+func (as *ActionSuite) Test_VerifyEmail_MailedAfterCommit() {
+	captured, restore := as.captureMails()
+	defer restore()
+
+	res := as.JSON("/api/v1/auth/register").Post(RegisterPayload{Email: "mailed@example.com", Password: "correct horse battery", Name: "Mailed"})
+	as.Equal(http.StatusCreated, res.Code)
+	// a rejected registration mails nothing
+	res = as.JSON("/api/v1/auth/register").Post(RegisterPayload{Email: "mailed@example.com", Password: "correct horse battery", Name: "Mailed"})
+	as.Equal(http.StatusUnprocessableEntity, res.Code)
+
+	backgroundJobs.Wait()
+	as.Len(captured.messages, 1)
+	as.Equal([]string{"mailed@example.com"}, captured.messages[0].To)
+}
+
+func (as *ActionSuite) Test_VerifyEmail_RejectsOtherAddress() {
+	user, _ := as.logInAs("changed@example.com")
+
+	token, err := utils.NewPurposeToken(emailVerificationPurpose, user.ID.String(), "previous@example.com", time.Hour)
+	as.NoError(err)
+
+	res := as.JSON("/api/v1/auth/email/verify?token=%s", token).Get()
+	as.Equal(http.StatusUnprocessableEntity, res.Code)
+}
+
+func (as *ActionSuite) Test_ResendEmailVerification_Throttled() {
+	_, accessToken := as.logInAs("resend@example.com")
+
+	req := as.JSON("/api/v1/auth/email/resend")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	as.Equal(http.StatusAccepted, req.Post(nil).Code)
+
+	req = as.JSON("/api/v1/auth/email/resend")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	as.Equal(http.StatusTooManyRequests, req.Post(nil).Code)
+}
+
+func (as *ActionSuite) Test_CreatePost_RequiresVerifiedEmail() {
+	_, accessToken := as.logInAs("unverified@example.com")
+
+	req := as.JSON("/api/v1/posts/create")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	res := req.Post(map[string]string{"title": "Hello", "description": "World"})
+	as.Equal(http.StatusForbidden, res.Code)
+}
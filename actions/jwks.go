@@ -0,0 +1,21 @@
+package actions
+
+import (
+	"blog/utils"
+	"net/http"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+)
+
+// JWKSHandler - Publish the public keys of the key ring so other services can verify blog tokens,
+// HS256 shared secrets are never published
+func JWKSHandler(c buffalo.Context) error {
+	ring, ringErr := utils.CurrentKeyRing()
+	if ringErr != nil {
+		return c.Error(http.StatusInternalServerError, ringErr)
+	}
+
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.Render(http.StatusOK, r.JSON(ring.JWKS(time.Now())))
+}
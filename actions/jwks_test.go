@@ -0,0 +1,15 @@
+package actions
+
+import (
+	"blog/utils"
+	"net/http"
+)
+
+func (as *ActionSuite) Test_JWKSHandler_HidesSharedSecret() {
+	res := as.JSON("/.well-known/jwks.json").Get()
+	as.Equal(http.StatusOK, res.Code)
+
+	keySet := &utils.JWKSet{}
+	res.Bind(keySet)
+	as.Empty(keySet.Keys)
+}
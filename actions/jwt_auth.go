@@ -1,18 +1,24 @@
 package actions
 
 import (
+	"blog/lockout"
 	"blog/models"
 	"blog/utils"
 	"fmt"
+	"log"
+	"math"
+	"net"
 	"net/http"
-	"time"
+	"strings"
 
 	"github.com/dgrijalva/jwt-go/v4"
 	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v5"
 	"github.com/gobuffalo/validate/v3"
 	"github.com/gobuffalo/validate/v3/validators"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/gofrs/uuid"
 )
 
 type LogInPayload struct {
@@ -27,8 +33,11 @@ type RegisterPayload struct {
 }
 
 type LogInResponse struct {
-	AccessToken string      `json:"access_token"`
-	User        models.User `json:"user"`
+	AccessToken  string      `json:"access_token"`
+	TokenType    string      `json:"token_type"`
+	ExpiresIn    int64       `json:"expires_in"`
+	RefreshToken string      `json:"refresh_token"`
+	User         models.User `json:"user"`
 }
 
 type RegisterResponse struct {
@@ -41,6 +50,12 @@ type UserAuthResponse struct {
 	Data models.User `json:"data"`
 }
 
+// MessageResponse - Response body of actions that only report an outcome
+type MessageResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
 type Claims struct {
 	Email          string `json:"email"`
 	StandardClaims jwt.StandardClaims
@@ -53,22 +68,61 @@ func (payload *LogInPayload) Validate() (*validate.Errors, error) {
 	), nil
 }
 
+// dummyPasswordHash - compared against when the email is unknown, so the response
+// takes as long as for an existing account and does not reveal which emails exist
+var dummyPasswordHash, _ = utils.Passwords.Hash("not a real password")
+
+// loginGuard - throttles failed log ins, tests swap it for a guard with an in-memory store
+var loginGuard = lockout.NewGuardFromEnv()
+
 // AttemptAuth - Attempt on authentication
 func AttemptAuth(payload LogInPayload, db *pop.Connection) (bool, *models.User) {
 	user := &models.User{}
 
-	userNotFound := db.Where("email = ?", payload.Email).First(user)
+	userNotFound := db.Where("email = ?", strings.ToLower(payload.Email)).First(user)
 
 	if userNotFound != nil {
+		utils.Passwords.Verify(dummyPasswordHash, payload.Password)
 		return false, user
 	}
 
-	passwordNotMatched := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(payload.Password))
+	// a failed rehash is not fatal, the next log in tries again
+	passwordMatched, _ := user.CheckPassword(db, payload.Password)
 
-	if passwordNotMatched != nil {
-		return false, user
+	return passwordMatched, user
+}
+
+// trustedProxies - the reverse proxies of TRUSTED_PROXIES, only they may name the client
+// through X-Forwarded-For
+var trustedProxies = mustParseTrustedProxies(envy.Get("TRUSTED_PROXIES", ""))
+
+// mustParseTrustedProxies - a broken proxy list would throttle every client as one, refuse to start
+func mustParseTrustedProxies(list string) []*net.IPNet {
+	proxies, err := utils.ParseTrustedProxies(list)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return proxies
+}
+
+// clientIP - the address of the client without the port
+func clientIP(c buffalo.Context) string {
+	return utils.ClientIP(c.Request(), trustedProxies)
+}
+
+// renderLoginThrottled - 423 while locked out, 429 while the progressive delay runs
+func renderLoginThrottled(c buffalo.Context, decision lockout.Decision) error {
+	seconds := int(math.Ceil(decision.RetryAfter.Seconds()))
+	c.Response().Header().Set("Retry-After", fmt.Sprintf("%d", seconds))
+
+	if decision.Locked {
+		errorResponse := utils.NewErrorResponse(http.StatusLocked, "email", fmt.Sprintf("Too many failed log ins, the account is locked for %d seconds", seconds))
+		return c.Render(http.StatusLocked, r.JSON(errorResponse))
 	}
-	return true, user
+
+	errorResponse := utils.NewErrorResponse(http.StatusTooManyRequests, "email", fmt.Sprintf("Please wait %d seconds before trying again", seconds))
+	return c.Render(http.StatusTooManyRequests, r.JSON(errorResponse))
 }
 
 // JwtAuthLogIn default implementation.
@@ -80,10 +134,28 @@ func JwtAuthLogIn(c buffalo.Context) error {
 	if err != nil {
 		return c.Error(http.StatusInternalServerError, err)
 	}
+	ip := clientIP(c)
+	decision, guardErr := loginGuard.Check(request.Email, ip)
+	if guardErr != nil {
+		return c.Error(http.StatusInternalServerError, guardErr)
+	}
+	if !decision.Allowed() {
+		if err := recordLoginFailure(c, request.Email, nil, "throttled"); err != nil {
+			return c.Error(http.StatusInternalServerError, err)
+		}
+		return renderLoginThrottled(c, decision)
+	}
+
 	db := c.Value("tx").(*pop.Connection)
 	exist, user := AttemptAuth(*request, db)
 
 	if !exist {
+		if err := loginGuard.Fail(request.Email, ip); err != nil {
+			return c.Error(http.StatusInternalServerError, err)
+		}
+		if err := recordLoginFailure(c, request.Email, user, "invalid_credentials"); err != nil {
+			return c.Error(http.StatusInternalServerError, err)
+		}
 		verrs.Add("email", "The account credentials doesn't match with our database records")
 		errorResponse := utils.NewValidationErrorResponse(http.StatusUnprocessableEntity, verrs.Errors)
 		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
@@ -94,31 +166,62 @@ func JwtAuthLogIn(c buffalo.Context) error {
 		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
 	}
 
-	tokenExpiration := &jwt.Time{
-		Time: time.Now().Add(10080 * time.Minute),
+	// accounts with two factor authentication need a TOTP code before getting tokens, their
+	// failures are only forgotten once the code is right
+	if user.HasTOTP() {
+		pendingResponse, pendingErr := newMFAPendingResponse(*user)
+		if pendingErr != nil {
+			return c.Error(http.StatusInternalServerError, pendingErr)
+		}
+		return c.Render(http.StatusOK, r.JSON(pendingResponse))
 	}
-	claims := &jwt.StandardClaims{
-		ExpiresAt: tokenExpiration,
-		Issuer:    "buffalo-cms.api.dev",
-		ID:        user.ID.String(),
+
+	if err := loginGuard.Succeed(request.Email); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
 	}
 
-	tokenAlgo := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	key, keyErr := utils.ReadJWTKey()
-	if keyErr != nil {
-		c.Error(http.StatusInternalServerError, keyErr)
+	response, tokenErr := logInUser(c, db, *user, "password")
+	if tokenErr != nil {
+		return c.Error(http.StatusInternalServerError, tokenErr)
 	}
 
-	token, tokenSignedErr := tokenAlgo.SignedString(key)
+	return c.Render(http.StatusOK, r.JSON(response))
+}
+
+// logInUser - start a new session with its own refresh token family and build the log in response,
+// method names the credentials in the audit trail
+func logInUser(c buffalo.Context, tx *pop.Connection, user models.User, method string) (*LogInResponse, error) {
+	familyID := uuid.Must(uuid.NewV4())
+	refreshToken, _, err := models.IssueRefreshToken(tx, user.ID, familyID)
+	if err != nil {
+		return nil, err
+	}
 
-	if tokenSignedErr != nil {
-		c.Error(http.StatusInternalServerError, tokenSignedErr)
+	session, err := models.CreateUserSession(tx, user.ID, familyID, c.Request().UserAgent(), clientIP(c))
+	if err != nil {
+		return nil, err
+	}
+	if err := recordLoginSuccess(c, tx, user, session, method); err != nil {
+		return nil, err
 	}
 
-	return c.Render(http.StatusOK, r.JSON(LogInResponse{
-		AccessToken: token,
-		User:        *user,
-	}))
+	return newLogInResponse(user, session, refreshToken)
+}
+
+// newLogInResponse - sign a short lived access token for the session and pair it with the refresh token
+func newLogInResponse(user models.User, session *models.UserSession, refreshToken string) (*LogInResponse, error) {
+	accessToken, err := utils.NewAccessToken(user.ID.String(), session.ID.String(), utils.AllScopes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LogInResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(utils.AccessTokenTTL().Seconds()),
+		RefreshToken: refreshToken,
+		User:         user,
+	}, nil
 }
 
 // RegisterUser - Create a user
@@ -131,7 +234,7 @@ func RegisterUser(c buffalo.Context) error {
 	verrs := validate.Validate(
 		&validators.EmailIsPresent{Field: request.Email, Name: "email"},
 		&validators.StringLengthInRange{Field: request.Name, Name: "name", Min: 3, Max: 255},
-		&validators.StringLengthInRange{Field: request.Password, Name: "password", Min: 5, Max: 32},
+		&utils.PasswordPolicyValidator{Field: request.Password, Name: "password", Personal: []string{request.Name, request.Email}},
 	)
 
 	existUser := &models.User{}
@@ -157,6 +260,20 @@ func RegisterUser(c buffalo.Context) error {
 		return c.Render(http.StatusInternalServerError, r.JSON(errorResponse))
 	}
 
+	if err := recordAudit(c, tx, &models.AuditEvent{
+		Action:     models.AuditRegistered,
+		ActorID:    nulls.NewUUID(user.ID),
+		TargetType: "user",
+		TargetID:   user.ID.String(),
+		Changes:    models.DiffAudit(nil, user),
+	}); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	if verificationErr := sendEmailVerification(c, tx, user); verificationErr != nil {
+		return c.Error(http.StatusInternalServerError, verificationErr)
+	}
+
 	response := RegisterResponse{
 		Code: fmt.Sprintf("%d", http.StatusCreated),
 		Data: *user,
@@ -1,6 +1,17 @@
 package actions
 
+import "net/http"
+
 func (as *ActionSuite) Test_JwtAuth_LogIn() {
 	as.Fail("Not Implemented!")
 }
 
+func (as *ActionSuite) Test_RegisterUser_PasswordPolicy() {
+	res := as.JSON("/api/v1/auth/register").Post(RegisterPayload{Email: "policy@example.com", Password: "policy-example", Name: "Policy"})
+	as.Equal(http.StatusUnprocessableEntity, res.Code)
+	as.Contains(res.Body.String(), "must not contain your name or email address")
+
+	res = as.JSON("/api/v1/auth/register").Post(RegisterPayload{Email: "policy@example.com", Password: "abc", Name: "Policy"})
+	as.Equal(http.StatusUnprocessableEntity, res.Code)
+	as.Contains(res.Body.String(), "password")
+}
@@ -0,0 +1,61 @@
+package actions
+
+import (
+	"blog/lockout"
+	"blog/models"
+	"blog/utils"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+func (as *ActionSuite) Test_JwtAuthLogIn_LocksAccount() {
+	previousGuard := loginGuard
+	defer func() { loginGuard = previousGuard }()
+	loginGuard = &lockout.Guard{
+		Store:           lockout.NewMemoryStore(),
+		Clock:           utils.FixedClock{At: time.Now()},
+		MaxAttempts:     2,
+		IPMaxAttempts:   2,
+		LockoutDuration: time.Minute,
+	}
+
+	admin, adminToken := as.logInAs("unlock-admin@example.com")
+	as.NoError(admin.AssignRole(models.DB, models.RoleAdmin))
+	user, _ := as.logInAs("locked@example.com")
+
+	for i := 0; i < 2; i++ {
+		res := as.JSON("/api/v1/auth/login").Post(LogInPayload{Email: user.Email, Password: "wrong"})
+		as.Equal(http.StatusUnprocessableEntity, res.Code)
+	}
+
+	res := as.JSON("/api/v1/auth/login").Post(LogInPayload{Email: user.Email, Password: "secret"})
+	as.Equal(http.StatusLocked, res.Code)
+	as.Equal("60", res.Header().Get("Retry-After"))
+
+	// the IP of the failures is locked as well, the unlock lifts both
+	req := as.JSON("/api/v1/admin/users/%s/lockout", user.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", adminToken)
+	as.Equal(http.StatusOK, req.Delete().Code)
+
+	res = as.JSON("/api/v1/auth/login").Post(LogInPayload{Email: user.Email, Password: "secret"})
+	as.Equal(http.StatusOK, res.Code)
+}
+
+func (as *ActionSuite) Test_JwtAuthLogIn_UnknownEmailIsThrottled() {
+	previousGuard := loginGuard
+	defer func() { loginGuard = previousGuard }()
+	loginGuard = &lockout.Guard{
+		Store:           lockout.NewMemoryStore(),
+		Clock:           utils.FixedClock{At: time.Now()},
+		MaxAttempts:     1,
+		IPMaxAttempts:   10,
+		LockoutDuration: time.Minute,
+	}
+
+	res := as.JSON("/api/v1/auth/login").Post(LogInPayload{Email: "nobody@example.com", Password: "wrong"})
+	as.Equal(http.StatusUnprocessableEntity, res.Code)
+
+	res = as.JSON("/api/v1/auth/login").Post(LogInPayload{Email: "nobody@example.com", Password: "wrong"})
+	as.Equal(http.StatusLocked, res.Code)
+}
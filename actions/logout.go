@@ -0,0 +1,90 @@
+package actions
+
+import (
+	"blog/models"
+	"blog/utils"
+	"fmt"
+	"net/http"
+
+	"github.com/dgrijalva/jwt-go/v4"
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+)
+
+// LogOutPayload - Request body of log out, the refresh token is optional
+type LogOutPayload struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// JwtAuthLogOut - Revoke the access token and the session of the request and the refresh token family given in the body
+func JwtAuthLogOut(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	request := &LogOutPayload{}
+	c.Bind(request)
+
+	// personal access tokens carry no claims, they are revoked through their own endpoint
+	if claims, ok := c.Value("authClaims").(jwt.StandardClaims); ok {
+		if err := models.RevokeToken(tx, claims.ID, authUser.ID, claims.ExpiresAt.Time); err != nil {
+			return c.Error(http.StatusInternalServerError, err)
+		}
+	}
+
+	event := &models.AuditEvent{
+		Action:     models.AuditTokenRevoked,
+		TargetType: "user",
+		TargetID:   authUser.ID.String(),
+		Metadata:   models.AuditMetadata{"reason": "logout"},
+	}
+	if session, ok := c.Value("authSession").(models.UserSession); ok {
+		if err := session.Revoke(tx); err != nil {
+			return c.Error(http.StatusInternalServerError, err)
+		}
+		event.Metadata["session_id"] = session.ID.String()
+	}
+
+	if request.RefreshToken != "" {
+		refreshToken, findErr := models.FindRefreshToken(tx, request.RefreshToken)
+		// silently ignore refresh tokens that do not belong to the caller
+		if findErr == nil && refreshToken.UserID == authUser.ID {
+			if err := models.RevokeRefreshTokenFamily(tx, refreshToken.FamilyID); err != nil {
+				return c.Error(http.StatusInternalServerError, err)
+			}
+			event.Metadata["family_id"] = refreshToken.FamilyID.String()
+		}
+	}
+
+	if err := recordAudit(c, tx, event); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(MessageResponse{
+		Code:    fmt.Sprintf("%d", http.StatusOK),
+		Message: "You have been logged out",
+	}))
+}
+
+// JwtAuthLogOutAll - Revoke every access and refresh token of the authenticated user
+func JwtAuthLogOutAll(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	if err := authUser.RevokeAllTokens(tx); err != nil {
+		errorResponse := utils.NewErrorResponse(http.StatusInternalServerError, "user", "There is a problem while revoking the tokens please try again later")
+		return c.Render(http.StatusInternalServerError, r.JSON(errorResponse))
+	}
+	if err := recordAudit(c, tx, &models.AuditEvent{
+		Action:     models.AuditTokenRevoked,
+		TargetType: "user",
+		TargetID:   authUser.ID.String(),
+		Metadata:   models.AuditMetadata{"reason": "logout_all"},
+	}); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(MessageResponse{
+		Code:    fmt.Sprintf("%d", http.StatusOK),
+		Message: "You have been logged out from every device",
+	}))
+}
@@ -0,0 +1,49 @@
+package actions
+
+import (
+	"blog/models"
+	"blog/utils"
+	"fmt"
+	"net/http"
+)
+
+func (as *ActionSuite) logInAs(email string) (*models.User, string) {
+	user := &models.User{Email: email, Password: "secret", Name: "Logout"}
+	_, err := user.Create(models.DB)
+	as.NoError(err)
+
+	accessToken, err := utils.NewAccessToken(user.ID.String(), "", utils.AllScopes)
+	as.NoError(err)
+
+	return user, accessToken
+}
+
+func (as *ActionSuite) Test_JwtAuthLogOut_RevokesToken() {
+	_, accessToken := as.logInAs("logout@example.com")
+
+	req := as.JSON("/api/v1/auth/logout")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	res := req.Post(LogOutPayload{})
+	as.Equal(http.StatusOK, res.Code)
+
+	req = as.JSON("/api/v1/auth/user")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	res = req.Get()
+	as.Equal(http.StatusUnauthorized, res.Code)
+}
+
+func (as *ActionSuite) Test_JwtAuthLogOutAll_RevokesEveryToken() {
+	user, accessToken := as.logInAs("logout-all@example.com")
+	otherToken, err := utils.NewAccessToken(user.ID.String(), "", utils.AllScopes)
+	as.NoError(err)
+
+	req := as.JSON("/api/v1/auth/logout-all")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	res := req.Post(LogOutPayload{})
+	as.Equal(http.StatusOK, res.Code)
+
+	req = as.JSON("/api/v1/auth/user")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", otherToken)
+	res = req.Get()
+	as.Equal(http.StatusUnauthorized, res.Code)
+}
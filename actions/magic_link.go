@@ -0,0 +1,180 @@
+package actions
+
+import (
+	"blog/lockout"
+	"blog/mailers"
+	"blog/models"
+	"blog/utils"
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/pkg/errors"
+)
+
+// magicLinkGuard - throttles the link requests per email and per client IP with
+// MAGIC_LINK_MAX_REQUESTS and MAGIC_LINK_IP_MAX_REQUESTS within MAGIC_LINK_WINDOW
+var magicLinkGuard = newMagicLinkGuard()
+
+// newMagicLinkGuard - a guard on the store of the log in guard, its counters kept apart by a prefix
+func newMagicLinkGuard() *lockout.Guard {
+	guard := lockout.NewGuardFromEnv()
+	guard.Prefix = "magic_link:"
+	guard.MaxAttempts = utils.IntFromEnv("MAGIC_LINK_MAX_REQUESTS", 3)
+	guard.IPMaxAttempts = utils.IntFromEnv("MAGIC_LINK_IP_MAX_REQUESTS", 20)
+	guard.LockoutDuration = utils.DurationFromEnv("MAGIC_LINK_WINDOW", 15*time.Minute)
+
+	return guard
+}
+
+// MagicLinkPayload - Request body to ask for a log in link
+type MagicLinkPayload struct {
+	Email string `json:"email"`
+}
+
+// RedeemMagicLinkPayload - Request body to log in with the token of a magic link
+type RedeemMagicLinkPayload struct {
+	Token string `json:"token"`
+}
+
+// RequestMagicLink - Mail a single use log in link, the response is the same whether the email
+// belongs to an account or not
+func RequestMagicLink(c buffalo.Context) error {
+	request := &MagicLinkPayload{}
+	c.Bind(request)
+
+	verrs := validate.Validate(
+		&validators.EmailIsPresent{Field: request.Email, Name: "email"},
+	)
+	if verrs.HasAny() {
+		errorResponse := utils.NewValidationErrorResponse(http.StatusUnprocessableEntity, verrs.Errors)
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	// every request counts, for unknown emails as well, so the limit does not tell which exist
+	email := strings.ToLower(request.Email)
+	ip := clientIP(c)
+	decision, guardErr := magicLinkGuard.Check(email, ip)
+	if guardErr != nil {
+		return c.Error(http.StatusInternalServerError, guardErr)
+	}
+	if !decision.Allowed() {
+		seconds := int(math.Ceil(decision.RetryAfter.Seconds()))
+		c.Response().Header().Set("Retry-After", fmt.Sprintf("%d", seconds))
+		errorResponse := utils.NewErrorResponse(http.StatusTooManyRequests, "email", fmt.Sprintf("Please wait %d seconds before asking for another log in link", seconds))
+		return c.Render(http.StatusTooManyRequests, r.JSON(errorResponse))
+	}
+	if err := magicLinkGuard.Fail(email, ip); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	// the lookup, the token and the mail all happen after the response, so known and unknown
+	// emails cost the request the same
+	runInBackground(c, "unable to send the magic link mail", func() error {
+		return sendMagicLink(email)
+	})
+
+	return c.Render(http.StatusAccepted, r.JSON(MessageResponse{
+		Code:    fmt.Sprintf("%d", http.StatusAccepted),
+		Message: "If the email belongs to an account, a log in link has been sent to it",
+	}))
+}
+
+// RedeemMagicLink - Exchange the token of a magic link for the same response as JwtAuthLogIn
+func RedeemMagicLink(c buffalo.Context) error {
+	request := &RedeemMagicLinkPayload{}
+	c.Bind(request)
+
+	verrs := validate.Validate(
+		&validators.StringIsPresent{Field: request.Token, Name: "token"},
+	)
+	if verrs.HasAny() {
+		errorResponse := utils.NewValidationErrorResponse(http.StatusUnprocessableEntity, verrs.Errors)
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	tx := c.Value("tx").(*pop.Connection)
+	user, consumeErr := models.ConsumeMagicLinkToken(tx, request.Token)
+	if consumeErr != nil {
+		errorResponse := utils.NewErrorResponse(http.StatusUnauthorized, "token", "The log in link is invalid or has expired")
+		return c.Render(http.StatusUnauthorized, r.JSON(errorResponse))
+	}
+	// the link replaces the password, not the lockout, the refusal rolls the redemption back
+	ip := clientIP(c)
+	decision, guardErr := loginGuard.Check(user.Email, ip)
+	if guardErr != nil {
+		return c.Error(http.StatusInternalServerError, guardErr)
+	}
+	if !decision.Allowed() {
+		if err := recordLoginFailure(c, user.Email, user, "throttled"); err != nil {
+			return c.Error(http.StatusInternalServerError, err)
+		}
+		return renderLoginThrottled(c, decision)
+	}
+
+	// the link reached the inbox, which proves the ownership of the email
+	if err := user.MarkEmailVerified(tx); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	// the link replaces the password, not the second factor
+	if user.HasTOTP() {
+		pendingResponse, pendingErr := newMFAPendingResponse(*user)
+		if pendingErr != nil {
+			return c.Error(http.StatusInternalServerError, pendingErr)
+		}
+		return c.Render(http.StatusOK, r.JSON(pendingResponse))
+	}
+
+	if err := loginGuard.Succeed(user.Email); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	response, tokenErr := logInUser(c, tx, *user, "magic_link")
+	if tokenErr != nil {
+		return c.Error(http.StatusInternalServerError, tokenErr)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(response))
+}
+
+// sendMagicLink - Issue a magic link to the account of the email and mail it once the token is
+// committed, unknown emails get nothing
+func sendMagicLink(email string) error {
+	user := &models.User{}
+	plainToken := ""
+	err := models.DB.Transaction(func(tx *pop.Connection) error {
+		findErr := tx.Where("email = ?", email).First(user)
+		if errors.Cause(findErr) == sql.ErrNoRows {
+			return nil
+		}
+		if findErr != nil {
+			return findErr
+		}
+
+		var issueErr error
+		plainToken, issueErr = models.IssueMagicLinkToken(tx, user, utils.DurationFromEnv("MAGIC_LINK_TTL", 15*time.Minute))
+		return issueErr
+	})
+	if err != nil || plainToken == "" {
+		return err
+	}
+
+	return mailers.SendMagicLink(*user, magicLinkURL(plainToken))
+}
+
+// magicLinkURL - Link of the front end page that redeems the magic link token
+func magicLinkURL(plainToken string) string {
+	linkURL := envy.Get("MAGIC_LINK_URL", "http://127.0.0.1:3000/login/magic")
+
+	return fmt.Sprintf("%s?token=%s", linkURL, url.QueryEscape(plainToken))
+}
@@ -0,0 +1,78 @@
+package actions
+
+import (
+	"blog/lockout"
+	"blog/models"
+	"blog/utils"
+	"net/http"
+	"time"
+)
+
+func (as *ActionSuite) Test_RequestMagicLink_DoesNotRevealEmail() {
+	as.logInAs("magic-request@example.com")
+
+	known := as.JSON("/api/v1/auth/magic-link").Post(MagicLinkPayload{Email: "magic-request@example.com"})
+	unknown := as.JSON("/api/v1/auth/magic-link").Post(MagicLinkPayload{Email: "nobody@example.com"})
+	as.Equal(http.StatusAccepted, known.Code)
+	as.Equal(known.Body.String(), unknown.Body.String())
+
+	// the link is issued after the response
+	backgroundJobs.Wait()
+	user := &models.User{}
+	as.NoError(models.DB.Where("email = ?", "magic-request@example.com").First(user))
+	count, err := models.DB.Where("user_id = ? AND used_at IS NULL", user.ID).Count(&models.MagicLinkToken{})
+	as.NoError(err)
+	as.Equal(1, count)
+}
+
+func (as *ActionSuite) Test_RequestMagicLink_Throttled() {
+	previousGuard := magicLinkGuard
+	defer func() { magicLinkGuard = previousGuard }()
+	magicLinkGuard = &lockout.Guard{Prefix: "magic_link:", Store: lockout.NewMemoryStore(), Clock: utils.FixedClock{At: time.Now()}, MaxAttempts: 2, IPMaxAttempts: 10, LockoutDuration: time.Minute}
+
+	for i := 0; i < 2; i++ {
+		res := as.JSON("/api/v1/auth/magic-link").Post(MagicLinkPayload{Email: "nobody@example.com"})
+		as.Equal(http.StatusAccepted, res.Code)
+	}
+	res := as.JSON("/api/v1/auth/magic-link").Post(MagicLinkPayload{Email: "nobody@example.com"})
+	as.Equal(http.StatusTooManyRequests, res.Code)
+	as.Equal("60", res.Header().Get("Retry-After"))
+	backgroundJobs.Wait()
+}
+
+func (as *ActionSuite) Test_RedeemMagicLink_RespectsLockout() {
+	previousGuard := loginGuard
+	defer func() { loginGuard = previousGuard }()
+	loginGuard = &lockout.Guard{Store: lockout.NewMemoryStore(), Clock: utils.FixedClock{At: time.Now()}, MaxAttempts: 1, IPMaxAttempts: 10, LockoutDuration: time.Minute}
+
+	user, _ := as.logInAs("magic-locked@example.com")
+	res := as.JSON("/api/v1/auth/login").Post(LogInPayload{Email: user.Email, Password: "wrong"})
+	as.Equal(http.StatusUnprocessableEntity, res.Code)
+
+	plainToken, err := models.IssueMagicLinkToken(models.DB, user, time.Minute)
+	as.NoError(err)
+	res = as.JSON("/api/v1/auth/magic-link/redeem").Post(RedeemMagicLinkPayload{Token: plainToken})
+	as.Equal(http.StatusLocked, res.Code)
+
+	// the refused redemption left the link usable
+	as.NoError(loginGuard.Unlock(user.Email))
+	res = as.JSON("/api/v1/auth/magic-link/redeem").Post(RedeemMagicLinkPayload{Token: plainToken})
+	as.Equal(http.StatusOK, res.Code)
+}
+
+func (as *ActionSuite) Test_RedeemMagicLink() {
+	user, _ := as.logInAs("magic-redeem@example.com")
+	plainToken, err := models.IssueMagicLinkToken(models.DB, user, time.Minute)
+	as.NoError(err)
+
+	res := as.JSON("/api/v1/auth/magic-link/redeem").Post(RedeemMagicLinkPayload{Token: plainToken})
+	as.Equal(http.StatusOK, res.Code)
+
+	loggedIn := &LogInResponse{}
+	res.Bind(loggedIn)
+	as.NotEmpty(loggedIn.AccessToken)
+	as.NotEmpty(loggedIn.RefreshToken)
+
+	res = as.JSON("/api/v1/auth/magic-link/redeem").Post(RedeemMagicLinkPayload{Token: plainToken})
+	as.Equal(http.StatusUnauthorized, res.Code)
+}
@@ -0,0 +1,278 @@
+package actions
+
+import (
+	"blog/models"
+	"blog/utils"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// mfaPendingPurpose - audience of the token handed out between the password and the TOTP step
+const mfaPendingPurpose = "mfa_pending"
+
+// mfaClock - time source of the TOTP checks, tests swap it for a fixed clock
+var mfaClock utils.Clock = utils.SystemClock
+
+// MFACodePayload - Request body carrying a TOTP code
+type MFACodePayload struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// MFAVerifyPayload - Request body to exchange the pending token for an access token
+type MFAVerifyPayload struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// MFAPendingResponse - Log in response of accounts with two factor authentication
+type MFAPendingResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// MFAEnrollResponse - Secret to add to an authenticator app
+type MFAEnrollResponse struct {
+	Code       string `json:"code"`
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	QRCodeURL  string `json:"qr_code_url"`
+}
+
+// MFARecoveryCodesResponse - Recovery codes, only shown once
+type MFARecoveryCodesResponse struct {
+	Code          string   `json:"code"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// mfaIssuer - name shown in the authenticator app
+func mfaIssuer() string {
+	return envy.Get("MFA_ISSUER", "Buffalo CMS")
+}
+
+// newMFAPendingResponse - short lived token proving the password step succeeded
+func newMFAPendingResponse(user models.User) (*MFAPendingResponse, error) {
+	ttl := utils.DurationFromEnv("MFA_PENDING_TTL", 5*time.Minute)
+	token, err := utils.NewPurposeToken(mfaPendingPurpose, user.ID.String(), "", ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MFAPendingResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresIn:   int64(ttl.Seconds()),
+	}, nil
+}
+
+// EnrollMFA - Generate a TOTP secret for the authenticated user, 2FA is only enabled after ConfirmMFA
+func EnrollMFA(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	if authUser.HasTOTP() {
+		errorResponse := utils.NewErrorResponse(http.StatusUnprocessableEntity, "mfa", "Two factor authentication is already enabled")
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	secret, secretErr := utils.GenerateTOTPSecret()
+	if secretErr != nil {
+		return c.Error(http.StatusInternalServerError, secretErr)
+	}
+	if err := authUser.StartTOTPEnrollment(tx, secret); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(MFAEnrollResponse{
+		Code:       fmt.Sprintf("%d", http.StatusOK),
+		Secret:     secret,
+		OTPAuthURI: utils.TOTPURI(mfaIssuer(), authUser.Email, secret),
+		QRCodeURL:  "/api/v1/auth/mfa/enroll/qr.png",
+	}))
+}
+
+// MFAQRCode - Render the pending enrollment as a QR code PNG
+func MFAQRCode(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+
+	if !authUser.TOTPSecret.Valid || authUser.HasTOTP() {
+		errorResponse := utils.NewErrorResponse(http.StatusNotFound, "mfa", "There is no pending two factor enrollment")
+		return c.Render(http.StatusNotFound, r.JSON(errorResponse))
+	}
+
+	png, qrErr := qrcode.Encode(utils.TOTPURI(mfaIssuer(), authUser.Email, authUser.TOTPSecret.String), qrcode.Medium, 256)
+	if qrErr != nil {
+		return c.Error(http.StatusInternalServerError, qrErr)
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.Render(http.StatusOK, r.Func("image/png", func(w io.Writer, d render.Data) error {
+		_, err := w.Write(png)
+		return err
+	}))
+}
+
+// ConfirmMFA - Enable 2FA with the first valid code and hand out the recovery codes
+func ConfirmMFA(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	request := &MFACodePayload{}
+	c.Bind(request)
+
+	if authUser.HasTOTP() || !authUser.TOTPSecret.Valid {
+		errorResponse := utils.NewErrorResponse(http.StatusUnprocessableEntity, "mfa", "There is no pending two factor enrollment")
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	valid, verifyErr := authUser.VerifyTOTP(tx, request.Code, mfaClock.Now())
+	if verifyErr != nil {
+		return c.Error(http.StatusInternalServerError, verifyErr)
+	}
+	if !valid {
+		errorResponse := utils.NewErrorResponse(http.StatusUnprocessableEntity, "code", "The authentication code is invalid")
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	if err := authUser.EnableTOTP(tx); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	recoveryCodes, codesErr := models.GenerateRecoveryCodes(tx, authUser.ID)
+	if codesErr != nil {
+		return c.Error(http.StatusInternalServerError, codesErr)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(MFARecoveryCodesResponse{
+		Code:          fmt.Sprintf("%d", http.StatusOK),
+		RecoveryCodes: recoveryCodes,
+	}))
+}
+
+// DisableMFA - Turn 2FA off, a valid code or recovery code is required
+func DisableMFA(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	request := &MFACodePayload{}
+	c.Bind(request)
+
+	if !authUser.HasTOTP() {
+		errorResponse := utils.NewErrorResponse(http.StatusUnprocessableEntity, "mfa", "Two factor authentication is not enabled")
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	valid, verifyErr := verifySecondFactor(tx, &authUser, request.Code, request.RecoveryCode)
+	if verifyErr != nil {
+		return c.Error(http.StatusInternalServerError, verifyErr)
+	}
+	if !valid {
+		errorResponse := utils.NewErrorResponse(http.StatusUnprocessableEntity, "code", "The authentication code is invalid")
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	if err := authUser.DisableTOTP(tx); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(MessageResponse{
+		Code:    fmt.Sprintf("%d", http.StatusOK),
+		Message: "Two factor authentication has been disabled",
+	}))
+}
+
+// VerifyMFA - Exchange the pending token and a TOTP or recovery code for the access token,
+// every pending token is good for one attempt
+func VerifyMFA(c buffalo.Context) error {
+	request := &MFAVerifyPayload{}
+	c.Bind(request)
+
+	verrs := validate.Validate(
+		&validators.StringIsPresent{Field: request.MFAToken, Name: "mfa_token"},
+	)
+	if request.Code == "" && request.RecoveryCode == "" {
+		verrs.Add("code", "Code can not be blank.")
+	}
+	if verrs.HasAny() {
+		errorResponse := utils.NewValidationErrorResponse(http.StatusUnprocessableEntity, verrs.Errors)
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	invalidResponse := utils.NewErrorResponse(http.StatusUnauthorized, "code", "The authentication code or token is invalid")
+	claims, parseErr := utils.ParsePurposeToken(request.MFAToken, mfaPendingPurpose)
+	if parseErr != nil {
+		return c.Render(http.StatusUnauthorized, r.JSON(invalidResponse))
+	}
+
+	tx := c.Value("tx").(*pop.Connection)
+	user := &models.User{}
+	if findErr := tx.Find(user, claims.Subject); findErr != nil || !user.HasTOTP() {
+		return c.Render(http.StatusUnauthorized, r.JSON(invalidResponse))
+	}
+
+	// wrong codes count against the account like wrong passwords
+	ip := clientIP(c)
+	decision, guardErr := loginGuard.Check(user.Email, ip)
+	if guardErr != nil {
+		return c.Error(http.StatusInternalServerError, guardErr)
+	}
+	if !decision.Allowed() {
+		if err := recordLoginFailure(c, user.Email, user, "throttled"); err != nil {
+			return c.Error(http.StatusInternalServerError, err)
+		}
+		return renderLoginThrottled(c, decision)
+	}
+
+	// the pending token allows a single guess, the claim must survive the failed response
+	claimed, claimErr := models.ClaimToken(models.DB, claims.ID, user.ID, claims.ExpiresAt.Time)
+	if claimErr != nil {
+		return c.Error(http.StatusInternalServerError, claimErr)
+	}
+	if !claimed {
+		return c.Render(http.StatusUnauthorized, r.JSON(invalidResponse))
+	}
+
+	valid, verifyErr := verifySecondFactor(tx, user, request.Code, request.RecoveryCode)
+	if verifyErr != nil {
+		return c.Error(http.StatusInternalServerError, verifyErr)
+	}
+	if !valid {
+		if err := loginGuard.Fail(user.Email, ip); err != nil {
+			return c.Error(http.StatusInternalServerError, err)
+		}
+		if err := recordLoginFailure(c, user.Email, user, "invalid_second_factor"); err != nil {
+			return c.Error(http.StatusInternalServerError, err)
+		}
+		return c.Render(http.StatusUnauthorized, r.JSON(invalidResponse))
+	}
+	if err := loginGuard.Succeed(user.Email); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	response, tokenErr := logInUser(c, tx, *user, "mfa")
+	if tokenErr != nil {
+		return c.Error(http.StatusInternalServerError, tokenErr)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(response))
+}
+
+// verifySecondFactor - accept either a TOTP code or an unused recovery code
+func verifySecondFactor(tx *pop.Connection, user *models.User, code string, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		return models.ConsumeRecoveryCode(tx, user.ID, recoveryCode)
+	}
+
+	return user.VerifyTOTP(tx, code, mfaClock.Now())
+}
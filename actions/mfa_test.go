@@ -0,0 +1,94 @@
+package actions
+
+import (
+	"blog/lockout"
+	"blog/utils"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+func (as *ActionSuite) Test_MFA_EnrollAndLogIn() {
+	clock := utils.FixedClock{At: time.Date(2021, 3, 20, 9, 0, 0, 0, time.UTC)}
+	mfaClock = clock
+	defer func() { mfaClock = utils.SystemClock }()
+
+	user, accessToken := as.logInAs("mfa@example.com")
+	authorization := fmt.Sprintf("Bearer %s", accessToken)
+
+	req := as.JSON("/api/v1/auth/mfa/enroll")
+	req.Headers["Authorization"] = authorization
+	res := req.Post(nil)
+	as.Equal(http.StatusOK, res.Code)
+	enrollment := &MFAEnrollResponse{}
+	res.Bind(enrollment)
+	as.Contains(enrollment.OTPAuthURI, "otpauth://totp/")
+
+	req = as.JSON("/api/v1/auth/mfa/enroll/qr.png")
+	req.Headers["Authorization"] = authorization
+	res = req.Get()
+	as.Equal(http.StatusOK, res.Code)
+	as.Equal("image/png", res.Header().Get("Content-Type"))
+
+	code, err := utils.TOTPCode(enrollment.Secret, utils.TOTPStep(clock.Now()))
+	as.NoError(err)
+	req = as.JSON("/api/v1/auth/mfa/confirm")
+	req.Headers["Authorization"] = authorization
+	res = req.Post(MFACodePayload{Code: code})
+	as.Equal(http.StatusOK, res.Code)
+	recovery := &MFARecoveryCodesResponse{}
+	res.Bind(recovery)
+	as.Len(recovery.RecoveryCodes, 10)
+
+	// wrong codes count against the account, every log in backs off without a base delay
+	previousGuard := loginGuard
+	defer func() { loginGuard = previousGuard }()
+	loginGuard = &lockout.Guard{Store: lockout.NewMemoryStore(), Clock: utils.FixedClock{At: time.Now()}, MaxAttempts: 3, IPMaxAttempts: 10, LockoutDuration: time.Minute}
+
+	// the password alone only yields a pending token
+	pendingToken := func() string {
+		res := as.JSON("/api/v1/auth/login").Post(LogInPayload{Email: user.Email, Password: "secret"})
+		as.Equal(http.StatusOK, res.Code)
+		pending := &MFAPendingResponse{}
+		res.Bind(pending)
+		as.True(pending.MFARequired)
+		return pending.MFAToken
+	}
+	mfaToken := pendingToken()
+
+	// the pending token is not an access token
+	req = as.JSON("/api/v1/auth/user")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", mfaToken)
+	as.Equal(http.StatusUnauthorized, req.Get().Code)
+
+	// the code used for the enrollment can not be replayed
+	res = as.JSON("/api/v1/auth/mfa/verify").Post(MFAVerifyPayload{MFAToken: mfaToken, Code: code})
+	as.Equal(http.StatusUnauthorized, res.Code)
+
+	// the pending token was spent on the wrong code
+	mfaClock = utils.FixedClock{At: clock.Now().Add(30 * time.Second)}
+	nextCode, err := utils.TOTPCode(enrollment.Secret, utils.TOTPStep(mfaClock.Now()))
+	as.NoError(err)
+	res = as.JSON("/api/v1/auth/mfa/verify").Post(MFAVerifyPayload{MFAToken: mfaToken, Code: nextCode})
+	as.Equal(http.StatusUnauthorized, res.Code)
+
+	res = as.JSON("/api/v1/auth/mfa/verify").Post(MFAVerifyPayload{MFAToken: pendingToken(), Code: nextCode})
+	as.Equal(http.StatusOK, res.Code)
+	logIn := &LogInResponse{}
+	res.Bind(logIn)
+	as.NotEmpty(logIn.AccessToken)
+
+	mfaToken = pendingToken()
+	res = as.JSON("/api/v1/auth/mfa/verify").Post(MFAVerifyPayload{MFAToken: mfaToken, RecoveryCode: recovery.RecoveryCodes[0]})
+	as.Equal(http.StatusOK, res.Code)
+	res = as.JSON("/api/v1/auth/mfa/verify").Post(MFAVerifyPayload{MFAToken: pendingToken(), RecoveryCode: recovery.RecoveryCodes[0]})
+	as.Equal(http.StatusUnauthorized, res.Code)
+
+	// guessing codes locks the account
+	for i := 0; i < 2; i++ {
+		res = as.JSON("/api/v1/auth/mfa/verify").Post(MFAVerifyPayload{MFAToken: pendingToken(), Code: "000000"})
+		as.Equal(http.StatusUnauthorized, res.Code)
+	}
+	res = as.JSON("/api/v1/auth/login").Post(LogInPayload{Email: user.Email, Password: "secret"})
+	as.Equal(http.StatusLocked, res.Code)
+}
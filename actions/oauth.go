@@ -0,0 +1,585 @@
+package actions
+
+import (
+	"blog/models"
+	"blog/utils"
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// oauthScopeDescriptions - what the consent page tells the user about every delegable scope
+var oauthScopeDescriptions = map[string]string{
+	utils.ScopePostsRead:  "Read the posts",
+	utils.ScopePostsWrite: "Create, update and delete your posts",
+}
+
+// oauthLayout - the consent pages do not depend on the assets or the CSRF token of the application layout
+const oauthLayout = "oauth/layout.plush.html"
+
+// OAuthErrorResponse - Error body of the OAuth endpoints (RFC 6749 5.2)
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// OAuthTokenResponse - Response body of the token endpoint (RFC 6749 5.1)
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+// OAuthIntrospectionResponse - Response body of the introspection endpoint (RFC 7662 2.2)
+type OAuthIntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
+
+// oauthAuthorizationRequest - A validated authorization request, carried through the consent form
+type oauthAuthorizationRequest struct {
+	Client        *models.OAuthClient
+	RedirectURI   string
+	State         string
+	Scopes        []string
+	CodeChallenge string
+}
+
+// errorRedirect - send the error back to the client (RFC 6749 4.1.2.1)
+func (a *oauthAuthorizationRequest) errorRedirect(c buffalo.Context, code string, description string) error {
+	return c.Redirect(http.StatusFound, a.redirectURL(url.Values{
+		"error":             {code},
+		"error_description": {description},
+	}))
+}
+
+// redirectURL - the redirect URI with the response parameters and the state of the client
+func (a *oauthAuthorizationRequest) redirectURL(params url.Values) string {
+	redirectURL, _ := url.Parse(a.RedirectURI)
+	query := redirectURL.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	if a.State != "" {
+		query.Set("state", a.State)
+	}
+	redirectURL.RawQuery = query.Encode()
+
+	return redirectURL.String()
+}
+
+// parseOAuthAuthorizationRequest - validate the parameters of an authorization request. Errors
+// about the client or the redirect URI are rendered since the redirect URI can not be trusted,
+// the others are redirected to the client.
+func parseOAuthAuthorizationRequest(c buffalo.Context, tx *pop.Connection) (*oauthAuthorizationRequest, error) {
+	client, clientErr := models.FindOAuthClient(tx, c.Param("client_id"))
+	if clientErr != nil {
+		return nil, renderOAuthPageError(c, "The application is unknown or has been disabled.")
+	}
+
+	redirectURI := c.Param("redirect_uri")
+	if redirectURI == "" && len(client.RedirectURIList()) == 1 {
+		redirectURI = client.RedirectURIList()[0]
+	}
+	if !client.AllowsRedirectURI(redirectURI) {
+		return nil, renderOAuthPageError(c, "The redirect URI is not registered for the application.")
+	}
+
+	request := &oauthAuthorizationRequest{
+		Client:        client,
+		RedirectURI:   redirectURI,
+		State:         c.Param("state"),
+		Scopes:        utils.ParseScopes(c.Param("scope")),
+		CodeChallenge: c.Param("code_challenge"),
+	}
+	if len(request.Scopes) == 0 {
+		request.Scopes = client.ScopeList()
+	}
+
+	if c.Param("response_type") != "code" {
+		return nil, request.errorRedirect(c, "unsupported_response_type", "Only the code response type is supported")
+	}
+	// PKCE is required from every client, public or confidential
+	if request.CodeChallenge == "" || c.Param("code_challenge_method") != "S256" {
+		return nil, request.errorRedirect(c, "invalid_request", "A S256 code_challenge is required")
+	}
+	if !client.AllowsScopes(request.Scopes) {
+		return nil, request.errorRedirect(c, "invalid_scope", "The application may not ask for the requested scope")
+	}
+
+	return request, nil
+}
+
+// renderOAuthPageError - an authorization request that can not be redirected back to the client
+func renderOAuthPageError(c buffalo.Context, message string) error {
+	c.Set("errorMessage", message)
+
+	return c.Render(http.StatusBadRequest, r.HTML("oauth/error.plush.html", oauthLayout))
+}
+
+// renderOAuthConsent - the consent page, with an optional error of the previous submission
+func renderOAuthConsent(c buffalo.Context, status int, request *oauthAuthorizationRequest, errorMessage string) error {
+	scopes := []string{}
+	for _, scope := range request.Scopes {
+		scopes = append(scopes, oauthScopeDescriptions[scope])
+	}
+
+	// the page asks for credentials, it must never be framed by another site or cached
+	c.Response().Header().Set("X-Frame-Options", "DENY")
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Set("client", request.Client)
+	c.Set("scopeDescriptions", scopes)
+	c.Set("authorization", map[string]string{
+		"client_id":             request.Client.ClientID,
+		"redirect_uri":          request.RedirectURI,
+		"state":                 request.State,
+		"scope":                 utils.FormatScopes(request.Scopes),
+		"code_challenge":        request.CodeChallenge,
+		"code_challenge_method": "S256",
+		"response_type":         "code",
+	})
+	c.Set("email", c.Param("email"))
+	c.Set("errorMessage", errorMessage)
+
+	return c.Render(status, r.HTML("oauth/authorize.plush.html", oauthLayout))
+}
+
+// OAuthAuthorize - Render the consent page of an authorization request (RFC 6749 4.1.1)
+func OAuthAuthorize(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	request, parseErr := parseOAuthAuthorizationRequest(c, tx)
+	if request == nil {
+		return parseErr
+	}
+
+	return renderOAuthConsent(c, http.StatusOK, request, "")
+}
+
+// OAuthConsent - Authenticate the user on the consent page and redirect back to the client
+// with an authorization code, or with access_denied when the user declined
+func OAuthConsent(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	request, parseErr := parseOAuthAuthorizationRequest(c, tx)
+	if request == nil {
+		return parseErr
+	}
+
+	if c.Param("decision") != "approve" {
+		return request.errorRedirect(c, "access_denied", "The user declined the authorization")
+	}
+
+	email := c.Param("email")
+	ip := clientIP(c)
+	decision, guardErr := loginGuard.Check(email, ip)
+	if guardErr != nil {
+		return c.Error(http.StatusInternalServerError, guardErr)
+	}
+	if !decision.Allowed() {
+		seconds := int(math.Ceil(decision.RetryAfter.Seconds()))
+		c.Response().Header().Set("Retry-After", fmt.Sprintf("%d", seconds))
+		return renderOAuthConsent(c, http.StatusTooManyRequests, request, fmt.Sprintf("Too many failed log ins, please wait %d seconds before trying again.", seconds))
+	}
+
+	matched, user := AttemptAuth(LogInPayload{Email: email, Password: c.Param("password")}, tx)
+	if !matched {
+		if err := loginGuard.Fail(email, ip); err != nil {
+			return c.Error(http.StatusInternalServerError, err)
+		}
+		if err := recordLoginFailure(c, email, user, "invalid_credentials"); err != nil {
+			return c.Error(http.StatusInternalServerError, err)
+		}
+		return renderOAuthConsent(c, http.StatusUnprocessableEntity, request, "The account credentials doesn't match with our database records.")
+	}
+
+	// the consent replaces the log in, so it asks for the second factor as well
+	if user.HasTOTP() {
+		valid, verifyErr := verifySecondFactor(tx, user, c.Param("code"), "")
+		if verifyErr != nil {
+			return c.Error(http.StatusInternalServerError, verifyErr)
+		}
+		if !valid {
+			if err := loginGuard.Fail(email, ip); err != nil {
+				return c.Error(http.StatusInternalServerError, err)
+			}
+			if err := recordLoginFailure(c, email, user, "invalid_second_factor"); err != nil {
+				return c.Error(http.StatusInternalServerError, err)
+			}
+			return renderOAuthConsent(c, http.StatusUnprocessableEntity, request, "The authentication code is invalid.")
+		}
+	}
+
+	if err := loginGuard.Succeed(email); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	ttl := utils.DurationFromEnv("OAUTH_CODE_TTL", time.Minute)
+	plainCode, issueErr := models.IssueOAuthAuthorizationCode(tx, request.Client, user.ID, request.RedirectURI, request.Scopes, request.CodeChallenge, ttl)
+	if issueErr != nil {
+		return c.Error(http.StatusInternalServerError, issueErr)
+	}
+
+	return c.Redirect(http.StatusFound, request.redirectURL(url.Values{"code": {plainCode}}))
+}
+
+// renderOAuthError - an error of the token, introspection or revocation endpoint
+func renderOAuthError(c buffalo.Context, status int, code string, description string) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	return c.Render(status, r.JSON(OAuthErrorResponse{Error: code, ErrorDescription: description}))
+}
+
+// authenticateOAuthClient - identify the client with HTTP Basic or the client_id and
+// client_secret form parameters (RFC 6749 2.3.1), public clients only send their client_id
+func authenticateOAuthClient(c buffalo.Context, tx *pop.Connection, form url.Values) (*models.OAuthClient, error) {
+	clientID, clientSecret, basic := c.Request().BasicAuth()
+	if basic {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = form.Get("client_id"), form.Get("client_secret")
+	}
+
+	client, findErr := models.FindOAuthClient(tx, clientID)
+	if findErr == nil && (client.CheckSecret(clientSecret) || (!client.Confidential && clientSecret == "")) {
+		return client, nil
+	}
+
+	if basic {
+		c.Response().Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+
+	return nil, renderOAuthError(c, http.StatusUnauthorized, "invalid_client", "The client authentication failed")
+}
+
+// OAuthToken - Exchange an authorization code, a refresh token or the client credentials for an access token
+func OAuthToken(c buffalo.Context) error {
+	if err := c.Request().ParseForm(); err != nil {
+		return renderOAuthError(c, http.StatusBadRequest, "invalid_request", "The request body must be form encoded")
+	}
+	form := c.Request().PostForm
+	tx := c.Value("tx").(*pop.Connection)
+
+	client, authErr := authenticateOAuthClient(c, tx, form)
+	if client == nil {
+		return authErr
+	}
+
+	switch form.Get("grant_type") {
+	case "authorization_code":
+		return oauthAuthorizationCodeGrant(c, tx, client, form)
+	case "refresh_token":
+		return oauthRefreshTokenGrant(c, tx, client, form)
+	case "client_credentials":
+		return oauthClientCredentialsGrant(c, client, form)
+	}
+
+	return renderOAuthError(c, http.StatusBadRequest, "unsupported_grant_type", "The grant type is not supported")
+}
+
+// oauthAuthorizationCodeGrant - RFC 6749 4.1.3 with the PKCE verification of RFC 7636 4.6
+func oauthAuthorizationCodeGrant(c buffalo.Context, tx *pop.Connection, client *models.OAuthClient, form url.Values) error {
+	// consumed outside the request transaction so a failed exchange burns the code
+	authorizationCode, consumeErr := models.ConsumeOAuthAuthorizationCode(models.DB, client, form.Get("code"))
+	if consumeErr == models.ErrOAuthCodeReplayed {
+		// the code leaked, the tokens issued for it can not be trusted either (RFC 6749 4.1.2)
+		if authorizationCode.FamilyID.Valid {
+			if err := models.RevokeRefreshTokenFamily(models.DB, authorizationCode.FamilyID.UUID); err != nil {
+				return c.Error(http.StatusInternalServerError, err)
+			}
+		}
+		return renderOAuthError(c, http.StatusBadRequest, "invalid_grant", "The authorization code has already been used")
+	}
+	if consumeErr != nil {
+		return renderOAuthError(c, http.StatusBadRequest, "invalid_grant", "The authorization code is invalid or expired")
+	}
+
+	if form.Get("redirect_uri") != authorizationCode.RedirectURI {
+		return renderOAuthError(c, http.StatusBadRequest, "invalid_grant", "The redirect URI does not match the authorization request")
+	}
+	if !utils.VerifyPKCE(authorizationCode.CodeChallenge, form.Get("code_verifier")) {
+		return renderOAuthError(c, http.StatusBadRequest, "invalid_grant", "The code verifier does not match the code challenge")
+	}
+
+	user := &models.User{}
+	if err := tx.Find(user, authorizationCode.UserID); err != nil {
+		return renderOAuthError(c, http.StatusBadRequest, "invalid_grant", "The user of the authorization code no longer exists")
+	}
+
+	familyID := uuid.Must(uuid.NewV4())
+	refreshToken, _, issueErr := models.IssueRefreshToken(tx, user.ID, familyID)
+	if issueErr != nil {
+		return c.Error(http.StatusInternalServerError, issueErr)
+	}
+	session, sessionErr := models.CreateOAuthSession(tx, user.ID, familyID, client, authorizationCode.ScopeList(), clientIP(c))
+	if sessionErr != nil {
+		return c.Error(http.StatusInternalServerError, sessionErr)
+	}
+	if err := authorizationCode.RecordFamily(models.DB, familyID); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return renderOAuthTokens(c, user.ID.String(), session.ID.String(), client, authorizationCode.ScopeList(), refreshToken)
+}
+
+// oauthRefreshTokenGrant - RFC 6749 6, the refresh token is rotated like the one of a log in
+func oauthRefreshTokenGrant(c buffalo.Context, tx *pop.Connection, client *models.OAuthClient, form url.Values) error {
+	refreshToken, findErr := models.FindRefreshToken(tx, form.Get("refresh_token"))
+	if findErr != nil {
+		return renderOAuthError(c, http.StatusBadRequest, "invalid_grant", "The refresh token is invalid or expired")
+	}
+
+	session, sessionErr := models.FindUserSessionByFamily(tx, refreshToken.FamilyID)
+	if errors.Cause(sessionErr) == sql.ErrNoRows || (sessionErr == nil && session.OAuthClientID.UUID != client.ID) {
+		return renderOAuthError(c, http.StatusBadRequest, "invalid_grant", "The refresh token was not issued to the client")
+	}
+	if sessionErr != nil {
+		return c.Error(http.StatusInternalServerError, sessionErr)
+	}
+
+	if refreshToken.IsReplayed() {
+		if err := models.RevokeRefreshTokenFamily(models.DB, refreshToken.FamilyID); err != nil {
+			return c.Error(http.StatusInternalServerError, err)
+		}
+		return renderOAuthError(c, http.StatusBadRequest, "invalid_grant", "The refresh token has already been used")
+	}
+	if !refreshToken.IsActive() || !session.IsActive() {
+		return renderOAuthError(c, http.StatusBadRequest, "invalid_grant", "The refresh token is invalid or expired")
+	}
+
+	// a refresh may narrow the scopes of the access token, never widen them (RFC 6749 6)
+	scopes := utils.ParseScopes(session.Scopes)
+	if requested := utils.ParseScopes(form.Get("scope")); len(requested) > 0 {
+		for _, scope := range requested {
+			if !utils.HasScope(scopes, scope) {
+				return renderOAuthError(c, http.StatusBadRequest, "invalid_scope", "The requested scope exceeds the granted scope")
+			}
+		}
+		scopes = requested
+	}
+
+	nextToken, _, rotateErr := refreshToken.Rotate(tx)
+	if errors.Is(rotateErr, models.ErrRefreshTokenReplayed) {
+		return renderOAuthError(c, http.StatusBadRequest, "invalid_grant", "The refresh token is invalid or expired")
+	}
+	if rotateErr != nil {
+		return c.Error(http.StatusInternalServerError, rotateErr)
+	}
+	if err := session.Touch(tx, time.Now()); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return renderOAuthTokens(c, refreshToken.UserID.String(), session.ID.String(), client, scopes, nextToken)
+}
+
+// oauthClientCredentialsGrant - RFC 6749 4.4, a confidential client acts as the user who registered it
+func oauthClientCredentialsGrant(c buffalo.Context, client *models.OAuthClient, form url.Values) error {
+	if !client.Confidential {
+		return renderOAuthError(c, http.StatusBadRequest, "unauthorized_client", "Only confidential clients may use the client credentials grant")
+	}
+
+	scopes := utils.ParseScopes(form.Get("scope"))
+	if len(scopes) == 0 {
+		scopes = client.ScopeList()
+	}
+	if !client.AllowsScopes(scopes) {
+		return renderOAuthError(c, http.StatusBadRequest, "invalid_scope", "The client may not ask for the requested scope")
+	}
+
+	return renderOAuthTokens(c, client.UserID.String(), "", client, scopes, "")
+}
+
+// renderOAuthTokens - sign the access token of the client and answer the token request
+func renderOAuthTokens(c buffalo.Context, userID string, sessionID string, client *models.OAuthClient, scopes []string, refreshToken string) error {
+	accessToken, signErr := utils.NewClientAccessToken(userID, sessionID, client.ClientID, scopes)
+	if signErr != nil {
+		return c.Error(http.StatusInternalServerError, signErr)
+	}
+
+	tx := c.Value("tx").(*pop.Connection)
+	actorID, _ := uuid.FromString(userID)
+	if err := recordAudit(c, tx, &models.AuditEvent{
+		Action:     models.AuditTokenIssued,
+		ActorID:    nulls.NewUUID(actorID),
+		TargetType: "oauth_client",
+		TargetID:   client.ID.String(),
+		Metadata: models.AuditMetadata{
+			"grant_type": c.Request().PostForm.Get("grant_type"),
+			"scope":      utils.FormatScopes(scopes),
+			"session_id": sessionID,
+		},
+	}); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	return c.Render(http.StatusOK, r.JSON(OAuthTokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(utils.AccessTokenTTL().Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        utils.FormatScopes(scopes),
+	}))
+}
+
+// findClientAccessToken - the claims of an access token the client was issued, nil for anything else
+func findClientAccessToken(client *models.OAuthClient, token string) *utils.AccessClaims {
+	keyRing, ringErr := utils.CurrentKeyRing()
+	if ringErr != nil {
+		return nil
+	}
+	claims, parseErr := utils.ParseAccessToken(token, keyRing)
+	if parseErr != nil || claims.ClientID != client.ClientID {
+		return nil
+	}
+
+	return claims
+}
+
+// findClientRefreshToken - the refresh token and session of a grant of the client, nil for anything else
+func findClientRefreshToken(tx *pop.Connection, client *models.OAuthClient, token string) (*models.RefreshToken, *models.UserSession) {
+	refreshToken, findErr := models.FindRefreshToken(tx, token)
+	if findErr != nil {
+		return nil, nil
+	}
+	session, sessionErr := models.FindUserSessionByFamily(tx, refreshToken.FamilyID)
+	if sessionErr != nil || session.OAuthClientID.UUID != client.ID {
+		return nil, nil
+	}
+
+	return refreshToken, session
+}
+
+// OAuthIntrospect - Tell a confidential client whether a token it was issued is still active (RFC 7662)
+func OAuthIntrospect(c buffalo.Context) error {
+	if err := c.Request().ParseForm(); err != nil {
+		return renderOAuthError(c, http.StatusBadRequest, "invalid_request", "The request body must be form encoded")
+	}
+	form := c.Request().PostForm
+	tx := c.Value("tx").(*pop.Connection)
+
+	client, authErr := authenticateOAuthClient(c, tx, form)
+	if client == nil {
+		return authErr
+	}
+	if !client.Confidential {
+		return renderOAuthError(c, http.StatusUnauthorized, "invalid_client", "Only confidential clients may introspect tokens")
+	}
+
+	token := form.Get("token")
+	if claims := findClientAccessToken(client, token); claims != nil {
+		active, activeErr := isAccessTokenActive(tx, claims)
+		if activeErr != nil {
+			return c.Error(http.StatusInternalServerError, activeErr)
+		}
+		if active {
+			return c.Render(http.StatusOK, r.JSON(OAuthIntrospectionResponse{
+				Active:    true,
+				Scope:     claims.Scope,
+				ClientID:  claims.ClientID,
+				Subject:   claims.Subject,
+				TokenType: "access_token",
+				ExpiresAt: claims.ExpiresAt.Unix(),
+				IssuedAt:  claims.IssuedAt.Unix(),
+			}))
+		}
+	}
+
+	if refreshToken, session := findClientRefreshToken(tx, client, token); refreshToken != nil && refreshToken.IsActive() && session.IsActive() {
+		return c.Render(http.StatusOK, r.JSON(OAuthIntrospectionResponse{
+			Active:    true,
+			Scope:     session.Scopes,
+			ClientID:  client.ClientID,
+			Subject:   refreshToken.UserID.String(),
+			TokenType: "refresh_token",
+			ExpiresAt: refreshToken.ExpiresAt.Unix(),
+			IssuedAt:  refreshToken.CreatedAt.Unix(),
+		}))
+	}
+
+	return c.Render(http.StatusOK, r.JSON(OAuthIntrospectionResponse{Active: false}))
+}
+
+// isAccessTokenActive - the same checks JWTMiddleware runs on an access token of a client
+func isAccessTokenActive(tx *pop.Connection, claims *utils.AccessClaims) (bool, error) {
+	revoked, err := models.IsTokenRevoked(tx, claims.ID)
+	if err != nil || revoked {
+		return false, err
+	}
+
+	user := &models.User{}
+	if err := tx.Find(user, claims.Subject); err != nil || user.IssuedBeforeRevocation(claims.IssuedAt.Time) {
+		return false, nil
+	}
+
+	if claims.SessionID != "" {
+		session := &models.UserSession{}
+		if err := tx.Find(session, claims.SessionID); err != nil || !session.IsActive() {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// OAuthRevoke - Revoke an access or refresh token the client was issued (RFC 7009), revoking a
+// refresh token ends the whole grant. Unknown tokens are answered with success as well.
+func OAuthRevoke(c buffalo.Context) error {
+	if err := c.Request().ParseForm(); err != nil {
+		return renderOAuthError(c, http.StatusBadRequest, "invalid_request", "The request body must be form encoded")
+	}
+	form := c.Request().PostForm
+	tx := c.Value("tx").(*pop.Connection)
+
+	client, authErr := authenticateOAuthClient(c, tx, form)
+	if client == nil {
+		return authErr
+	}
+
+	token := strings.TrimSpace(form.Get("token"))
+	event := &models.AuditEvent{
+		Action:     models.AuditTokenRevoked,
+		TargetType: "oauth_client",
+		TargetID:   client.ID.String(),
+	}
+	if claims := findClientAccessToken(client, token); claims != nil {
+		userID, _ := uuid.FromString(claims.Subject)
+		if err := models.RevokeToken(tx, claims.ID, userID, claims.ExpiresAt.Time); err != nil {
+			return c.Error(http.StatusInternalServerError, err)
+		}
+		event.ActorID = nulls.NewUUID(userID)
+		event.Metadata = models.AuditMetadata{"token_type": "access_token", "jti": claims.ID}
+	} else if refreshToken, _ := findClientRefreshToken(tx, client, token); refreshToken != nil {
+		if err := models.RevokeRefreshTokenFamily(tx, refreshToken.FamilyID); err != nil {
+			return c.Error(http.StatusInternalServerError, err)
+		}
+		event.ActorID = nulls.NewUUID(refreshToken.UserID)
+		event.Metadata = models.AuditMetadata{"token_type": "refresh_token", "family_id": refreshToken.FamilyID.String()}
+	}
+	if event.ActorID.Valid {
+		if err := recordAudit(c, tx, event); err != nil {
+			return c.Error(http.StatusInternalServerError, err)
+		}
+	}
+
+	return c.Render(http.StatusOK, r.JSON(map[string]string{}))
+}
@@ -0,0 +1,98 @@
+package actions
+
+import (
+	"blog/models"
+	"blog/utils"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+)
+
+// OAuthClientPayload - Request body to register a third party app
+type OAuthClientPayload struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+	Confidential bool     `json:"confidential"`
+}
+
+// OAuthClientResponse - The registered client, the secret of a confidential client is only shown once
+type OAuthClientResponse struct {
+	ClientSecret string              `json:"client_secret,omitempty"`
+	OAuthClient  *models.OAuthClient `json:"oauth_client"`
+}
+
+// ListOAuthClients - List the third party apps registered by the authenticated user
+func ListOAuthClients(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	clients := &models.OAuthClients{}
+	if err := tx.Where("user_id = ?", authUser.ID).Order("created_at desc").All(clients); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(clients))
+}
+
+// CreateOAuthClient - Register a third party app that may ask users for the requested scopes
+func CreateOAuthClient(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	request := &OAuthClientPayload{}
+	if err := c.Bind(request); err != nil {
+		errorResponse := utils.NewErrorResponse(http.StatusBadRequest, "name", "The request body is invalid")
+		return c.Render(http.StatusBadRequest, r.JSON(errorResponse))
+	}
+
+	client := &models.OAuthClient{
+		UserID:       authUser.ID,
+		Name:         request.Name,
+		RedirectURIs: strings.Join(request.RedirectURIs, " "),
+		Scopes:       utils.FormatScopes(request.Scopes),
+		Confidential: request.Confidential,
+	}
+
+	plainSecret, verrs, err := models.CreateOAuthClient(tx, client)
+	if err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	if verrs.HasAny() {
+		errorResponse := utils.NewValidationErrorResponse(http.StatusUnprocessableEntity, verrs.Errors)
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	return c.Render(http.StatusCreated, r.JSON(OAuthClientResponse{
+		ClientSecret: plainSecret,
+		OAuthClient:  client,
+	}))
+}
+
+// RevokeOAuthClient - Disable a third party app of the authenticated user and end every session granted to it
+func RevokeOAuthClient(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	client := &models.OAuthClient{}
+	if err := tx.Where("user_id = ?", authUser.ID).Find(client, c.Param("client_id")); err != nil {
+		notFoundResponse := utils.NewErrorResponse(
+			http.StatusNotFound,
+			"client_id",
+			fmt.Sprintf("The requested OAuth client %s does not exist.", c.Param("client_id")),
+		)
+		return c.Render(http.StatusNotFound, r.JSON(notFoundResponse))
+	}
+
+	if err := client.Revoke(tx); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(MessageResponse{
+		Code:    fmt.Sprintf("%d", http.StatusOK),
+		Message: "The OAuth client has been revoked",
+	}))
+}
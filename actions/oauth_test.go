@@ -0,0 +1,184 @@
+package actions
+
+import (
+	"blog/oidc"
+	"blog/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// registerOAuthClient - register a client of the user through the API
+func (as *ActionSuite) registerOAuthClient(accessToken string, confidential bool) *OAuthClientResponse {
+	req := as.JSON("/api/v1/oauth/clients")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	res := req.Post(OAuthClientPayload{
+		Name:         "Partner App",
+		RedirectURIs: []string{"https://partner.example.com/callback"},
+		Scopes:       []string{utils.ScopePostsRead, utils.ScopePostsWrite},
+		Confidential: confidential,
+	})
+	as.Equal(http.StatusCreated, res.Code)
+
+	created := &OAuthClientResponse{}
+	res.Bind(created)
+
+	return created
+}
+
+// authorizeOAuthClient - consent as the user and return the code of the redirect
+func (as *ActionSuite) authorizeOAuthClient(clientID string, email string, verifier string) string {
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {clientID},
+		"redirect_uri":          {"https://partner.example.com/callback"},
+		"scope":                 {utils.ScopePostsRead},
+		"state":                 {"xyz"},
+		"code_challenge":        {utils.PKCEChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	res := as.HTML("/oauth/authorize?%s", params.Encode()).Get()
+	as.Equal(http.StatusOK, res.Code)
+	as.Contains(res.Body.String(), "Partner App")
+
+	params.Set("email", email)
+	params.Set("password", "secret")
+	params.Set("decision", "approve")
+	res = as.HTML("/oauth/authorize").Post(params)
+	as.Equal(http.StatusFound, res.Code)
+
+	location, err := url.Parse(res.Header().Get("Location"))
+	as.NoError(err)
+	as.Equal("partner.example.com", location.Host)
+	as.Equal("xyz", location.Query().Get("state"))
+
+	return location.Query().Get("code")
+}
+
+func (as *ActionSuite) Test_OAuth_AuthorizationCodeFlow() {
+	_, accessToken := as.logInAs("oauth-owner@example.com")
+	client := as.registerOAuthClient(accessToken, false)
+	as.Empty(client.ClientSecret)
+
+	verifier, _ := oidc.NewCodeVerifier()
+	code := as.authorizeOAuthClient(client.OAuthClient.ClientID, "oauth-owner@example.com", verifier)
+
+	exchange := url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {client.OAuthClient.ClientID},
+		"code":          {code},
+		"redirect_uri":  {"https://partner.example.com/callback"},
+		"code_verifier": {"a-wrong-verifier-that-is-long-enough-to-be-accepted-as-one"},
+	}
+	res := as.HTML("/oauth/token").Post(exchange)
+	as.Equal(http.StatusBadRequest, res.Code)
+	as.Contains(res.Body.String(), "invalid_grant")
+
+	// the failed exchange burnt the code
+	exchange.Set("code_verifier", verifier)
+	res = as.HTML("/oauth/token").Post(exchange)
+	as.Equal(http.StatusBadRequest, res.Code)
+
+	code = as.authorizeOAuthClient(client.OAuthClient.ClientID, "oauth-owner@example.com", verifier)
+	exchange.Set("code", code)
+	res = as.HTML("/oauth/token").Post(exchange)
+	as.Equal(http.StatusOK, res.Code)
+
+	tokens := &OAuthTokenResponse{}
+	as.NoError(json.Unmarshal(res.Body.Bytes(), tokens))
+	as.Equal(utils.ScopePostsRead, tokens.Scope)
+	as.NotEmpty(tokens.RefreshToken)
+
+	// the token carries the consented scope only
+	req := as.JSON("/api/v1/posts/")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", tokens.AccessToken)
+	as.Equal(http.StatusOK, req.Get().Code)
+	req = as.JSON("/api/v1/auth/tokens")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", tokens.AccessToken)
+	as.Equal(http.StatusForbidden, req.Get().Code)
+
+	// the refresh token only works at the token endpoint of its client
+	as.Equal(http.StatusUnauthorized, as.JSON("/api/v1/auth/refresh").Post(RefreshPayload{RefreshToken: tokens.RefreshToken}).Code)
+
+	res = as.HTML("/oauth/token").Post(url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {client.OAuthClient.ClientID},
+		"refresh_token": {tokens.RefreshToken},
+	})
+	as.Equal(http.StatusOK, res.Code)
+	refreshed := &OAuthTokenResponse{}
+	as.NoError(json.Unmarshal(res.Body.Bytes(), refreshed))
+	as.NotEqual(tokens.RefreshToken, refreshed.RefreshToken)
+
+	// revoking the client ends the grant
+	req = as.JSON("/api/v1/oauth/clients/%s", client.OAuthClient.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	as.Equal(http.StatusOK, req.Delete().Code)
+
+	req = as.JSON("/api/v1/posts/")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", refreshed.AccessToken)
+	as.Equal(http.StatusUnauthorized, req.Get().Code)
+}
+
+func (as *ActionSuite) Test_OAuth_ConsentDenied() {
+	_, accessToken := as.logInAs("oauth-deny@example.com")
+	client := as.registerOAuthClient(accessToken, false)
+
+	res := as.HTML("/oauth/authorize").Post(url.Values{
+		"response_type":         {"code"},
+		"client_id":             {client.OAuthClient.ClientID},
+		"state":                 {"xyz"},
+		"code_challenge":        {utils.PKCEChallenge("verifier")},
+		"code_challenge_method": {"S256"},
+		"decision":              {"deny"},
+	})
+	as.Equal(http.StatusFound, res.Code)
+	as.Contains(res.Header().Get("Location"), "error=access_denied")
+	as.Contains(res.Header().Get("Location"), "state=xyz")
+
+	// an unregistered redirect URI is never redirected to
+	res = as.HTML("/oauth/authorize?response_type=code&client_id=%s&redirect_uri=https://evil.example.com/", client.OAuthClient.ClientID).Get()
+	as.Equal(http.StatusBadRequest, res.Code)
+}
+
+func (as *ActionSuite) Test_OAuth_ClientCredentialsIntrospectAndRevoke() {
+	owner, accessToken := as.logInAs("oauth-service@example.com")
+	client := as.registerOAuthClient(accessToken, true)
+	as.NotEmpty(client.ClientSecret)
+
+	req := as.HTML("/oauth/token")
+	req.SetBasicAuth(client.OAuthClient.ClientID, "wrong")
+	res := req.Post(url.Values{"grant_type": {"client_credentials"}})
+	as.Equal(http.StatusUnauthorized, res.Code)
+
+	req = as.HTML("/oauth/token")
+	req.SetBasicAuth(client.OAuthClient.ClientID, client.ClientSecret)
+	res = req.Post(url.Values{"grant_type": {"client_credentials"}, "scope": {utils.ScopePostsWrite}})
+	as.Equal(http.StatusOK, res.Code)
+	tokens := &OAuthTokenResponse{}
+	as.NoError(json.Unmarshal(res.Body.Bytes(), tokens))
+	as.Empty(tokens.RefreshToken)
+
+	introspect := func() *OAuthIntrospectionResponse {
+		req := as.HTML("/oauth/introspect")
+		req.SetBasicAuth(client.OAuthClient.ClientID, client.ClientSecret)
+		res := req.Post(url.Values{"token": {tokens.AccessToken}})
+		as.Equal(http.StatusOK, res.Code)
+		introspection := &OAuthIntrospectionResponse{}
+		as.NoError(json.Unmarshal(res.Body.Bytes(), introspection))
+		return introspection
+	}
+
+	introspection := introspect()
+	as.True(introspection.Active)
+	as.Equal(owner.ID.String(), introspection.Subject)
+	as.Equal(utils.ScopePostsWrite, introspection.Scope)
+
+	req = as.HTML("/oauth/revoke")
+	req.SetBasicAuth(client.OAuthClient.ClientID, client.ClientSecret)
+	as.Equal(http.StatusOK, req.Post(url.Values{"token": {tokens.AccessToken}}).Code)
+
+	as.False(introspect().Active)
+}
@@ -0,0 +1,110 @@
+package actions
+
+import (
+	"blog/models"
+	"blog/oidc"
+	"blog/utils"
+	"net/http"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+)
+
+// OIDCAuthorize - Start a log in at an external OpenID Connect provider, the browser is
+// redirected to the provider with a fresh state, nonce and PKCE challenge
+func OIDCAuthorize(c buffalo.Context) error {
+	provider, providerErr := oidc.ProviderFromEnv(c.Param("provider"))
+	if providerErr != nil {
+		return renderUnknownOIDCProvider(c, providerErr)
+	}
+
+	nonce, nonceErr := utils.GenerateOpaqueToken()
+	if nonceErr != nil {
+		return c.Error(http.StatusInternalServerError, nonceErr)
+	}
+	codeVerifier, verifierErr := oidc.NewCodeVerifier()
+	if verifierErr != nil {
+		return c.Error(http.StatusInternalServerError, verifierErr)
+	}
+
+	tx := c.Value("tx").(*pop.Connection)
+	ttl := utils.DurationFromEnv("OIDC_STATE_TTL", 10*time.Minute)
+	state, stateErr := models.CreateOIDCAuthRequest(tx, provider.Name, nonce, codeVerifier, ttl)
+	if stateErr != nil {
+		return c.Error(http.StatusInternalServerError, stateErr)
+	}
+
+	authorizationURL, urlErr := provider.AuthorizationURL(state, nonce, oidc.CodeChallenge(codeVerifier))
+	if urlErr != nil {
+		c.Logger().Errorf("unable to reach the %s OpenID Connect provider: %v", provider.Name, urlErr)
+		errorResponse := utils.NewErrorResponse(http.StatusBadGateway, "provider", "The identity provider is not available")
+		return c.Render(http.StatusBadGateway, r.JSON(errorResponse))
+	}
+
+	return c.Redirect(http.StatusFound, authorizationURL)
+}
+
+// OIDCCallback - Finish the log in at the provider, link the identity to a user (creating
+// the user on the first log in) and answer like JwtAuthLogIn
+func OIDCCallback(c buffalo.Context) error {
+	provider, providerErr := oidc.ProviderFromEnv(c.Param("provider"))
+	if providerErr != nil {
+		return renderUnknownOIDCProvider(c, providerErr)
+	}
+
+	if providerError := c.Param("error"); providerError != "" {
+		errorResponse := utils.NewErrorResponse(http.StatusUnauthorized, "provider", "The identity provider refused the log in: "+providerError)
+		return c.Render(http.StatusUnauthorized, r.JSON(errorResponse))
+	}
+
+	// consumed outside the request transaction so a failed callback can not be replayed
+	authRequest, stateErr := models.ConsumeOIDCAuthRequest(models.DB, provider.Name, c.Param("state"))
+	if stateErr != nil {
+		errorResponse := utils.NewErrorResponse(http.StatusUnauthorized, "state", "The log in request is invalid or has expired")
+		return c.Render(http.StatusUnauthorized, r.JSON(errorResponse))
+	}
+
+	claims, exchangeErr := provider.Exchange(c.Param("code"), authRequest.CodeVerifier, authRequest.Nonce)
+	if exchangeErr != nil {
+		c.Logger().Warnf("OpenID Connect log in with %s failed: %v", provider.Name, exchangeErr)
+		errorResponse := utils.NewErrorResponse(http.StatusUnauthorized, "code", "The identity provider did not confirm the log in")
+		return c.Render(http.StatusUnauthorized, r.JSON(errorResponse))
+	}
+
+	tx := c.Value("tx").(*pop.Connection)
+	user, userErr := models.FindOrCreateUserForIdentity(tx, provider.Name, claims.Subject, claims.Email, claims.EmailVerified, claims.Name)
+	if userErr != nil {
+		if userErr == models.ErrIdentityEmailTaken || userErr == models.ErrIdentityEmailMissing {
+			errorResponse := utils.NewErrorResponse(http.StatusConflict, "email", "The identity can not be linked to an account, log in with your password first")
+			return c.Render(http.StatusConflict, r.JSON(errorResponse))
+		}
+		return c.Error(http.StatusInternalServerError, userErr)
+	}
+
+	// the provider replaces the password, not the second factor
+	if user.HasTOTP() {
+		pendingResponse, pendingErr := newMFAPendingResponse(*user)
+		if pendingErr != nil {
+			return c.Error(http.StatusInternalServerError, pendingErr)
+		}
+		return c.Render(http.StatusOK, r.JSON(pendingResponse))
+	}
+
+	response, tokenErr := logInUser(c, tx, *user, "oidc:"+provider.Name)
+	if tokenErr != nil {
+		return c.Error(http.StatusInternalServerError, tokenErr)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(response))
+}
+
+// renderUnknownOIDCProvider - 404 for providers that are not enabled, misconfigured ones are logged
+func renderUnknownOIDCProvider(c buffalo.Context, err error) error {
+	if err != oidc.ErrUnknownProvider {
+		c.Logger().Errorf("%v", err)
+	}
+	errorResponse := utils.NewErrorResponse(http.StatusNotFound, "provider", "The identity provider is not available")
+
+	return c.Render(http.StatusNotFound, r.JSON(errorResponse))
+}
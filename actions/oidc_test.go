@@ -0,0 +1,77 @@
+package actions
+
+import (
+	"blog/oidc/oidctest"
+	"net/http"
+	"net/url"
+
+	"github.com/gobuffalo/envy"
+)
+
+// useMockOIDCIssuer - start a mock issuer and enable it as the "mock" provider, call it inside envy.Temp
+func (as *ActionSuite) useMockOIDCIssuer() *oidctest.MockIssuer {
+	issuer := oidctest.NewMockIssuer("blog", "blog-secret")
+	envy.Set("OIDC_PROVIDERS", "mock")
+	envy.Set("OIDC_MOCK_ISSUER", issuer.URL())
+	envy.Set("OIDC_MOCK_CLIENT_ID", issuer.ClientID)
+	envy.Set("OIDC_MOCK_CLIENT_SECRET", issuer.ClientSecret)
+	envy.Set("OIDC_MOCK_REDIRECT_URL", "http://127.0.0.1:3000/api/v1/auth/oidc/mock/callback")
+
+	return issuer
+}
+
+// oidcSignIn - run the browser part of the flow and return the callback query for the blog
+func (as *ActionSuite) oidcSignIn(issuer *oidctest.MockIssuer) url.Values {
+	res := as.HTML("/api/v1/auth/oidc/mock/authorize").Get()
+	as.Equal(http.StatusFound, res.Code)
+
+	callback, err := issuer.SignIn(res.Header().Get("Location"))
+	as.NoError(err)
+
+	return callback.Query()
+}
+
+func (as *ActionSuite) Test_OIDC_FirstLogInCreatesUser() {
+	envy.Temp(func() {
+		issuer := as.useMockOIDCIssuer()
+		defer issuer.Close()
+		issuer.Identity = oidctest.Identity{Subject: "oidc-1", Email: "oidc@example.com", EmailVerified: true, Name: "OIDC User"}
+
+		query := as.oidcSignIn(issuer)
+		res := as.JSON("/api/v1/auth/oidc/mock/callback?" + query.Encode()).Get()
+		as.Equal(http.StatusOK, res.Code)
+
+		loggedIn := &LogInResponse{}
+		res.Bind(loggedIn)
+		as.NotEmpty(loggedIn.AccessToken)
+		as.Equal("oidc@example.com", loggedIn.User.Email)
+
+		// the state is single use
+		res = as.JSON("/api/v1/auth/oidc/mock/callback?" + query.Encode()).Get()
+		as.Equal(http.StatusUnauthorized, res.Code)
+
+		// the second log in finds the same user through the identity
+		res = as.JSON("/api/v1/auth/oidc/mock/callback?" + as.oidcSignIn(issuer).Encode()).Get()
+		as.Equal(http.StatusOK, res.Code)
+		again := &LogInResponse{}
+		res.Bind(again)
+		as.Equal(loggedIn.User.ID, again.User.ID)
+	})
+}
+
+func (as *ActionSuite) Test_OIDC_UnverifiedEmailOfExistingUser() {
+	envy.Temp(func() {
+		issuer := as.useMockOIDCIssuer()
+		defer issuer.Close()
+		as.logInAs("oidc-taken@example.com")
+		issuer.Identity = oidctest.Identity{Subject: "oidc-2", Email: "oidc-taken@example.com", EmailVerified: false, Name: "Taken"}
+
+		res := as.JSON("/api/v1/auth/oidc/mock/callback?" + as.oidcSignIn(issuer).Encode()).Get()
+		as.Equal(http.StatusConflict, res.Code)
+	})
+}
+
+func (as *ActionSuite) Test_OIDC_UnknownProvider() {
+	res := as.HTML("/api/v1/auth/oidc/nobody/authorize").Get()
+	as.Equal(http.StatusNotFound, res.Code)
+}
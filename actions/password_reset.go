@@ -0,0 +1,149 @@
+package actions
+
+import (
+	"blog/mailers"
+	"blog/models"
+	"blog/utils"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/pkg/errors"
+)
+
+// ForgotPasswordPayload - Request body to ask for a password reset link
+type ForgotPasswordPayload struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordPayload - Request body to set a new password with a reset token
+type ResetPasswordPayload struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// ForgotPassword - Mail a password reset link, the response is the same whether the email
+// belongs to an account or not
+func ForgotPassword(c buffalo.Context) error {
+	request := &ForgotPasswordPayload{}
+	c.Bind(request)
+
+	verrs := validate.Validate(
+		&validators.EmailIsPresent{Field: request.Email, Name: "email"},
+	)
+	if verrs.HasAny() {
+		errorResponse := utils.NewValidationErrorResponse(http.StatusUnprocessableEntity, verrs.Errors)
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	// the lookup, the token and the mail all happen after the response, so known and unknown
+	// emails cost the request the same
+	email := strings.ToLower(request.Email)
+	runInBackground(c, "unable to send the password reset mail", func() error {
+		return sendPasswordReset(email)
+	})
+
+	return c.Render(http.StatusAccepted, r.JSON(MessageResponse{
+		Code:    fmt.Sprintf("%d", http.StatusAccepted),
+		Message: "If the email belongs to an account, a password reset link has been sent to it",
+	}))
+}
+
+// ResetPassword - Set a new password with a single use reset token and revoke the existing sessions
+func ResetPassword(c buffalo.Context) error {
+	request := &ResetPasswordPayload{}
+	c.Bind(request)
+
+	verrs := validate.Validate(
+		&validators.StringIsPresent{Field: request.Token, Name: "token"},
+		&validators.StringIsPresent{Field: request.Password, Name: "password"},
+	)
+	if verrs.HasAny() {
+		errorResponse := utils.NewValidationErrorResponse(http.StatusUnprocessableEntity, verrs.Errors)
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	tx := c.Value("tx").(*pop.Connection)
+	resetToken, consumeErr := models.ConsumePasswordResetToken(tx, request.Token)
+	if consumeErr != nil {
+		errorResponse := utils.NewErrorResponse(http.StatusUnprocessableEntity, "token", "The password reset link is invalid or has expired")
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	user := &models.User{}
+	if findErr := tx.Find(user, resetToken.UserID); findErr != nil {
+		errorResponse := utils.NewErrorResponse(http.StatusUnprocessableEntity, "token", "The password reset link is invalid or has expired")
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	// a rejected password rolls the transaction back, so the reset token stays usable
+	verrs = validate.Validate(
+		&utils.PasswordPolicyValidator{Field: request.Password, Name: "password", Personal: []string{user.Name, user.Email}},
+	)
+	if verrs.HasAny() {
+		errorResponse := utils.NewValidationErrorResponse(http.StatusUnprocessableEntity, verrs.Errors)
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	if err := user.UpdatePassword(tx, request.Password); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	if err := user.RevokeAllTokens(tx); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	if err := recordAudit(c, tx, &models.AuditEvent{
+		Action:     models.AuditTokenRevoked,
+		ActorID:    nulls.NewUUID(user.ID),
+		TargetType: "user",
+		TargetID:   user.ID.String(),
+		Metadata:   models.AuditMetadata{"reason": "password_reset"},
+	}); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(MessageResponse{
+		Code:    fmt.Sprintf("%d", http.StatusOK),
+		Message: "Your password has been reset, please log in again",
+	}))
+}
+
+// sendPasswordReset - Issue a reset token to the account of the email and mail the link once the
+// token is committed, unknown emails get nothing
+func sendPasswordReset(email string) error {
+	user := &models.User{}
+	plainToken := ""
+	err := models.DB.Transaction(func(tx *pop.Connection) error {
+		findErr := tx.Where("email = ?", email).First(user)
+		if errors.Cause(findErr) == sql.ErrNoRows {
+			return nil
+		}
+		if findErr != nil {
+			return findErr
+		}
+
+		var issueErr error
+		plainToken, issueErr = models.IssuePasswordResetToken(tx, user.ID, utils.DurationFromEnv("PASSWORD_RESET_TTL", time.Hour))
+		return issueErr
+	})
+	if err != nil || plainToken == "" {
+		return err
+	}
+
+	return mailers.SendPasswordReset(*user, passwordResetURL(plainToken))
+}
+
+// passwordResetURL - Link of the front end page that submits the reset token
+func passwordResetURL(plainToken string) string {
+	resetURL := envy.Get("PASSWORD_RESET_URL", "http://127.0.0.1:3000/password/reset")
+
+	return fmt.Sprintf("%s?token=%s", resetURL, url.QueryEscape(plainToken))
+}
@@ -0,0 +1,49 @@
+package actions
+
+import (
+	"blog/models"
+	"net/http"
+	"time"
+)
+
+func (as *ActionSuite) Test_ForgotPassword_DoesNotRevealEmail() {
+	user := &models.User{Email: "forgot@example.com", Password: "secret", Name: "Forgot"}
+	_, err := user.Create(models.DB)
+	as.NoError(err)
+
+	known := as.JSON("/api/v1/auth/password/forgot").Post(ForgotPasswordPayload{Email: "forgot@example.com"})
+	unknown := as.JSON("/api/v1/auth/password/forgot").Post(ForgotPasswordPayload{Email: "nobody@example.com"})
+
+	as.Equal(http.StatusAccepted, known.Code)
+	as.Equal(known.Code, unknown.Code)
+	as.Equal(known.Body.String(), unknown.Body.String())
+
+	// the token is issued after the response
+	backgroundJobs.Wait()
+	count, err := models.DB.Where("user_id = ? AND used_at IS NULL", user.ID).Count(&models.PasswordResetToken{})
+	as.NoError(err)
+	as.Equal(1, count)
+}
+
+func (as *ActionSuite) Test_ResetPassword() {
+	user := &models.User{Email: "reset@example.com", Password: "secret", Name: "Reset"}
+	_, err := user.Create(models.DB)
+	as.NoError(err)
+
+	plainToken, err := models.IssuePasswordResetToken(models.DB, user.ID, time.Hour)
+	as.NoError(err)
+
+	res := as.JSON("/api/v1/auth/password/reset").Post(ResetPasswordPayload{Token: plainToken, Password: "new-secret"})
+	as.Equal(http.StatusOK, res.Code)
+
+	revoked := &models.AuditEvent{}
+	as.NoError(models.DB.Where("action = ? AND target_id = ?", models.AuditTokenRevoked, user.ID.String()).First(revoked))
+	as.Equal("password_reset", revoked.Metadata["reason"])
+
+	res = as.JSON("/api/v1/auth/login").Post(LogInPayload{Email: "reset@example.com", Password: "new-secret"})
+	as.Equal(http.StatusOK, res.Code)
+
+	// the token can not be used twice
+	res = as.JSON("/api/v1/auth/password/reset").Post(ResetPasswordPayload{Token: plainToken, Password: "other-secret"})
+	as.Equal(http.StatusUnprocessableEntity, res.Code)
+}
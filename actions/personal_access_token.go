@@ -0,0 +1,115 @@
+package actions
+
+import (
+	"blog/models"
+	"blog/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+)
+
+// PersonalAccessTokenPayload - Request body to create a personal access token, the expiry is optional
+type PersonalAccessTokenPayload struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// PersonalAccessTokenResponse - The created token, the plain value is only shown once
+type PersonalAccessTokenResponse struct {
+	Token               string                      `json:"token"`
+	PersonalAccessToken *models.PersonalAccessToken `json:"personal_access_token"`
+}
+
+// ListPersonalAccessTokens - List the personal access tokens of the authenticated user
+func ListPersonalAccessTokens(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	accessTokens := &models.PersonalAccessTokens{}
+	if err := tx.Where("user_id = ?", authUser.ID).Order("created_at desc").All(accessTokens); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(accessTokens))
+}
+
+// CreatePersonalAccessToken - Create a named personal access token with the requested scopes for the authenticated user
+func CreatePersonalAccessToken(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	request := &PersonalAccessTokenPayload{}
+	if err := c.Bind(request); err != nil {
+		errorResponse := utils.NewErrorResponse(http.StatusBadRequest, "name", "The request body is invalid")
+		return c.Render(http.StatusBadRequest, r.JSON(errorResponse))
+	}
+	if request.ExpiresInDays < 0 {
+		errorResponse := utils.NewErrorResponse(http.StatusUnprocessableEntity, "expires_in_days", "The expiry must be a positive number of days")
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	accessToken := &models.PersonalAccessToken{UserID: authUser.ID, Name: request.Name, Scopes: utils.FormatScopes(request.Scopes)}
+	if request.ExpiresInDays > 0 {
+		accessToken.ExpiresAt = nulls.NewTime(time.Now().AddDate(0, 0, request.ExpiresInDays))
+	}
+
+	plainToken, verrs, err := models.CreatePersonalAccessToken(tx, accessToken)
+	if err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	if verrs.HasAny() {
+		errorResponse := utils.NewValidationErrorResponse(http.StatusUnprocessableEntity, verrs.Errors)
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+	if err := recordAudit(c, tx, &models.AuditEvent{
+		Action:     models.AuditTokenIssued,
+		TargetType: "personal_access_token",
+		TargetID:   accessToken.ID.String(),
+		Metadata:   models.AuditMetadata{"name": accessToken.Name, "scope": accessToken.Scopes},
+	}); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusCreated, r.JSON(PersonalAccessTokenResponse{
+		Token:               plainToken,
+		PersonalAccessToken: accessToken,
+	}))
+}
+
+// RevokePersonalAccessToken - Revoke a personal access token of the authenticated user
+func RevokePersonalAccessToken(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	accessToken := &models.PersonalAccessToken{}
+	if err := tx.Where("user_id = ?", authUser.ID).Find(accessToken, c.Param("token_id")); err != nil {
+		notFoundResponse := utils.NewErrorResponse(
+			http.StatusNotFound,
+			"token_id",
+			fmt.Sprintf("The requested personal access token %s does not exist.", c.Param("token_id")),
+		)
+		return c.Render(http.StatusNotFound, r.JSON(notFoundResponse))
+	}
+
+	if err := accessToken.Revoke(tx); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	if err := recordAudit(c, tx, &models.AuditEvent{
+		Action:     models.AuditTokenRevoked,
+		TargetType: "personal_access_token",
+		TargetID:   accessToken.ID.String(),
+		Metadata:   models.AuditMetadata{"name": accessToken.Name},
+	}); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(MessageResponse{
+		Code:    fmt.Sprintf("%d", http.StatusOK),
+		Message: "The personal access token has been revoked",
+	}))
+}
@@ -0,0 +1,86 @@
+package actions
+
+import (
+	"blog/utils"
+	"fmt"
+	"net/http"
+)
+
+func (as *ActionSuite) Test_PersonalAccessToken_Authenticates() {
+	_, accessToken := as.logInAs("pat@example.com")
+
+	req := as.JSON("/api/v1/auth/tokens")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	res := req.Post(PersonalAccessTokenPayload{Name: "ci", Scopes: []string{utils.ScopePostsRead}, ExpiresInDays: 30})
+	as.Equal(http.StatusCreated, res.Code)
+
+	created := &PersonalAccessTokenResponse{}
+	res.Bind(created)
+	as.NotEmpty(created.Token)
+
+	req = as.JSON("/api/v1/auth/user")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", created.Token)
+	res = req.Get()
+	as.Equal(http.StatusOK, res.Code)
+
+	req = as.JSON("/api/v1/auth/tokens/%s", created.PersonalAccessToken.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	res = req.Delete()
+	as.Equal(http.StatusOK, res.Code)
+
+	req = as.JSON("/api/v1/auth/user")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", created.Token)
+	res = req.Get()
+	as.Equal(http.StatusUnauthorized, res.Code)
+}
+
+func (as *ActionSuite) Test_PersonalAccessToken_MissingScope() {
+	_, accessToken := as.logInAs("pat-scope@example.com")
+
+	req := as.JSON("/api/v1/auth/tokens")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	res := req.Post(PersonalAccessTokenPayload{Name: "reader", Scopes: []string{utils.ScopePostsRead}})
+	as.Equal(http.StatusCreated, res.Code)
+
+	created := &PersonalAccessTokenResponse{}
+	res.Bind(created)
+
+	req = as.JSON("/api/v1/posts/")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", created.Token)
+	res = req.Get()
+	as.Equal(http.StatusOK, res.Code)
+
+	req = as.JSON("/api/v1/auth/tokens")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", created.Token)
+	res = req.Get()
+	as.Equal(http.StatusForbidden, res.Code)
+	as.Contains(res.Body.String(), utils.ScopeAccountAdmin)
+}
+
+func (as *ActionSuite) Test_PersonalAccessToken_UnknownScope() {
+	_, accessToken := as.logInAs("pat-unknown@example.com")
+
+	req := as.JSON("/api/v1/auth/tokens")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	res := req.Post(PersonalAccessTokenPayload{Name: "ci", Scopes: []string{"posts:admin"}})
+	as.Equal(http.StatusUnprocessableEntity, res.Code)
+}
+
+func (as *ActionSuite) Test_PersonalAccessToken_RevokedByLogOutAll() {
+	_, accessToken := as.logInAs("pat-logout-all@example.com")
+
+	req := as.JSON("/api/v1/auth/tokens")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	res := req.Post(PersonalAccessTokenPayload{Name: "ci", Scopes: []string{utils.ScopePostsRead}})
+	as.Equal(http.StatusCreated, res.Code)
+	created := &PersonalAccessTokenResponse{}
+	res.Bind(created)
+
+	req = as.JSON("/api/v1/auth/logout-all")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	as.Equal(http.StatusOK, req.Post(LogOutPayload{}).Code)
+
+	req = as.JSON("/api/v1/posts/")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", created.Token)
+	as.Equal(http.StatusUnauthorized, req.Get().Code)
+}
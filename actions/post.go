@@ -2,12 +2,16 @@ package actions
 
 import (
 	"blog/models"
+	"blog/policies"
 	"blog/utils"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
 	"github.com/pkg/errors"
 )
 
@@ -24,6 +28,19 @@ type PostResponse struct {
 	Data *models.Post `json:"data"`
 }
 
+// PublishPostPayload - Request body to publish a post, a published_at in the future schedules it
+type PublishPostPayload struct {
+	PublishedAt *time.Time `json:"published_at"`
+}
+
+// UnpublishPostPayload - Request body to take a post down, archived posts keep their publication time
+type UnpublishPostPayload struct {
+	Archive bool `json:"archive"`
+}
+
+// postClock - time source of the publishing workflow, tests swap it for a fixed clock
+var postClock utils.Clock = utils.SystemClock
+
 // PostDeletedResponse - Response body when post get removed
 type PostDeletedResponse struct {
 	Code string       `json:"code"`
@@ -33,11 +50,34 @@ type PostDeletedResponse struct {
 // ListPost - list a collection of post with user
 func ListPost(c buffalo.Context) error {
 
+	authUser := c.Value("authUser").(models.User)
 	db := c.Value("tx").(*pop.Connection)
 
 	posts := &models.Posts{}
 
-	query := db.PaginateFromParams(c.Params())
+	query := db.PaginateFromParams(c.Params()).Scope(models.PostsNotDeleted)
+	// an unpublished post nobody owns is only visible to those who see every post
+	if !policies.Can(authUser, policies.ActionRead, &models.Post{Status: models.PostStatusDraft}) {
+		query = query.Where("(status = ? OR user_id = ?)", models.PostStatusPublished, authUser.ID)
+	}
+	if status := c.Param("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if tag := c.Param("tag"); tag != "" {
+		query = query.Scope(models.PostsTagged(utils.Slugify(tag)))
+	}
+	if categoryParam := c.Param("category"); categoryParam != "" {
+		// a category lists the posts of its subcategories as well
+		category, err := models.FindCategoryByIDOrSlug(db, categoryParam)
+		if err != nil {
+			return renderCategoryNotFound(c, "category", categoryParam)
+		}
+		categoryIDs, err := models.CategoryDescendantIDs(db, category.ID)
+		if err != nil {
+			return c.Error(http.StatusInternalServerError, err)
+		}
+		query = query.Scope(models.PostsInCategories(categoryIDs))
+	}
 
 	if err := query.Order("created_at desc").Eager().All(posts); err != nil {
 
@@ -58,13 +98,39 @@ func ListPost(c buffalo.Context) error {
 func CreatePost(c buffalo.Context) error {
 	authUser := c.Value("authUser").(models.User)
 	post := &models.Post{}
+	if !policies.Can(authUser, policies.ActionCreate, post) {
+		forbiddenResponse := utils.NewErrorResponse(http.StatusForbidden, "role", "Your role is not allowed to write posts")
+		return c.Render(http.StatusForbidden, r.JSON(forbiddenResponse))
+	}
 	if err := c.Bind(post); err != nil {
 		return errors.WithStack(err)
 	}
 	db := c.Value("tx").(*pop.Connection)
 	post.UserID = authUser.ID
 	post.User = &authUser
-	validationErrors, err := db.Eager().ValidateAndCreate(post)
+	// posts start as drafts, they go live through the publish endpoint
+	post.Status = models.PostStatusDraft
+	post.PublishedAt = nulls.Time{}
+	// posts only go to the trash through the delete endpoint
+	post.DeletedAt = nulls.Time{}
+	// the slug is built from the title
+	post.Slug = ""
+	// the tags are given by name, they are attached once the post exists
+	tags, tagErrors, err := models.ResolveTags(db, post.Tags.Names())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if tagErrors.HasAny() {
+		errResponse := utils.NewValidationErrorResponse(http.StatusUnprocessableEntity, tagErrors.Errors)
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errResponse))
+	}
+	post.Tags = nil
+	var validationErrors *validate.Errors
+	err = models.SavePostWithUniqueSlug(db, post, func() error {
+		var createErr error
+		validationErrors, createErr = db.Eager().ValidateAndCreate(post)
+		return createErr
+	})
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -76,6 +142,15 @@ func CreatePost(c buffalo.Context) error {
 		)
 		return c.Render(http.StatusUnprocessableEntity, r.JSON(errResponse))
 	}
+	if err := models.SetPostTags(db, post, tags); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := models.RecordPostRevision(db, post, nulls.NewUUID(authUser.ID)); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := recordPostAudit(c, db, models.AuditPostCreated, post, models.DiffAudit(nil, post, "user")); err != nil {
+		return errors.WithStack(err)
+	}
 
 	postResponse := PostResponse{
 		Code: fmt.Sprintf("%d", http.StatusCreated),
@@ -84,13 +159,24 @@ func CreatePost(c buffalo.Context) error {
 	return c.Render(http.StatusCreated, r.JSON(postResponse))
 }
 
-// ShowPost - update the post based on the given ID
+// ShowPost - update the post based on the given ID or slug, a former slug answers with a
+// permanent redirect to the current one
 func ShowPost(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
 	database := c.Value("tx").(*pop.Connection)
 
 	post := &models.Post{}
+	txErr := models.FindPostByIDOrSlug(database.Scope(models.PostsNotDeleted).Eager(), post, c.Param("post_id"))
+	redirect := false
+	if txErr != nil {
+		if slugRedirect, redirectErr := models.FindPostSlugRedirect(database, c.Param("post_id")); redirectErr == nil {
+			txErr = database.Scope(models.PostsNotDeleted).Find(post, slugRedirect.PostID)
+			redirect = true
+		}
+	}
 
-	if txErr := database.Eager().Find(post, c.Param("post_id")); txErr != nil {
+	// unpublished posts of someone else do not exist for the caller
+	if txErr != nil || !policies.Can(authUser, policies.ActionRead, post) {
 
 		notFoundResponse := utils.NewErrorResponse(
 			http.StatusNotFound,
@@ -99,6 +185,9 @@ func ShowPost(c buffalo.Context) error {
 		)
 		return c.Render(http.StatusNotFound, r.JSON(notFoundResponse))
 	}
+	if redirect {
+		return c.Redirect(http.StatusMovedPermanently, "showPostPath()", map[string]interface{}{"post_id": post.Slug})
+	}
 
 	postResponse := PostResponse{
 		Code: fmt.Sprintf("%d", http.StatusOK),
@@ -107,13 +196,13 @@ func ShowPost(c buffalo.Context) error {
 	return c.Render(http.StatusOK, r.JSON(postResponse))
 }
 
-// UpdatePost - Update a single post
+// UpdatePost - Update a single post, the new content is kept as a revision
 func UpdatePost(c buffalo.Context) error {
 	authUser := c.Value("authUser").(models.User)
 	post := &models.Post{}
 	database := c.Value("tx").(*pop.Connection)
 	// retrieve the existing record
-	if txErr := database.Find(post, c.Param("post_id")); txErr != nil {
+	if txErr := database.Scope(models.PostsNotDeleted).Find(post, c.Param("post_id")); txErr != nil {
 
 		notFoundResponse := utils.NewErrorResponse(
 			http.StatusNotFound,
@@ -122,6 +211,18 @@ func UpdatePost(c buffalo.Context) error {
 		)
 		return c.Render(http.StatusNotFound, r.JSON(notFoundResponse))
 	}
+	if err := database.Load(post, "Tags"); err != nil {
+		return errors.WithStack(err)
+	}
+	// editors may update the post of someone else, the post keeps its ID and its author
+	postID, ownerID := post.ID, post.UserID
+	status, publishedAt, slug, deletedAt := post.Status, post.PublishedAt, post.Slug, post.DeletedAt
+	before := *post
+	if err := models.EnsureInitialPostRevision(database, &before); err != nil {
+		return errors.WithStack(err)
+	}
+	// a payload without tags keeps them, an empty list removes them
+	post.Tags = nil
 	// bind the form input
 	if bindErr := c.Bind(post); bindErr != nil {
 		emptyBodyResponse := utils.NewErrorResponse(
@@ -131,8 +232,32 @@ func UpdatePost(c buffalo.Context) error {
 		)
 		return c.Render(http.StatusUnprocessableEntity, r.JSON(emptyBodyResponse))
 	}
-	post.UserID = authUser.ID
-	validationErrors, err := database.ValidateAndUpdate(post)
+	post.ID, post.UserID = postID, ownerID
+	post.Status, post.PublishedAt, post.Slug, post.DeletedAt = status, publishedAt, slug, deletedAt
+	tagsGiven := post.Tags != nil
+	if tagsGiven {
+		tags, tagErrors, err := models.ResolveTags(database, post.Tags.Names())
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if tagErrors.HasAny() {
+			errResponse := utils.NewValidationErrorResponse(http.StatusUnprocessableEntity, tagErrors.Errors)
+			return c.Render(http.StatusUnprocessableEntity, r.JSON(errResponse))
+		}
+		post.Tags = tags
+	} else {
+		post.Tags = before.Tags
+	}
+	// a new title moves the post to a new slug, the former one keeps redirecting
+	if err := post.SyncSlug(database); err != nil {
+		return errors.WithStack(err)
+	}
+	var validationErrors *validate.Errors
+	err := models.SavePostWithUniqueSlug(database, post, func() error {
+		var updateErr error
+		validationErrors, updateErr = database.ValidateAndUpdate(post)
+		return updateErr
+	})
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -144,6 +269,17 @@ func UpdatePost(c buffalo.Context) error {
 		)
 		return c.Render(http.StatusUnprocessableEntity, r.JSON(errResponse))
 	}
+	if tagsGiven {
+		if err := models.SetPostTags(database, post, post.Tags); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if _, err := models.RecordPostRevision(database, post, nulls.NewUUID(authUser.ID)); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := recordPostAudit(c, database, models.AuditPostUpdated, post, models.DiffAudit(&before, post, "user")); err != nil {
+		return errors.WithStack(err)
+	}
 
 	response := PostResponse{
 		Code: fmt.Sprintf("%d", http.StatusOK),
@@ -153,12 +289,12 @@ func UpdatePost(c buffalo.Context) error {
 	return c.Render(http.StatusOK, r.JSON(response))
 }
 
-// DeletePost - Remove a post based on ID
+// DeletePost - Move a post to the trash based on ID, it is purged after the retention period
 func DeletePost(c buffalo.Context) error {
 	post := &models.Post{}
 	database := c.Value("tx").(*pop.Connection)
 
-	txErr := database.Find(post, c.Param("post_id"))
+	txErr := database.Scope(models.PostsNotDeleted).Find(post, c.Param("post_id"))
 	if txErr != nil {
 		notFoundResponse := utils.NewErrorResponse(
 			http.StatusNotFound,
@@ -168,7 +304,8 @@ func DeletePost(c buffalo.Context) error {
 		return c.Render(http.StatusNotFound, r.JSON(notFoundResponse))
 	}
 
-	if deleteErr := database.Destroy(post); deleteErr != nil {
+	before := *post
+	if deleteErr := post.SoftDelete(database, postClock.Now()); deleteErr != nil {
 		deleteErrResponse := utils.NewErrorResponse(
 			http.StatusInternalServerError,
 			"post",
@@ -177,6 +314,9 @@ func DeletePost(c buffalo.Context) error {
 
 		return c.Render(http.StatusInternalServerError, r.JSON(deleteErrResponse))
 	}
+	if err := recordPostAudit(c, database, models.AuditPostDeleted, post, models.DiffAudit(&before, post, "user")); err != nil {
+		return errors.WithStack(err)
+	}
 
 	deleteSuccessResponse := PostDeletedResponse{
 		Code: fmt.Sprintf("%d", http.StatusOK),
@@ -184,3 +324,150 @@ func DeletePost(c buffalo.Context) error {
 	}
 	return c.Render(http.StatusOK, r.JSON(deleteSuccessResponse))
 }
+
+// PublishPost - Publish a post now or schedule it for the given published_at
+func PublishPost(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	post, findErr := findPublishablePost(c, tx)
+	if post == nil {
+		return findErr
+	}
+
+	request := &PublishPostPayload{}
+	c.Bind(request)
+
+	now := postClock.Now()
+	publishAt := now
+	if request.PublishedAt != nil {
+		publishAt = *request.PublishedAt
+	}
+
+	before := *post
+	if err := post.Publish(tx, publishAt, now); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	if err := recordPostAudit(c, tx, models.AuditPostUpdated, post, models.DiffAudit(&before, post, "user")); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(PostResponse{
+		Code: fmt.Sprintf("%d", http.StatusOK),
+		Data: post,
+	}))
+}
+
+// UnpublishPost - Take a post back to a draft, or archive it
+func UnpublishPost(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	post, findErr := findPublishablePost(c, tx)
+	if post == nil {
+		return findErr
+	}
+
+	request := &UnpublishPostPayload{}
+	c.Bind(request)
+
+	status := models.PostStatusDraft
+	if request.Archive {
+		status = models.PostStatusArchived
+	}
+
+	before := *post
+	if err := post.Unpublish(tx, status); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	if err := recordPostAudit(c, tx, models.AuditPostUpdated, post, models.DiffAudit(&before, post, "user")); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(PostResponse{
+		Code: fmt.Sprintf("%d", http.StatusOK),
+		Data: post,
+	}))
+}
+
+// ListTrashedPosts - List the posts in the trash the caller may restore, recently deleted first
+func ListTrashedPosts(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	posts := &models.Posts{}
+	query := tx.PaginateFromParams(c.Params()).Scope(models.PostsInTrash)
+	// a deleted post nobody owns is only restorable by those who may delete every post
+	if !policies.Can(authUser, policies.ActionDelete, &models.Post{}) {
+		query = query.Where("user_id = ?", authUser.ID)
+	}
+	if err := query.Order("deleted_at desc").All(posts); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(PostsResponse{
+		Code: fmt.Sprintf("%d", http.StatusOK),
+		Data: *posts,
+		Meta: *query.Paginator,
+	}))
+}
+
+// RestorePost - Take a post out of the trash
+func RestorePost(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	post := &models.Post{}
+	if err := tx.Scope(models.PostsInTrash).Find(post, c.Param("post_id")); err != nil || !policies.Can(authUser, policies.ActionRead, post) {
+		notFoundResponse := utils.NewErrorResponse(
+			http.StatusNotFound,
+			"post_id",
+			fmt.Sprintf("The requested post %s is not in the trash.", c.Param("post_id")),
+		)
+		return c.Render(http.StatusNotFound, r.JSON(notFoundResponse))
+	}
+	// whoever may delete the post may take it back
+	if !policies.Can(authUser, policies.ActionDelete, post) {
+		return renderForbiddenRole(c)
+	}
+
+	before := *post
+	if err := post.Restore(tx); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	if err := recordPostAudit(c, tx, models.AuditPostRestored, post, models.DiffAudit(&before, post, "user")); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(PostResponse{
+		Code: fmt.Sprintf("%d", http.StatusOK),
+		Data: post,
+	}))
+}
+
+// findPublishablePost - the post of the route when the caller may publish it, otherwise the
+// error response is returned
+func findPublishablePost(c buffalo.Context, tx *pop.Connection) (*models.Post, error) {
+	authUser := c.Value("authUser").(models.User)
+
+	post := &models.Post{}
+	if err := tx.Scope(models.PostsNotDeleted).Find(post, c.Param("post_id")); err != nil || !policies.Can(authUser, policies.ActionRead, post) {
+		notFoundResponse := utils.NewErrorResponse(
+			http.StatusNotFound,
+			"post_id",
+			fmt.Sprintf("The requested post %s is removed or move to somewhere else.", c.Param("post_id")),
+		)
+		return nil, c.Render(http.StatusNotFound, r.JSON(notFoundResponse))
+	}
+	if !policies.Can(authUser, policies.ActionPublish, post) {
+		return nil, renderForbiddenRole(c)
+	}
+
+	return post, nil
+}
+
+// recordPostAudit - append a post event with the changed fields to the audit trail
+func recordPostAudit(c buffalo.Context, tx *pop.Connection, action string, post *models.Post, changes models.AuditChanges) error {
+	return recordAudit(c, tx, &models.AuditEvent{
+		Action:     action,
+		TargetType: "post",
+		TargetID:   post.ID.String(),
+		Changes:    changes,
+	})
+}
@@ -0,0 +1,158 @@
+package actions
+
+import (
+	"blog/models"
+	"blog/policies"
+	"blog/utils"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+)
+
+// PostRevisionsResponse - Post revisions collection response body
+type PostRevisionsResponse struct {
+	Code string               `json:"code"`
+	Data models.PostRevisions `json:"data"`
+	Meta pop.Paginator        `json:"meta"`
+}
+
+// PostRevisionResponse - Single post revision response body
+type PostRevisionResponse struct {
+	Code string               `json:"code"`
+	Data *models.PostRevision `json:"data"`
+}
+
+// PostRevisionDiff - The line level changes of the title and the description between two revisions
+type PostRevisionDiff struct {
+	From        int              `json:"from"`
+	To          int              `json:"to"`
+	Title       []utils.DiffLine `json:"title"`
+	Description []utils.DiffLine `json:"description"`
+}
+
+// PostRevisionDiffResponse - Post revision diff response body
+type PostRevisionDiffResponse struct {
+	Code string           `json:"code"`
+	Data PostRevisionDiff `json:"data"`
+}
+
+// ListPostRevisions - List the revisions of a post, newest first
+func ListPostRevisions(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	post, findErr := findRevisedPost(c, tx)
+	if post == nil {
+		return findErr
+	}
+
+	revisions := &models.PostRevisions{}
+	query := tx.PaginateFromParams(c.Params()).Where("post_id = ?", post.ID)
+	if err := query.Order("number desc").All(revisions); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(PostRevisionsResponse{
+		Code: fmt.Sprintf("%d", http.StatusOK),
+		Data: *revisions,
+		Meta: *query.Paginator,
+	}))
+}
+
+// DiffPostRevisions - Compare the revisions given by the from and to numbers line by line
+func DiffPostRevisions(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	post, findErr := findRevisedPost(c, tx)
+	if post == nil {
+		return findErr
+	}
+
+	from, fromErr := findPostRevision(c, tx, post, "from")
+	if from == nil {
+		return fromErr
+	}
+	to, toErr := findPostRevision(c, tx, post, "to")
+	if to == nil {
+		return toErr
+	}
+
+	return c.Render(http.StatusOK, r.JSON(PostRevisionDiffResponse{
+		Code: fmt.Sprintf("%d", http.StatusOK),
+		Data: PostRevisionDiff{
+			From:        from.Number,
+			To:          to.Number,
+			Title:       utils.DiffLines(from.Title, to.Title),
+			Description: utils.DiffLines(from.Description, to.Description),
+		},
+	}))
+}
+
+// RestorePostRevision - Put the content of a revision back into the post
+func RestorePostRevision(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+	post, findErr := findRevisedPost(c, tx)
+	if post == nil {
+		return findErr
+	}
+
+	revision, revisionErr := findPostRevision(c, tx, post, "revision")
+	if revision == nil {
+		return revisionErr
+	}
+
+	before := *post
+	restored, err := models.RestorePostRevision(tx, post, revision, nulls.NewUUID(authUser.ID))
+	if err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	if err := recordPostAudit(c, tx, models.AuditPostUpdated, post, models.DiffAudit(&before, post, "user")); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(PostRevisionResponse{
+		Code: fmt.Sprintf("%d", http.StatusOK),
+		Data: restored,
+	}))
+}
+
+// findRevisedPost - the post of the route when the caller may edit it, the history holds
+// content that may never have been published. Otherwise the error response is returned.
+func findRevisedPost(c buffalo.Context, tx *pop.Connection) (*models.Post, error) {
+	authUser := c.Value("authUser").(models.User)
+
+	post := &models.Post{}
+	if err := tx.Scope(models.PostsNotDeleted).Find(post, c.Param("post_id")); err != nil || !policies.Can(authUser, policies.ActionRead, post) {
+		notFoundResponse := utils.NewErrorResponse(
+			http.StatusNotFound,
+			"post_id",
+			fmt.Sprintf("The requested post %s is removed or move to somewhere else.", c.Param("post_id")),
+		)
+		return nil, c.Render(http.StatusNotFound, r.JSON(notFoundResponse))
+	}
+	if !policies.Can(authUser, policies.ActionUpdate, post) {
+		return nil, renderForbiddenRole(c)
+	}
+
+	return post, nil
+}
+
+// findPostRevision - the revision of the post numbered by the param, otherwise the error
+// response is returned
+func findPostRevision(c buffalo.Context, tx *pop.Connection, post *models.Post, param string) (*models.PostRevision, error) {
+	number, parseErr := strconv.Atoi(c.Param(param))
+	if parseErr == nil {
+		if revision, err := models.FindPostRevision(tx, post.ID, number); err == nil {
+			return revision, nil
+		}
+	}
+
+	notFoundResponse := utils.NewErrorResponse(
+		http.StatusNotFound,
+		param,
+		fmt.Sprintf("The post has no revision %s.", c.Param(param)),
+	)
+	return nil, c.Render(http.StatusNotFound, r.JSON(notFoundResponse))
+}
@@ -0,0 +1,57 @@
+package actions
+
+import (
+	"blog/models"
+	"blog/utils"
+	"fmt"
+	"net/http"
+)
+
+func (as *ActionSuite) Test_PostRevisions_DiffAndRestore() {
+	author, authorToken := as.logInAs("revising@example.com")
+	as.NoError(author.MarkEmailVerified(models.DB))
+	_, otherToken := as.logInAs("not-the-author@example.com")
+
+	req := as.JSON("/api/v1/posts/create")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	as.Equal(http.StatusCreated, req.Post(map[string]string{"title": "Revised post", "description": "one\ntwo"}).Code)
+
+	post := &models.Post{}
+	as.NoError(models.DB.Where("user_id = ?", author.ID).First(post))
+
+	req = as.JSON("/api/v1/posts/%s", post.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	as.Equal(http.StatusOK, req.Put(map[string]string{"title": "Revised post", "description": "one\n2"}).Code)
+
+	req = as.JSON("/api/v1/posts/%s/revisions", post.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", otherToken)
+	as.Equal(http.StatusNotFound, req.Get().Code)
+
+	req = as.JSON("/api/v1/posts/%s/revisions", post.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	revisions := &PostRevisionsResponse{}
+	req.Get().Bind(revisions)
+	as.Len(revisions.Data, 2)
+	as.Equal(2, revisions.Data[0].Number)
+
+	req = as.JSON("/api/v1/posts/%s/revisions/diff?from=1&to=2", post.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	diff := &PostRevisionDiffResponse{}
+	req.Get().Bind(diff)
+	as.Equal([]utils.DiffLine{
+		{Op: utils.DiffEqual, Text: "one"},
+		{Op: utils.DiffDelete, Text: "two"},
+		{Op: utils.DiffInsert, Text: "2"},
+	}, diff.Data.Description)
+
+	req = as.JSON("/api/v1/posts/%s/revisions/9/restore", post.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	as.Equal(http.StatusNotFound, req.Post(nil).Code)
+
+	req = as.JSON("/api/v1/posts/%s/revisions/1/restore", post.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	as.Equal(http.StatusOK, req.Post(nil).Code)
+
+	as.NoError(models.DB.Find(post, post.ID))
+	as.Equal("one\ntwo", post.Description)
+}
@@ -1,6 +1,230 @@
 package actions
 
+import (
+	"blog/models"
+	"blog/utils"
+	"fmt"
+	"net/http"
+	"time"
+)
+
 func (as *ActionSuite) Test_Post_List() {
 	as.Fail("Not Implemented!")
 }
 
+func (as *ActionSuite) Test_Post_PublishingWorkflow() {
+	author, authorToken := as.logInAs("publisher@example.com")
+	_, readerToken := as.logInAs("visitor@example.com")
+
+	post := &models.Post{Title: "Draft post", Description: "Body", UserID: author.ID}
+	as.NoError(models.DB.Create(post))
+	as.Equal(models.PostStatusDraft, post.Status)
+
+	req := as.JSON("/api/v1/posts/%s", post.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", readerToken)
+	as.Equal(http.StatusNotFound, req.Get().Code)
+
+	req = as.JSON("/api/v1/posts/%s/publish", post.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", readerToken)
+	as.Equal(http.StatusNotFound, req.Post(PublishPostPayload{}).Code)
+
+	// a publication time in the future schedules the post
+	publishAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	req = as.JSON("/api/v1/posts/%s/publish", post.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	res := req.Post(PublishPostPayload{PublishedAt: &publishAt})
+	as.Equal(http.StatusOK, res.Code)
+
+	stored := &models.Post{}
+	as.NoError(models.DB.Find(stored, post.ID))
+	as.Equal(models.PostStatusScheduled, stored.Status)
+
+	req = as.JSON("/api/v1/posts/")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", readerToken)
+	listed := &PostsResponse{}
+	req.Get().Bind(listed)
+	as.Len(listed.Data, 0)
+
+	req = as.JSON("/api/v1/posts/%s/publish", post.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	as.Equal(http.StatusOK, req.Post(PublishPostPayload{}).Code)
+
+	req = as.JSON("/api/v1/posts/")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", readerToken)
+	req.Get().Bind(listed)
+	as.Len(listed.Data, 1)
+	as.Equal(models.PostStatusPublished, listed.Data[0].Status)
+
+	req = as.JSON("/api/v1/posts/%s/unpublish", post.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	as.Equal(http.StatusOK, req.Post(UnpublishPostPayload{Archive: true}).Code)
+
+	as.NoError(models.DB.Find(stored, post.ID))
+	as.Equal(models.PostStatusArchived, stored.Status)
+	as.True(stored.PublishedAt.Valid)
+}
+
+func (as *ActionSuite) Test_Post_ListFilterKeepsVisibility() {
+	author, authorToken := as.logInAs("filtering@example.com")
+	other, otherToken := as.logInAs("other-author@example.com")
+
+	as.NoError(models.DB.Create(&models.Post{Title: "Own draft", Description: "Body", UserID: author.ID}))
+	as.NoError(models.DB.Create(&models.Post{Title: "Other draft", Description: "Body", UserID: other.ID}))
+	as.NoError(models.DB.Create(&models.Post{Title: "Other published", Description: "Body", UserID: other.ID, Status: models.PostStatusPublished}))
+
+	// the filter narrows the visible posts, the published posts of others do not slip through
+	req := as.JSON("/api/v1/posts/?status=%s", models.PostStatusDraft)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	listed := &PostsResponse{}
+	req.Get().Bind(listed)
+	as.Len(listed.Data, 1)
+	as.Equal("Own draft", listed.Data[0].Title)
+
+	req = as.JSON("/api/v1/posts/?status=%s", models.PostStatusScheduled)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", otherToken)
+	req.Get().Bind(listed)
+	as.Len(listed.Data, 0)
+}
+
+func (as *ActionSuite) Test_Post_PublishUsesPostClock() {
+	previousClock := postClock
+	defer func() { postClock = previousClock }()
+	publishedAt := time.Date(2021, 5, 10, 9, 0, 0, 0, time.UTC)
+	postClock = utils.FixedClock{At: publishedAt}
+
+	author, authorToken := as.logInAs("clocked@example.com")
+	post := &models.Post{Title: "Clocked post", Description: "Body", UserID: author.ID}
+	as.NoError(models.DB.Create(post))
+
+	req := as.JSON("/api/v1/posts/%s/publish", post.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	as.Equal(http.StatusOK, req.Post(PublishPostPayload{}).Code)
+
+	stored := &models.Post{}
+	as.NoError(models.DB.Find(stored, post.ID))
+	as.Equal(models.PostStatusPublished, stored.Status)
+	as.True(publishedAt.Equal(stored.PublishedAt.Time))
+}
+
+func (as *ActionSuite) Test_Post_TrashAndRestore() {
+	author, authorToken := as.logInAs("trashing@example.com")
+	_, otherToken := as.logInAs("bystander@example.com")
+
+	post := &models.Post{Title: "Going away", Description: "Body", UserID: author.ID, Status: models.PostStatusPublished}
+	as.NoError(models.DB.Create(post))
+
+	req := as.JSON("/api/v1/posts/%s", post.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	as.Equal(http.StatusOK, req.Delete().Code)
+
+	stored := &models.Post{}
+	as.NoError(models.DB.Find(stored, post.ID))
+	as.True(stored.IsDeleted())
+
+	req = as.JSON("/api/v1/posts/%s", post.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", otherToken)
+	as.Equal(http.StatusNotFound, req.Get().Code)
+
+	req = as.JSON("/api/v1/posts/trash")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", otherToken)
+	trash := &PostsResponse{}
+	req.Get().Bind(trash)
+	as.Len(trash.Data, 0)
+
+	req = as.JSON("/api/v1/posts/trash")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	req.Get().Bind(trash)
+	as.Len(trash.Data, 1)
+
+	req = as.JSON("/api/v1/posts/%s/restore", post.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", otherToken)
+	as.Equal(http.StatusForbidden, req.Post(nil).Code)
+
+	req = as.JSON("/api/v1/posts/%s/restore", post.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	as.Equal(http.StatusOK, req.Post(nil).Code)
+
+	req = as.JSON("/api/v1/posts/%s", post.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", otherToken)
+	as.Equal(http.StatusOK, req.Get().Code)
+}
+
+func (as *ActionSuite) Test_Post_UpdateIgnoresBoundID() {
+	author, authorToken := as.logInAs("own-post@example.com")
+	other, _ := as.logInAs("foreign-post@example.com")
+
+	own := &models.Post{Title: "Own post", Description: "Body", UserID: author.ID}
+	as.NoError(models.DB.Create(own))
+	foreign := &models.Post{Title: "Foreign post", Description: "Untouched", UserID: other.ID, Status: models.PostStatusPublished}
+	as.NoError(models.DB.Create(foreign))
+
+	req := as.JSON("/api/v1/posts/%s", own.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	res := req.Put(map[string]string{"id": foreign.ID.String(), "title": "Own post", "description": "Edited"})
+	as.Equal(http.StatusOK, res.Code)
+
+	stored := &models.Post{}
+	as.NoError(models.DB.Find(stored, foreign.ID))
+	as.Equal("Untouched", stored.Description)
+	as.Equal(other.ID, stored.UserID)
+
+	as.NoError(models.DB.Find(stored, own.ID))
+	as.Equal("Edited", stored.Description)
+}
+
+func (as *ActionSuite) Test_Post_DeletedAtIsNotBound() {
+	author, authorToken := as.logInAs("no-trash@example.com")
+	as.NoError(author.MarkEmailVerified(models.DB))
+
+	req := as.JSON("/api/v1/posts/create")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	res := req.Post(map[string]string{"title": "Kept", "description": "Body", "deleted_at": "2021-05-10T09:00:00Z"})
+	as.Equal(http.StatusCreated, res.Code)
+
+	post := &models.Post{}
+	as.NoError(models.DB.Where("user_id = ?", author.ID).First(post))
+	as.False(post.IsDeleted())
+
+	req = as.JSON("/api/v1/posts/%s", post.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	as.Equal(http.StatusOK, req.Put(map[string]string{"title": "Kept", "description": "Edited", "deleted_at": "2021-05-10T09:00:00Z"}).Code)
+
+	as.NoError(models.DB.Find(post, post.ID))
+	as.False(post.IsDeleted())
+}
+
+func (as *ActionSuite) Test_Post_ShowBySlug() {
+	author, authorToken := as.logInAs("permalinks@example.com")
+
+	post := &models.Post{Title: "Hello World", Description: "Body", UserID: author.ID, Status: models.PostStatusPublished}
+	as.NoError(models.DB.Create(post))
+
+	req := as.JSON("/api/v1/posts/hello-world")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	as.Equal(http.StatusOK, req.Get().Code)
+
+	req = as.JSON("/api/v1/posts/%s", post.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	as.Equal(http.StatusOK, req.Put(map[string]string{"title": "Hello Again", "description": "Body"}).Code)
+
+	req = as.JSON("/api/v1/posts/hello-world")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	res := req.Get()
+	as.Equal(http.StatusMovedPermanently, res.Code)
+	as.Contains(res.Header().Get("Location"), "/api/v1/posts/hello-again")
+
+	// the slug of the payload is ignored, a retired permalink keeps pointing at its post
+	other, otherToken := as.logInAs("permalink-taker@example.com")
+	as.NoError(other.MarkEmailVerified(models.DB))
+	req = as.JSON("/api/v1/posts/create")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", otherToken)
+	res = req.Post(map[string]string{"title": "Taking over", "description": "Body", "slug": "hello-world"})
+	as.Equal(http.StatusCreated, res.Code)
+	created := PostResponse{}
+	res.Bind(&created)
+	as.Equal("taking-over", created.Data.Slug)
+
+	req = as.JSON("/api/v1/posts/hello-world")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	as.Equal(http.StatusMovedPermanently, req.Get().Code)
+}
@@ -0,0 +1,130 @@
+package actions
+
+import (
+	"blog/models"
+	"blog/utils"
+	"fmt"
+	"net/http"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+)
+
+// UpdateProfilePayload - Request body of a profile update, omitted fields are kept
+type UpdateProfilePayload struct {
+	Name  *string `json:"name"`
+	Email *string `json:"email"`
+}
+
+// ChangePasswordPayload - Request body of a password change
+type ChangePasswordPayload struct {
+	CurrentPassword string `json:"current_password"`
+	Password        string `json:"password"`
+}
+
+// UpdateProfile - Update the name and email of the authenticated user, a new email has to be verified again
+func UpdateProfile(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	request := &UpdateProfilePayload{}
+	if bindErr := c.Bind(request); bindErr != nil {
+		emptyBodyResponse := utils.NewErrorResponse(http.StatusUnprocessableEntity, "body", "The request body cannot be empty")
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(emptyBodyResponse))
+	}
+
+	user := &authUser
+	if request.Name != nil {
+		user.Name = *request.Name
+	}
+	emailChanged := request.Email != nil && user.ChangeEmail(*request.Email)
+
+	verrs, err := tx.ValidateAndUpdate(user)
+	if err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	if verrs.HasAny() {
+		errorResponse := utils.NewValidationErrorResponse(http.StatusUnprocessableEntity, verrs.Errors)
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	if emailChanged {
+		if err := sendEmailVerification(c, tx, user); err != nil {
+			return c.Error(http.StatusInternalServerError, err)
+		}
+	}
+
+	return c.Render(http.StatusOK, r.JSON(UserAuthResponse{
+		Code: fmt.Sprintf("%d", http.StatusOK),
+		Data: *user,
+	}))
+}
+
+// ChangePassword - Replace the password after checking the current one, every token of the
+// user is revoked and the caller gets a fresh pair of tokens in a new session
+func ChangePassword(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	request := &ChangePasswordPayload{}
+	c.Bind(request)
+	// the password of an account created through a provider is set through the password reset
+	if !authUser.HasPassword() {
+		return renderNoPasswordSet(c, "current_password")
+	}
+
+	verrs := validate.Validate(
+		&validators.StringIsPresent{Field: request.CurrentPassword, Name: "current_password"},
+		&utils.PasswordPolicyValidator{Field: request.Password, Name: "password", Personal: []string{authUser.Name, authUser.Email}},
+	)
+	if verrs.HasAny() {
+		errorResponse := utils.NewValidationErrorResponse(http.StatusUnprocessableEntity, verrs.Errors)
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	user := &authUser
+	passwordMatched, checkErr := user.CheckPassword(tx, request.CurrentPassword)
+	if checkErr != nil {
+		return c.Error(http.StatusInternalServerError, checkErr)
+	}
+	if !passwordMatched {
+		errorResponse := utils.NewErrorResponse(http.StatusUnprocessableEntity, "current_password", "The current password is incorrect")
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	if err := user.UpdatePassword(tx, request.Password); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	// every session ends, the caller continues in the session opened below
+	if err := user.RevokeAllTokens(tx); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	if err := recordAudit(c, tx, &models.AuditEvent{
+		Action:     models.AuditTokenRevoked,
+		TargetType: "user",
+		TargetID:   user.ID.String(),
+		Metadata:   models.AuditMetadata{"reason": "password_change"},
+	}); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	response, tokenErr := logInUser(c, tx, *user, "password_change")
+	if tokenErr != nil {
+		return c.Error(http.StatusInternalServerError, tokenErr)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(response))
+}
+
+// renderNoPasswordSet - the action needs the password of an account that has none yet
+func renderNoPasswordSet(c buffalo.Context, field string) error {
+	errorResponse := utils.NewErrorResponse(
+		http.StatusUnprocessableEntity,
+		field,
+		"Your account has no password yet, set one through the password reset at /api/v1/auth/password/forgot",
+	)
+
+	return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+}
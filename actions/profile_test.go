@@ -0,0 +1,92 @@
+package actions
+
+import (
+	"blog/models"
+	"blog/utils"
+	"fmt"
+	"net/http"
+)
+
+func (as *ActionSuite) Test_UpdateProfile_ChangesEmail() {
+	user, accessToken := as.logInAs("profile@example.com")
+	as.NoError(user.MarkEmailVerified(models.DB))
+
+	newName := "Profile Owner"
+	newEmail := "Profile-New@example.com"
+	req := as.JSON("/api/v1/auth/user")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	res := req.Patch(UpdateProfilePayload{Name: &newName, Email: &newEmail})
+	as.Equal(http.StatusOK, res.Code)
+
+	updated := &models.User{}
+	as.NoError(models.DB.Find(updated, user.ID))
+	as.Equal("profile-new@example.com", updated.Email)
+	as.Equal(newName, updated.Name)
+	as.False(updated.IsEmailVerified())
+}
+
+func (as *ActionSuite) Test_UpdateProfile_EmailTaken() {
+	as.logInAs("taken@example.com")
+	_, accessToken := as.logInAs("taker@example.com")
+
+	takenEmail := "taken@example.com"
+	req := as.JSON("/api/v1/auth/user")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	res := req.Patch(UpdateProfilePayload{Email: &takenEmail})
+	as.Equal(http.StatusUnprocessableEntity, res.Code)
+	as.Contains(res.Body.String(), "The email has been taken.")
+}
+
+func (as *ActionSuite) Test_ChangePassword_RevokesOtherTokens() {
+	user, accessToken := as.logInAs("change-password@example.com")
+
+	// a session and a personal access token opened within the same second
+	res := as.JSON("/api/v1/auth/login").Post(LogInPayload{Email: user.Email, Password: "secret"})
+	as.Equal(http.StatusOK, res.Code)
+	otherSession := &LogInResponse{}
+	res.Bind(otherSession)
+	req := as.JSON("/api/v1/auth/tokens")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	res = req.Post(PersonalAccessTokenPayload{Name: "ci", Scopes: []string{utils.ScopePostsRead}})
+	as.Equal(http.StatusCreated, res.Code)
+	personalToken := &PersonalAccessTokenResponse{}
+	res.Bind(personalToken)
+
+	req = as.JSON("/api/v1/auth/user/password")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	res = req.Put(ChangePasswordPayload{CurrentPassword: "wrong", Password: "correct horse battery"})
+	as.Equal(http.StatusUnprocessableEntity, res.Code)
+
+	req = as.JSON("/api/v1/auth/user/password")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	res = req.Put(ChangePasswordPayload{CurrentPassword: "secret", Password: "correct horse battery"})
+	as.Equal(http.StatusOK, res.Code)
+
+	loggedIn := &LogInResponse{}
+	res.Bind(loggedIn)
+
+	event := &models.AuditEvent{}
+	as.NoError(models.DB.Where("action = ? AND target_id = ?", models.AuditTokenRevoked, user.ID.String()).First(event))
+	as.Equal("password_change", event.Metadata["reason"])
+	as.Equal(user.ID, event.ActorID.UUID)
+
+	for _, revoked := range []string{accessToken, otherSession.AccessToken, personalToken.Token} {
+		req = as.JSON("/api/v1/auth/user")
+		req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", revoked)
+		as.Equal(http.StatusUnauthorized, req.Get().Code)
+	}
+
+	req = as.JSON("/api/v1/auth/user")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", loggedIn.AccessToken)
+	as.Equal(http.StatusOK, req.Get().Code)
+}
+
+func (as *ActionSuite) Test_ChangePassword_ProviderAccount() {
+	_, accessToken := as.logInAsProviderUser("provider-password@example.com")
+
+	req := as.JSON("/api/v1/auth/user/password")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", accessToken)
+	res := req.Put(ChangePasswordPayload{CurrentPassword: models.NoPassword, Password: "correct horse battery"})
+	as.Equal(http.StatusUnprocessableEntity, res.Code)
+	as.Contains(res.Body.String(), "/api/v1/auth/password/forgot")
+}
@@ -0,0 +1,137 @@
+package actions
+
+import (
+	"blog/models"
+	"blog/utils"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/pkg/errors"
+)
+
+// RefreshPayload - Request body of the refresh token exchange
+type RefreshPayload struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Validate - the refresh token must be present
+func (payload *RefreshPayload) Validate() *validate.Errors {
+	return validate.Validate(
+		&validators.StringIsPresent{Name: "refresh_token", Field: payload.RefreshToken},
+	)
+}
+
+// RefreshAccessToken - Exchange a refresh token for a new access token, the refresh token is rotated
+// on every exchange and presenting an already rotated token revokes the whole token family.
+func RefreshAccessToken(c buffalo.Context) error {
+	request := &RefreshPayload{}
+	c.Bind(request)
+
+	if verrs := request.Validate(); verrs.HasAny() {
+		errorResponse := utils.NewValidationErrorResponse(http.StatusUnprocessableEntity, verrs.Errors)
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	tx := c.Value("tx").(*pop.Connection)
+	invalidResponse := utils.NewErrorResponse(http.StatusUnauthorized, "refresh_token", "The refresh token is invalid or expired")
+
+	refreshToken, findErr := models.FindRefreshToken(tx, request.RefreshToken)
+	if findErr != nil {
+		return c.Render(http.StatusUnauthorized, r.JSON(invalidResponse))
+	}
+
+	if refreshToken.IsReplayed() {
+		return revokeReplayedFamily(c, refreshToken)
+	}
+
+	if !refreshToken.IsActive() {
+		return c.Render(http.StatusUnauthorized, r.JSON(invalidResponse))
+	}
+
+	user := &models.User{}
+	if userErr := tx.Find(user, refreshToken.UserID); userErr != nil {
+		return c.Render(http.StatusUnauthorized, r.JSON(invalidResponse))
+	}
+
+	session, sessionErr := refreshSession(c, tx, refreshToken)
+	if sessionErr != nil {
+		return c.Error(http.StatusInternalServerError, sessionErr)
+	}
+	// refresh tokens of third party clients are only exchanged at the OAuth token endpoint
+	if !session.IsActive() || session.IsOAuth() {
+		return c.Render(http.StatusUnauthorized, r.JSON(invalidResponse))
+	}
+
+	nextToken, _, rotateErr := refreshToken.Rotate(tx)
+	if errors.Is(rotateErr, models.ErrRefreshTokenReplayed) {
+		// a concurrent request exchanged the same token first
+		return c.Render(http.StatusUnauthorized, r.JSON(invalidResponse))
+	}
+	if rotateErr != nil {
+		return c.Error(http.StatusInternalServerError, rotateErr)
+	}
+	if err := recordAudit(c, tx, &models.AuditEvent{
+		Action:     models.AuditTokenIssued,
+		ActorID:    nulls.NewUUID(user.ID),
+		TargetType: "user",
+		TargetID:   user.ID.String(),
+		Metadata: models.AuditMetadata{
+			"reason":     "refresh",
+			"family_id":  refreshToken.FamilyID.String(),
+			"session_id": session.ID.String(),
+		},
+	}); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	response, tokenErr := newLogInResponse(*user, session, nextToken)
+	if tokenErr != nil {
+		return c.Error(http.StatusInternalServerError, tokenErr)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(response))
+}
+
+// refreshSession - the session of the refresh token family, families issued before
+// sessions were recorded get one on their first exchange
+func refreshSession(c buffalo.Context, tx *pop.Connection, refreshToken *models.RefreshToken) (*models.UserSession, error) {
+	session, err := models.FindUserSessionByFamily(tx, refreshToken.FamilyID)
+	if errors.Cause(err) == sql.ErrNoRows {
+		return models.CreateUserSession(tx, refreshToken.UserID, refreshToken.FamilyID, c.Request().UserAgent(), clientIP(c))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return session, session.Touch(tx, time.Now())
+}
+
+// revokeReplayedFamily - revoke the token family of a reused refresh token
+func revokeReplayedFamily(c buffalo.Context, refreshToken *models.RefreshToken) error {
+	// the request transaction is rolled back on a non 2xx response,
+	// so the revocation has to be written through its own connection
+	if err := models.RevokeRefreshTokenFamily(models.DB, refreshToken.FamilyID); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	if err := recordAudit(c, models.DB, &models.AuditEvent{
+		Action:     models.AuditTokenRevoked,
+		ActorID:    nulls.NewUUID(refreshToken.UserID),
+		TargetType: "user",
+		TargetID:   refreshToken.UserID.String(),
+		Metadata: models.AuditMetadata{
+			"reason":    "refresh_token_replayed",
+			"family_id": refreshToken.FamilyID.String(),
+		},
+	}); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	reusedResponse := utils.NewErrorResponse(http.StatusUnauthorized, "refresh_token", "The refresh token has already been used, please log in again")
+	return c.Render(http.StatusUnauthorized, r.JSON(reusedResponse))
+}
@@ -0,0 +1,62 @@
+package actions
+
+import (
+	"blog/models"
+	"net/http"
+
+	"github.com/gofrs/uuid"
+)
+
+func (as *ActionSuite) createRefreshTokenUser() (*models.User, string) {
+	user := &models.User{Email: "refresh@example.com", Password: "secret", Name: "Refresh"}
+	_, err := user.Create(models.DB)
+	as.NoError(err)
+
+	plainToken, _, err := models.IssueRefreshToken(models.DB, user.ID, uuid.Must(uuid.NewV4()))
+	as.NoError(err)
+
+	return user, plainToken
+}
+
+func (as *ActionSuite) Test_RefreshAccessToken_Rotates() {
+	user, plainToken := as.createRefreshTokenUser()
+
+	res := as.JSON("/api/v1/auth/refresh").Post(RefreshPayload{RefreshToken: plainToken})
+	as.Equal(http.StatusOK, res.Code)
+
+	response := &LogInResponse{}
+	res.Bind(response)
+	as.NotEmpty(response.AccessToken)
+	as.NotEmpty(response.RefreshToken)
+	as.NotEqual(plainToken, response.RefreshToken)
+
+	issued := &models.AuditEvent{}
+	as.NoError(models.DB.Where("action = ? AND target_id = ?", models.AuditTokenIssued, user.ID.String()).First(issued))
+	as.Equal("refresh", issued.Metadata["reason"])
+}
+
+func (as *ActionSuite) Test_RefreshAccessToken_ReuseRevokesFamily() {
+	user, plainToken := as.createRefreshTokenUser()
+
+	res := as.JSON("/api/v1/auth/refresh").Post(RefreshPayload{RefreshToken: plainToken})
+	as.Equal(http.StatusOK, res.Code)
+	rotated := &LogInResponse{}
+	res.Bind(rotated)
+
+	// replaying the first token revokes the token it was rotated into
+	res = as.JSON("/api/v1/auth/refresh").Post(RefreshPayload{RefreshToken: plainToken})
+	as.Equal(http.StatusUnauthorized, res.Code)
+
+	// the revocation is recorded although the request transaction rolled back
+	revoked := &models.AuditEvent{}
+	as.NoError(models.DB.Where("action = ? AND target_id = ?", models.AuditTokenRevoked, user.ID.String()).First(revoked))
+	as.Equal("refresh_token_replayed", revoked.Metadata["reason"])
+
+	res = as.JSON("/api/v1/auth/refresh").Post(RefreshPayload{RefreshToken: rotated.RefreshToken})
+	as.Equal(http.StatusUnauthorized, res.Code)
+}
+
+func (as *ActionSuite) Test_RefreshAccessToken_Unknown() {
+	res := as.JSON("/api/v1/auth/refresh").Post(RefreshPayload{RefreshToken: "unknown"})
+	as.Equal(http.StatusUnauthorized, res.Code)
+}
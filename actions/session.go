@@ -0,0 +1,79 @@
+package actions
+
+import (
+	"blog/models"
+	"blog/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+)
+
+// SessionResponse - A device logged into the account
+type SessionResponse struct {
+	models.UserSession
+	Current bool `json:"current"`
+}
+
+// SessionsResponse - Sessions collection response body
+type SessionsResponse struct {
+	Code string            `json:"code"`
+	Data []SessionResponse `json:"data"`
+}
+
+// ListSessions - List the devices logged into the account of the authenticated user
+func ListSessions(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+	currentSession, _ := c.Value("authSession").(models.UserSession)
+
+	sessions, err := models.ActiveUserSessions(tx, authUser.ID, time.Now())
+	if err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	data := []SessionResponse{}
+	for _, session := range *sessions {
+		data = append(data, SessionResponse{UserSession: session, Current: session.ID == currentSession.ID})
+	}
+
+	return c.Render(http.StatusOK, r.JSON(SessionsResponse{
+		Code: fmt.Sprintf("%d", http.StatusOK),
+		Data: data,
+	}))
+}
+
+// RevokeSession - Log a device out of the account of the authenticated user
+func RevokeSession(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	session := &models.UserSession{}
+	if err := tx.Where("user_id = ?", authUser.ID).Find(session, c.Param("session_id")); err != nil {
+		notFoundResponse := utils.NewErrorResponse(
+			http.StatusNotFound,
+			"session_id",
+			fmt.Sprintf("The requested session %s does not exist.", c.Param("session_id")),
+		)
+		return c.Render(http.StatusNotFound, r.JSON(notFoundResponse))
+	}
+
+	if err := session.Revoke(tx); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	if err := recordAudit(c, tx, &models.AuditEvent{
+		Action:     models.AuditTokenRevoked,
+		TargetType: "session",
+		TargetID:   session.ID.String(),
+		Metadata:   models.AuditMetadata{"reason": "session_revoked"},
+	}); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(MessageResponse{
+		Code:    fmt.Sprintf("%d", http.StatusOK),
+		Message: "The session has been revoked",
+	}))
+}
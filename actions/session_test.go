@@ -0,0 +1,56 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+)
+
+func (as *ActionSuite) Test_Sessions_ListAndRevoke() {
+	user, _ := as.logInAs("sessions@example.com")
+
+	logIn := func(userAgent string) *LogInResponse {
+		req := as.JSON("/api/v1/auth/login")
+		req.Headers["User-Agent"] = userAgent
+		res := req.Post(LogInPayload{Email: user.Email, Password: "secret"})
+		as.Equal(http.StatusOK, res.Code)
+
+		response := &LogInResponse{}
+		res.Bind(response)
+		return response
+	}
+	laptop := logIn("laptop")
+	phone := logIn("phone")
+
+	req := as.JSON("/api/v1/auth/sessions")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", laptop.AccessToken)
+	res := req.Get()
+	as.Equal(http.StatusOK, res.Code)
+
+	sessions := &SessionsResponse{}
+	res.Bind(sessions)
+	as.Len(sessions.Data, 2)
+
+	var phoneSessionID string
+	for _, session := range sessions.Data {
+		if session.UserAgent == "phone" {
+			as.False(session.Current)
+			phoneSessionID = session.ID.String()
+		}
+	}
+	as.NotEmpty(phoneSessionID)
+
+	req = as.JSON("/api/v1/auth/sessions/%s", phoneSessionID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", laptop.AccessToken)
+	as.Equal(http.StatusOK, req.Delete().Code)
+
+	req = as.JSON("/api/v1/auth/user")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", phone.AccessToken)
+	as.Equal(http.StatusUnauthorized, req.Get().Code)
+
+	res = as.JSON("/api/v1/auth/refresh").Post(RefreshPayload{RefreshToken: phone.RefreshToken})
+	as.Equal(http.StatusUnauthorized, res.Code)
+
+	req = as.JSON("/api/v1/auth/user")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", laptop.AccessToken)
+	as.Equal(http.StatusOK, req.Get().Code)
+}
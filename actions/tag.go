@@ -0,0 +1,234 @@
+package actions
+
+import (
+	"blog/models"
+	"blog/policies"
+	"blog/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+)
+
+// TagPayload - Request body to create or rename a tag
+type TagPayload struct {
+	Name string `json:"name"`
+}
+
+// MergeTagPayload - Request body to merge a tag into the tag given by its ID or slug
+type MergeTagPayload struct {
+	Into string `json:"into"`
+}
+
+// TagSummary - A tag with the number of posts outside the trash carrying it
+type TagSummary struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	PostCount int       `json:"post_count"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// newTagSummary - the summary of the tag carried by count posts
+func newTagSummary(tag models.Tag, count int) *TagSummary {
+	return &TagSummary{
+		ID:        tag.ID,
+		Name:      tag.Name,
+		Slug:      tag.Slug,
+		PostCount: count,
+		CreatedAt: tag.CreatedAt,
+		UpdatedAt: tag.UpdatedAt,
+	}
+}
+
+// TagsResponse - Tags collection response body
+type TagsResponse struct {
+	Code string        `json:"code"`
+	Data []TagSummary  `json:"data"`
+	Meta pop.Paginator `json:"meta"`
+}
+
+// TagResponse - Single tag response body
+type TagResponse struct {
+	Code string      `json:"code"`
+	Data *TagSummary `json:"data"`
+}
+
+// ListTags - List the tags by name with their post counts
+func ListTags(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	tags := &models.Tags{}
+	query := tx.PaginateFromParams(c.Params())
+	if err := query.Order("name asc").All(tags); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	counts, err := tagPostCounts(c, tx)
+	if err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	summaries := make([]TagSummary, 0, len(*tags))
+	for _, tag := range *tags {
+		summaries = append(summaries, *newTagSummary(tag, counts[tag.ID]))
+	}
+
+	return c.Render(http.StatusOK, r.JSON(TagsResponse{
+		Code: fmt.Sprintf("%d", http.StatusOK),
+		Data: summaries,
+		Meta: *query.Paginator,
+	}))
+}
+
+// CreateTag - Create a tag, posts also create the tags they are given
+func CreateTag(c buffalo.Context) error {
+	authUser := c.Value("authUser").(models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	tag := &models.Tag{}
+	if !policies.Can(authUser, policies.ActionCreate, tag) {
+		return renderForbiddenRole(c)
+	}
+	request := &TagPayload{}
+	if err := c.Bind(request); err != nil {
+		errorResponse := utils.NewErrorResponse(http.StatusBadRequest, "name", "The request body is invalid")
+		return c.Render(http.StatusBadRequest, r.JSON(errorResponse))
+	}
+
+	tag.Name = request.Name
+	verrs, err := tx.ValidateAndCreate(tag)
+	if err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	if verrs.HasAny() {
+		errorResponse := utils.NewValidationErrorResponse(http.StatusUnprocessableEntity, verrs.Errors)
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	return c.Render(http.StatusCreated, r.JSON(TagResponse{
+		Code: fmt.Sprintf("%d", http.StatusCreated),
+		Data: newTagSummary(*tag, 0),
+	}))
+}
+
+// UpdateTag - Rename a tag, every post carrying it shows the new name
+func UpdateTag(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	tag, findErr := findManagedTag(c, tx, policies.ActionUpdate)
+	if tag == nil {
+		return findErr
+	}
+
+	request := &TagPayload{}
+	if err := c.Bind(request); err != nil {
+		errorResponse := utils.NewErrorResponse(http.StatusBadRequest, "name", "The request body is invalid")
+		return c.Render(http.StatusBadRequest, r.JSON(errorResponse))
+	}
+
+	tag.Name = request.Name
+	verrs, err := tx.ValidateAndUpdate(tag)
+	if err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	if verrs.HasAny() {
+		errorResponse := utils.NewValidationErrorResponse(http.StatusUnprocessableEntity, verrs.Errors)
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	return renderTagSummary(c, tx, tag)
+}
+
+// MergeTag - Move every post of the tag to the tag given by into and remove the tag, the whole
+// merge commits or rolls back with the request transaction
+func MergeTag(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	source, findErr := findManagedTag(c, tx, policies.ActionUpdate)
+	if source == nil {
+		return findErr
+	}
+
+	request := &MergeTagPayload{}
+	if err := c.Bind(request); err != nil {
+		errorResponse := utils.NewErrorResponse(http.StatusBadRequest, "into", "The request body is invalid")
+		return c.Render(http.StatusBadRequest, r.JSON(errorResponse))
+	}
+	target, err := models.FindTagByIDOrSlug(tx, request.Into)
+	if err != nil || target.ID == source.ID {
+		errorResponse := utils.NewErrorResponse(http.StatusUnprocessableEntity, "into", "The tag to merge into must be another existing tag")
+		return c.Render(http.StatusUnprocessableEntity, r.JSON(errorResponse))
+	}
+
+	if _, err := models.MergeTags(tx, source, target); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return renderTagSummary(c, tx, target)
+}
+
+// DeleteTag - Remove a tag from every post and delete it
+func DeleteTag(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	tag, findErr := findManagedTag(c, tx, policies.ActionDelete)
+	if tag == nil {
+		return findErr
+	}
+
+	if err := tx.Destroy(tag); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(MessageResponse{
+		Code:    fmt.Sprintf("%d", http.StatusOK),
+		Message: "The tag has been deleted",
+	}))
+}
+
+// findManagedTag - the tag of the route when the caller may perform the action on it,
+// otherwise the error response is returned
+func findManagedTag(c buffalo.Context, tx *pop.Connection, action string) (*models.Tag, error) {
+	authUser := c.Value("authUser").(models.User)
+
+	tag, err := models.FindTagByIDOrSlug(tx, c.Param("tag_id"))
+	if err != nil {
+		notFoundResponse := utils.NewErrorResponse(
+			http.StatusNotFound,
+			"tag_id",
+			fmt.Sprintf("The requested tag %s does not exist.", c.Param("tag_id")),
+		)
+		return nil, c.Render(http.StatusNotFound, r.JSON(notFoundResponse))
+	}
+	if !policies.Can(authUser, action, tag) {
+		return nil, renderForbiddenRole(c)
+	}
+
+	return tag, nil
+}
+
+// tagPostCounts - the post counts of the tags over the posts the caller may read
+func tagPostCounts(c buffalo.Context, tx *pop.Connection) (map[uuid.UUID]int, error) {
+	authUser := c.Value("authUser").(models.User)
+	// an unpublished post nobody owns is only counted for those who see every post
+	if policies.Can(authUser, policies.ActionRead, &models.Post{Status: models.PostStatusDraft}) {
+		return models.CountTagPosts(tx, nulls.UUID{})
+	}
+
+	return models.CountTagPosts(tx, nulls.NewUUID(authUser.ID))
+}
+
+// renderTagSummary - respond with the tag and its current post count
+func renderTagSummary(c buffalo.Context, tx *pop.Connection, tag *models.Tag) error {
+	counts, err := tagPostCounts(c, tx)
+	if err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(TagResponse{
+		Code: fmt.Sprintf("%d", http.StatusOK),
+		Data: newTagSummary(*tag, counts[tag.ID]),
+	}))
+}
@@ -0,0 +1,83 @@
+package actions
+
+import (
+	"blog/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+func (as *ActionSuite) Test_Tags_PostPayloadFilterAndMerge() {
+	author, authorToken := as.logInAs("tagging@example.com")
+	as.NoError(author.MarkEmailVerified(models.DB))
+	editor, editorToken := as.logInAs("tag-editor@example.com")
+	as.NoError(editor.AssignRole(models.DB, models.RoleEditor))
+
+	req := as.JSON("/api/v1/posts/create")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	res := req.Post(map[string]interface{}{"title": "Tagged post", "description": "Body", "tags": []string{"Go", "golang"}})
+	as.Equal(http.StatusCreated, res.Code)
+
+	created := PostResponse{}
+	as.NoError(json.Unmarshal(res.Body.Bytes(), &created))
+	as.Equal([]string{"Go", "golang"}, created.Data.Tags.Names())
+
+	// updating without tags keeps them
+	req = as.JSON("/api/v1/posts/%s", created.Data.ID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	as.Equal(http.StatusOK, req.Put(map[string]string{"title": "Tagged post", "description": "Edited"}).Code)
+
+	req = as.JSON("/api/v1/posts/?tag=golang")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	res = req.Get()
+	as.Equal(http.StatusOK, res.Code)
+	listed := PostsResponse{}
+	as.NoError(json.Unmarshal(res.Body.Bytes(), &listed))
+	as.Len(listed.Data, 1)
+
+	// the draft is counted for its author only
+	_, readerToken := as.logInAs("tag-reader@example.com")
+	req = as.JSON("/api/v1/tags/")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", readerToken)
+	res = req.Get()
+	as.Equal(http.StatusOK, res.Code)
+	readerTags := TagsResponse{}
+	as.NoError(json.Unmarshal(res.Body.Bytes(), &readerTags))
+	as.Len(readerTags.Data, 2)
+	for _, tag := range readerTags.Data {
+		as.Equal(0, tag.PostCount)
+	}
+
+	req = as.JSON("/api/v1/tags/golang/merge")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", editorToken)
+	res = req.Post("not an object")
+	as.Equal(http.StatusBadRequest, res.Code)
+
+	// authors may not merge tags
+	req = as.JSON("/api/v1/tags/golang/merge")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	as.Equal(http.StatusForbidden, req.Post(MergeTagPayload{Into: "go"}).Code)
+
+	req = as.JSON("/api/v1/tags/golang/merge")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", editorToken)
+	res = req.Post(MergeTagPayload{Into: "go"})
+	as.Equal(http.StatusOK, res.Code)
+	merged := TagResponse{}
+	as.NoError(json.Unmarshal(res.Body.Bytes(), &merged))
+	as.Equal("go", merged.Data.Slug)
+	as.Equal(1, merged.Data.PostCount)
+
+	req = as.JSON("/api/v1/tags/go")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", editorToken)
+	as.Equal(http.StatusOK, req.Put(TagPayload{Name: "Golang"}).Code)
+
+	req = as.JSON("/api/v1/tags/")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", authorToken)
+	res = req.Get()
+	as.Equal(http.StatusOK, res.Code)
+	tags := TagsResponse{}
+	as.NoError(json.Unmarshal(res.Body.Bytes(), &tags))
+	as.Len(tags.Data, 1)
+	as.Equal("Golang", tags.Data[0].Name)
+	as.Equal(1, tags.Data[0].PostCount)
+}
@@ -0,0 +1,59 @@
+package grifts
+
+import (
+	"blog/models"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/markbates/grift/grift"
+)
+
+var _ = grift.Namespace("audit", func() {
+
+	grift.Desc("export", "Writes the audit trail to stdout as NDJSON, filtered by key=value arguments, e.g. audit:export action=auth.login.failed since=2021-05-01T00:00:00Z")
+	grift.Add("export", func(c *grift.Context) error {
+		filter := models.AuditFilter{}
+		for _, arg := range c.Args {
+			parts := strings.SplitN(arg, "=", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("usage: audit:export [action=..] [actor_id=..] [target_type=..] [target_id=..] [ip=..] [since=RFC3339] [until=RFC3339], got %q", arg)
+			}
+
+			switch key, value := parts[0], parts[1]; key {
+			case "action":
+				filter.Action = value
+			case "actor_id":
+				filter.ActorID = value
+			case "target_type":
+				filter.TargetType = value
+			case "target_id":
+				filter.TargetID = value
+			case "ip":
+				filter.IPAddress = value
+			case "since", "until":
+				parsed, err := time.Parse(time.RFC3339, value)
+				if err != nil {
+					return fmt.Errorf("the %s must be a RFC 3339 time: %v", key, err)
+				}
+				if key == "since" {
+					filter.Since = parsed
+				} else {
+					filter.Until = parsed
+				}
+			default:
+				return fmt.Errorf("unknown filter %q", key)
+			}
+		}
+
+		exported, err := models.ExportAuditEvents(models.DB, filter, os.Stdout)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "exported %d audit events\n", exported)
+		return nil
+	})
+
+})
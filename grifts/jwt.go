@@ -0,0 +1,63 @@
+package grifts
+
+import (
+	"blog/models"
+	"blog/utils"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/envy"
+	"github.com/markbates/grift/grift"
+)
+
+var _ = grift.Namespace("jwt", func() {
+
+	grift.Desc("prune", "Removes revocation entries of access tokens that have already expired")
+	grift.Add("prune", func(c *grift.Context) error {
+		pruned, err := models.PruneRevokedTokens(models.DB, time.Now())
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("pruned %d expired revocation entries\n", pruned)
+		return nil
+	})
+
+	grift.Desc("rotate", "Generates a new signing key, promotes it and retires keys past their grace period")
+	grift.Add("rotate", func(c *grift.Context) error {
+		path := utils.KeyRingPath()
+		if path == "" {
+			return errors.New("JWT_KEYRING_PATH must point to the key ring file")
+		}
+
+		keyRing, err := utils.ReadKeyRingFile(path)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if len(keyRing.Keys) == 0 && envy.Get("JWT_KEY_PATH", "") != "" {
+			// the first rotation keeps the single key verifying until its grace period ends
+			legacy, err := utils.LegacyKeyRingEntry(now)
+			if err != nil {
+				return err
+			}
+			keyRing.Keys = append(keyRing.Keys, legacy)
+		}
+
+		entry, err := utils.GenerateKeyRingEntry(utils.JWTAlgorithm(), now)
+		if err != nil {
+			return err
+		}
+		keyRing.Rotate(entry, utils.KeyGracePeriod(), now)
+
+		if err := keyRing.Write(path); err != nil {
+			return err
+		}
+
+		fmt.Printf("promoted %s key %s, %d keys in the ring\n", entry.Algorithm, entry.KeyID, len(keyRing.Keys))
+		return nil
+	})
+
+})
@@ -0,0 +1,25 @@
+package grifts
+
+import (
+	"blog/lockout"
+	"blog/models"
+	"fmt"
+	"time"
+
+	"github.com/markbates/grift/grift"
+)
+
+var _ = grift.Namespace("lockout", func() {
+
+	grift.Desc("prune", "Removes the failed log in counters whose failures are older than LOGIN_LOCKOUT_DURATION and whose lockout is over")
+	grift.Add("prune", func(c *grift.Context) error {
+		pruned, err := models.PruneLoginAttempts(models.DB, time.Now(), lockout.NewGuardFromEnv().LockoutDuration)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("pruned %d log in attempt counters\n", pruned)
+		return nil
+	})
+
+})
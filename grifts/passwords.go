@@ -0,0 +1,73 @@
+package grifts
+
+import (
+	"blog/utils"
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/markbates/grift/grift"
+)
+
+var _ = grift.Namespace("passwords", func() {
+
+	grift.Desc("import", "Splits a SHA1:COUNT breached password list into prefix buckets, e.g. passwords:import pwned-passwords-sha1.txt data/breached")
+	grift.Add("import", func(c *grift.Context) error {
+		if len(c.Args) != 2 {
+			return errors.New("usage: passwords:import <sha1 list> <bucket directory>")
+		}
+
+		source, err := os.Open(c.Args[0])
+		if err != nil {
+			return err
+		}
+		defer source.Close()
+
+		if err := os.MkdirAll(c.Args[1], 0755); err != nil {
+			return err
+		}
+
+		var bucket *os.File
+		var bucketPrefix string
+		imported := 0
+		scanner := bufio.NewScanner(source)
+		for scanner.Scan() {
+			line := strings.ToUpper(strings.TrimSpace(scanner.Text()))
+			if len(line) < 40 {
+				continue
+			}
+
+			// the published lists are sorted by hash, so a bucket is written in one go
+			prefix := line[:utils.BreachedPasswordPrefixLength]
+			if prefix != bucketPrefix {
+				if bucket != nil {
+					bucket.Close()
+				}
+				bucket, err = os.OpenFile(filepath.Join(c.Args[1], prefix), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+				if err != nil {
+					return err
+				}
+				bucketPrefix = prefix
+			}
+
+			if _, err := fmt.Fprintln(bucket, line[utils.BreachedPasswordPrefixLength:]); err != nil {
+				bucket.Close()
+				return err
+			}
+			imported++
+		}
+		if bucket != nil {
+			bucket.Close()
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+
+		fmt.Printf("imported %d breached password hashes\n", imported)
+		return nil
+	})
+
+})
@@ -0,0 +1,80 @@
+package grifts
+
+import (
+	"blog/models"
+	"blog/utils"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/markbates/grift/grift"
+)
+
+var _ = grift.Namespace("posts", func() {
+
+	grift.Desc("prune_revisions", "Deletes the post revisions beyond POST_REVISION_KEEP per post (default 50, 0 keeps all) or older than POST_REVISION_MAX_AGE (e.g. 2160h, unset keeps all), the newest revision of a post is always kept")
+	grift.Add("prune_revisions", func(c *grift.Context) error {
+		keep := utils.IntFromEnv("POST_REVISION_KEEP", 50)
+		cutoff := time.Time{}
+		if maxAge := utils.DurationFromEnv("POST_REVISION_MAX_AGE", 0); maxAge > 0 {
+			cutoff = time.Now().Add(-maxAge)
+		}
+
+		var pruned int
+		err := models.DB.Transaction(func(tx *pop.Connection) error {
+			var pruneErr error
+			pruned, pruneErr = models.PrunePostRevisions(tx, keep, cutoff)
+			return pruneErr
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("pruned %d post revisions\n", pruned)
+		return nil
+	})
+
+	grift.Desc("purge_trash", "Deletes the posts that have been in the trash longer than the given days for good, e.g. posts:purge_trash 30 (default POST_TRASH_RETENTION_DAYS or 30)")
+	grift.Add("purge_trash", func(c *grift.Context) error {
+		days := utils.IntFromEnv("POST_TRASH_RETENTION_DAYS", 30)
+		if len(c.Args) > 0 {
+			parsed, err := strconv.Atoi(c.Args[0])
+			if err != nil || parsed < 0 {
+				return errors.New("usage: posts:purge_trash [days]")
+			}
+			days = parsed
+		}
+
+		var purged int
+		err := models.DB.Transaction(func(tx *pop.Connection) error {
+			var purgeErr error
+			purged, purgeErr = models.PurgeDeletedPosts(tx, time.Now().AddDate(0, 0, -days))
+			return purgeErr
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("purged %d posts from the trash\n", purged)
+		return nil
+	})
+
+	grift.Desc("backfill_slugs", "Gives the posts written before slugs existed a slug from their title, part of the deploy that runs the slug migration")
+	grift.Add("backfill_slugs", func(c *grift.Context) error {
+		var backfilled int
+		err := models.DB.Transaction(func(tx *pop.Connection) error {
+			var backfillErr error
+			backfilled, backfillErr = models.BackfillPostSlugs(tx)
+			return backfillErr
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("gave %d posts a slug\n", backfilled)
+		return nil
+	})
+
+})
@@ -0,0 +1,32 @@
+package grifts
+
+import (
+	"blog/models"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/markbates/grift/grift"
+)
+
+var _ = grift.Namespace("roles", func() {
+
+	grift.Desc("assign", "Assigns a role to the user with the given email, e.g. roles:assign admin@example.com admin")
+	grift.Add("assign", func(c *grift.Context) error {
+		if len(c.Args) != 2 {
+			return errors.New("usage: roles:assign <email> <role>")
+		}
+
+		user := &models.User{}
+		if err := models.DB.Where("email = ?", strings.ToLower(c.Args[0])).First(user); err != nil {
+			return err
+		}
+		if err := user.AssignRole(models.DB, c.Args[1]); err != nil {
+			return err
+		}
+
+		fmt.Printf("%s is now %s\n", user.Email, user.Role)
+		return nil
+	})
+
+})
@@ -0,0 +1,30 @@
+package grifts
+
+import (
+	"blog/models"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/markbates/grift/grift"
+)
+
+var _ = grift.Namespace("users", func() {
+
+	grift.Desc("purge", "Deletes the accounts whose deletion grace period is over")
+	grift.Add("purge", func(c *grift.Context) error {
+		var purged int
+		err := models.DB.Transaction(func(tx *pop.Connection) error {
+			var purgeErr error
+			purged, purgeErr = models.PurgeDueUsers(tx, time.Now())
+			return purgeErr
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("purged %d accounts\n", purged)
+		return nil
+	})
+
+})
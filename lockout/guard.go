@@ -0,0 +1,143 @@
+package lockout
+
+import (
+	"blog/models"
+	"blog/utils"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/envy"
+)
+
+// Guard - Throttles failed log ins per account and per client IP. Every failure of an
+// account doubles the wait before the next attempt, too many failures lock the account
+// or the IP for the lockout duration.
+type Guard struct {
+	// Prefix - keeps the counters of guards sharing a store apart, empty for the log in guard
+	Prefix          string
+	Store           Store
+	Clock           utils.Clock
+	MaxAttempts     int
+	IPMaxAttempts   int
+	BaseDelay       time.Duration
+	LockoutDuration time.Duration
+}
+
+// Decision - Whether an attempt may go ahead, a zero RetryAfter allows it
+type Decision struct {
+	Locked     bool
+	RetryAfter time.Duration
+}
+
+// Allowed - the attempt may go ahead
+func (d Decision) Allowed() bool {
+	return d.RetryAfter <= 0
+}
+
+// NewGuardFromEnv - Configure the guard with LOGIN_MAX_ATTEMPTS, LOGIN_IP_MAX_ATTEMPTS,
+// LOGIN_BASE_DELAY, LOGIN_LOCKOUT_DURATION and LOGIN_ATTEMPT_STORE (database or memory)
+func NewGuardFromEnv() *Guard {
+	var store Store = &DatabaseStore{DB: models.DB}
+	if envy.Get("LOGIN_ATTEMPT_STORE", "database") == "memory" {
+		store = NewMemoryStore()
+	}
+
+	return &Guard{
+		Store:           store,
+		Clock:           utils.SystemClock,
+		MaxAttempts:     utils.IntFromEnv("LOGIN_MAX_ATTEMPTS", 5),
+		IPMaxAttempts:   utils.IntFromEnv("LOGIN_IP_MAX_ATTEMPTS", 50),
+		BaseDelay:       utils.DurationFromEnv("LOGIN_BASE_DELAY", time.Second),
+		LockoutDuration: utils.DurationFromEnv("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+	}
+}
+
+// AccountKey - counter key of an account, unknown emails are counted the same way
+func AccountKey(email string) string {
+	return "account:" + strings.ToLower(email)
+}
+
+// IPKey - counter key of a client IP
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check - decide whether the account may attempt to log in from the IP
+func (g *Guard) Check(email string, ip string) (Decision, error) {
+	now := g.Clock.Now()
+
+	ipAttempts, err := g.Store.Get(g.Prefix + IPKey(ip))
+	if err != nil {
+		return Decision{}, err
+	}
+	if ipAttempts.LockedUntil.After(now) {
+		return Decision{Locked: true, RetryAfter: ipAttempts.LockedUntil.Sub(now)}, nil
+	}
+
+	attempts, err := g.Store.Get(g.Prefix + AccountKey(email))
+	if err != nil {
+		return Decision{}, err
+	}
+	if attempts.LockedUntil.After(now) {
+		return Decision{Locked: true, RetryAfter: attempts.LockedUntil.Sub(now)}, nil
+	}
+	if attempts.Failures > 0 {
+		return Decision{RetryAfter: attempts.LastFailedAt.Add(g.delay(attempts.Failures)).Sub(now)}, nil
+	}
+
+	return Decision{}, nil
+}
+
+// Fail - count a failed attempt and lock the account or the IP once they reach their limit
+func (g *Guard) Fail(email string, ip string) error {
+	now := g.Clock.Now()
+
+	if err := g.fail(g.Prefix+IPKey(ip), g.IPMaxAttempts, now); err != nil {
+		return err
+	}
+
+	return g.fail(g.Prefix+AccountKey(email), g.MaxAttempts, now)
+}
+
+// Succeed - forget the failures of the account, the IP keeps its count so one valid
+// account can not be used to reset the counter of the IP
+func (g *Guard) Succeed(email string) error {
+	return g.Store.Reset(g.Prefix + AccountKey(email))
+}
+
+// Unlock - lift the lockout of an account and of the IPs it failed to log in from
+func (g *Guard) Unlock(email string, ips ...string) error {
+	for _, ip := range ips {
+		if err := g.Store.Reset(g.Prefix + IPKey(ip)); err != nil {
+			return err
+		}
+	}
+
+	return g.Store.Reset(g.Prefix + AccountKey(email))
+}
+
+// fail - count the failure of the key and lock it at the limit
+func (g *Guard) fail(key string, maxAttempts int, now time.Time) error {
+	attempts, err := g.Store.RecordFailure(key, now, g.LockoutDuration)
+	if err != nil {
+		return err
+	}
+	if attempts.Failures < maxAttempts {
+		return nil
+	}
+
+	return g.Store.Lock(key, now.Add(g.LockoutDuration))
+}
+
+// delay - the wait after the given number of failures, doubling up to the lockout duration
+func (g *Guard) delay(failures int) time.Duration {
+	delay := g.BaseDelay
+	for i := 1; i < failures && delay < g.LockoutDuration; i++ {
+		delay *= 2
+	}
+	if delay > g.LockoutDuration {
+		return g.LockoutDuration
+	}
+
+	return delay
+}
@@ -0,0 +1,128 @@
+package lockout
+
+import (
+	"blog/utils"
+	"testing"
+	"time"
+)
+
+func newTestGuard(at time.Time) *Guard {
+	return &Guard{
+		Store:           NewMemoryStore(),
+		Clock:           utils.FixedClock{At: at},
+		MaxAttempts:     3,
+		IPMaxAttempts:   5,
+		BaseDelay:       time.Second,
+		LockoutDuration: time.Minute,
+	}
+}
+
+func Test_Guard_ProgressiveDelay(t *testing.T) {
+	now := time.Date(2021, 4, 5, 9, 0, 0, 0, time.UTC)
+	guard := newTestGuard(now)
+
+	if err := guard.Fail("a@example.com", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	decision, _ := guard.Check("a@example.com", "10.0.0.1")
+	if decision.Allowed() || decision.Locked || decision.RetryAfter != time.Second {
+		t.Fatalf("expected a one second delay, got %+v", decision)
+	}
+
+	guard.Clock = utils.FixedClock{At: now.Add(time.Second)}
+	guard.Fail("a@example.com", "10.0.0.1")
+	decision, _ = guard.Check("a@example.com", "10.0.0.1")
+	if decision.RetryAfter != 2*time.Second {
+		t.Fatalf("expected the delay to double, got %+v", decision)
+	}
+
+	guard.Clock = utils.FixedClock{At: now.Add(5 * time.Second)}
+	if err := guard.Succeed("a@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if decision, _ = guard.Check("a@example.com", "10.0.0.1"); !decision.Allowed() {
+		t.Fatalf("expected a successful log in to reset the delay, got %+v", decision)
+	}
+}
+
+func Test_Guard_LocksAccount(t *testing.T) {
+	now := time.Date(2021, 4, 5, 9, 0, 0, 0, time.UTC)
+	guard := newTestGuard(now)
+
+	for i := 0; i < 3; i++ {
+		guard.Fail("b@example.com", "10.0.0.2")
+	}
+	decision, _ := guard.Check("b@example.com", "10.0.0.2")
+	if !decision.Locked || decision.RetryAfter != time.Minute {
+		t.Fatalf("expected the account to be locked, got %+v", decision)
+	}
+
+	if decision, _ = guard.Check("c@example.com", "10.0.0.2"); !decision.Allowed() {
+		t.Fatalf("expected other accounts to be unaffected, got %+v", decision)
+	}
+
+	guard.Unlock("b@example.com")
+	if decision, _ = guard.Check("b@example.com", "10.0.0.2"); !decision.Allowed() {
+		t.Fatalf("expected the unlock to lift the lockout, got %+v", decision)
+	}
+}
+
+func Test_Guard_UnlockResetsIPs(t *testing.T) {
+	now := time.Date(2021, 4, 5, 9, 0, 0, 0, time.UTC)
+	guard := newTestGuard(now)
+
+	for i := 0; i < 5; i++ {
+		guard.Fail("e@example.com", "10.0.0.5")
+	}
+	if err := guard.Unlock("e@example.com", "10.0.0.5"); err != nil {
+		t.Fatal(err)
+	}
+	if decision, _ := guard.Check("e@example.com", "10.0.0.5"); !decision.Allowed() {
+		t.Fatalf("expected the unlock to lift the lockout of the IP, got %+v", decision)
+	}
+}
+
+func Test_Guard_LocksIP(t *testing.T) {
+	now := time.Date(2021, 4, 5, 9, 0, 0, 0, time.UTC)
+	guard := newTestGuard(now)
+
+	for i := 0; i < 5; i++ {
+		guard.Fail("spray"+string(rune('a'+i))+"@example.com", "10.0.0.3")
+	}
+	decision, _ := guard.Check("fresh@example.com", "10.0.0.3")
+	if !decision.Locked {
+		t.Fatalf("expected the IP to be locked, got %+v", decision)
+	}
+}
+
+func Test_Guard_WindowExpires(t *testing.T) {
+	now := time.Date(2021, 4, 5, 9, 0, 0, 0, time.UTC)
+	guard := newTestGuard(now)
+
+	guard.Fail("d@example.com", "10.0.0.4")
+	guard.Fail("d@example.com", "10.0.0.4")
+
+	guard.Clock = utils.FixedClock{At: now.Add(2 * time.Minute)}
+	guard.Fail("d@example.com", "10.0.0.4")
+	decision, _ := guard.Check("d@example.com", "10.0.0.4")
+	if decision.Locked || decision.RetryAfter != time.Second {
+		t.Fatalf("expected old failures to be forgotten, got %+v", decision)
+	}
+}
+
+func Test_Guard_PrefixSeparatesCounters(t *testing.T) {
+	now := time.Date(2021, 4, 5, 9, 0, 0, 0, time.UTC)
+	guard := newTestGuard(now)
+	other := newTestGuard(now)
+	other.Store, other.Prefix = guard.Store, "other:"
+
+	for i := 0; i < 3; i++ {
+		other.Fail("f@example.com", "10.0.0.6")
+	}
+	if decision, _ := guard.Check("f@example.com", "10.0.0.6"); !decision.Allowed() {
+		t.Fatalf("expected the counters of the other guard to be kept apart, got %+v", decision)
+	}
+	if decision, _ := other.Check("f@example.com", "10.0.0.6"); !decision.Locked {
+		t.Fatalf("expected the other guard to lock, got %+v", decision)
+	}
+}
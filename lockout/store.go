@@ -0,0 +1,130 @@
+package lockout
+
+import (
+	"blog/models"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+)
+
+// Attempts - The recent failed log ins counted for a key
+type Attempts struct {
+	Failures     int
+	LastFailedAt time.Time
+	LockedUntil  time.Time
+}
+
+// Store - Keeps the failed attempt counters of accounts and client IPs
+type Store interface {
+	// Get - the counter of the key, an unknown key has no failures
+	Get(key string) (Attempts, error)
+	// RecordFailure - count a failure, failures older than the window start a new count
+	RecordFailure(key string, now time.Time, window time.Duration) (Attempts, error)
+	// Lock - refuse the key until the given time
+	Lock(key string, until time.Time) error
+	// Reset - forget the failures and the lockout of the key
+	Reset(key string) error
+}
+
+// MemoryStore - Store for a single process, the counters are lost on restart
+type MemoryStore struct {
+	mutex    sync.Mutex
+	attempts map[string]Attempts
+}
+
+// NewMemoryStore - an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{attempts: map[string]Attempts{}}
+}
+
+// Get - the counter of the key
+func (s *MemoryStore) Get(key string) (Attempts, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.attempts[key], nil
+}
+
+// RecordFailure - count a failure of the key
+func (s *MemoryStore) RecordFailure(key string, now time.Time, window time.Duration) (Attempts, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	attempts := s.attempts[key]
+	if attempts.LastFailedAt.Before(now.Add(-window)) {
+		attempts.Failures = 0
+	}
+	attempts.Failures++
+	attempts.LastFailedAt = now
+	s.attempts[key] = attempts
+
+	return attempts, nil
+}
+
+// Lock - refuse the key until the given time
+func (s *MemoryStore) Lock(key string, until time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	attempts := s.attempts[key]
+	attempts.LockedUntil = until
+	s.attempts[key] = attempts
+
+	return nil
+}
+
+// Reset - forget the key
+func (s *MemoryStore) Reset(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.attempts, key)
+
+	return nil
+}
+
+// DatabaseStore - Store shared by every process through the login_attempts table.
+// It must not use the request transaction, the failures have to survive the failed response.
+type DatabaseStore struct {
+	DB *pop.Connection
+}
+
+// Get - the counter of the key
+func (s *DatabaseStore) Get(key string) (Attempts, error) {
+	attempt, err := models.FindLoginAttempt(s.DB, key)
+	if err != nil {
+		return Attempts{}, err
+	}
+
+	return toAttempts(attempt), nil
+}
+
+// RecordFailure - count a failure of the key
+func (s *DatabaseStore) RecordFailure(key string, now time.Time, window time.Duration) (Attempts, error) {
+	attempt, err := models.RecordLoginFailure(s.DB, key, now, window)
+	if err != nil {
+		return Attempts{}, err
+	}
+
+	return toAttempts(attempt), nil
+}
+
+// Lock - refuse the key until the given time
+func (s *DatabaseStore) Lock(key string, until time.Time) error {
+	return models.LockLoginAttempt(s.DB, key, until)
+}
+
+// Reset - forget the key
+func (s *DatabaseStore) Reset(key string) error {
+	return models.ResetLoginAttempt(s.DB, key)
+}
+
+// toAttempts - convert the database row
+func toAttempts(attempt *models.LoginAttempt) Attempts {
+	return Attempts{
+		Failures:     attempt.Failures,
+		LastFailedAt: attempt.LastFailedAt.Time,
+		LockedUntil:  attempt.LockedUntil.Time,
+	}
+}
@@ -0,0 +1,22 @@
+package mailers
+
+import (
+	"blog/models"
+
+	"github.com/gobuffalo/buffalo/render"
+)
+
+// SendEmailVerification - Mail the signed verification link to the address of the user
+func SendEmailVerification(user models.User, verificationURL string) error {
+	m := newMessage(user.Email, "Verify your email address")
+
+	data := render.Data{
+		"user":            user,
+		"verificationURL": verificationURL,
+	}
+	if err := m.AddBodies(data, r.HTML("email_verification.html"), r.Plain("email_verification.txt")); err != nil {
+		return err
+	}
+
+	return mailer.Send(m)
+}
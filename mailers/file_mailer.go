@@ -0,0 +1,49 @@
+package mailers
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo/mail"
+)
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9@._-]+`)
+
+// FileMailer - Drop every message as a file into a directory instead of sending it,
+// meant for local development and tests
+type FileMailer struct {
+	Dir string
+}
+
+// NewFileMailer - Mailer writing into the given directory
+func NewFileMailer(dir string) *FileMailer {
+	return &FileMailer{Dir: dir}
+}
+
+// Send - Write the message headers and bodies into a new file
+func (fm *FileMailer) Send(m mail.Message) error {
+	if err := os.MkdirAll(fm.Dir, 0700); err != nil {
+		return err
+	}
+
+	content := &bytes.Buffer{}
+	fmt.Fprintf(content, "From: %s\n", m.From)
+	fmt.Fprintf(content, "To: %s\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(content, "Subject: %s\n", m.Subject)
+	for field, value := range m.Headers {
+		fmt.Fprintf(content, "%s: %s\n", field, value)
+	}
+	for _, body := range m.Bodies {
+		fmt.Fprintf(content, "\n--- %s\n%s\n", body.ContentType, body.Content)
+	}
+
+	fileName := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), unsafeFileChars.ReplaceAllString(strings.Join(m.To, "_"), "_"))
+
+	return ioutil.WriteFile(filepath.Join(fm.Dir, fileName), content.Bytes(), 0600)
+}
@@ -0,0 +1,22 @@
+package mailers
+
+import (
+	"blog/models"
+
+	"github.com/gobuffalo/buffalo/render"
+)
+
+// SendMagicLink - Mail the single use log in link to the user
+func SendMagicLink(user models.User, magicLinkURL string) error {
+	m := newMessage(user.Email, "Your log in link")
+
+	data := render.Data{
+		"user":         user,
+		"magicLinkURL": magicLinkURL,
+	}
+	if err := m.AddBodies(data, r.HTML("magic_link.html"), r.Plain("magic_link.txt")); err != nil {
+		return err
+	}
+
+	return mailer.Send(m)
+}
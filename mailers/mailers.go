@@ -0,0 +1,60 @@
+package mailers
+
+import (
+	"log"
+
+	"github.com/gobuffalo/buffalo/mail"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/packr/v2"
+)
+
+// Mailer - anything able to deliver a mail message
+type Mailer interface {
+	Send(mail.Message) error
+}
+
+var mailer Mailer
+var r *render.Engine
+
+func init() {
+	var err error
+	if mailer, err = NewMailerFromEnv(); err != nil {
+		log.Fatal(err)
+	}
+
+	r = render.New(render.Options{
+		HTMLLayout:   "layout.plush.html",
+		TemplatesBox: packr.New("app:mailers:templates", "../templates/mail"),
+		Helpers:      render.Helpers{},
+	})
+}
+
+// NewMailerFromEnv - Build the mailer selected by MAILER, either "file" (default) or "smtp"
+func NewMailerFromEnv() (Mailer, error) {
+	if envy.Get("MAILER", "file") == "smtp" {
+		return NewSMTPMailer(
+			envy.Get("SMTP_HOST", "localhost"),
+			envy.Get("SMTP_PORT", "1025"),
+			envy.Get("SMTP_USER", ""),
+			envy.Get("SMTP_PASSWORD", ""),
+		)
+	}
+
+	return NewFileMailer(envy.Get("MAILER_DIR", "tmp/mail")), nil
+}
+
+// SetMailer - Replace the mailer used to deliver every message, tests use it to capture mails
+func SetMailer(m Mailer) {
+	mailer = m
+}
+
+// newMessage - Message with the configured sender address
+func newMessage(to string, subject string) mail.Message {
+	m := mail.NewMessage()
+	m.From = envy.Get("MAIL_FROM", "no-reply@buffalo-cms.api.dev")
+	m.To = []string{to}
+	m.Subject = subject
+
+	return m
+}
@@ -0,0 +1,128 @@
+package mailers
+
+import (
+	"blog/models"
+	"bufio"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeSMTPServer - accept a single SMTP session and hand back the DATA section
+func fakeSMTPServer(t *testing.T) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan string, 1)
+
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost fake smtp")
+
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case command == "DATA":
+				reply("354 end data with <CR><LF>.<CR><LF>")
+				data := &strings.Builder{}
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil || dataLine == ".\r\n" {
+						break
+					}
+					data.WriteString(dataLine)
+				}
+				received <- data.String()
+				reply("250 queued")
+			case command == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), received
+}
+
+func Test_FileMailer_SendPasswordReset(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mailers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetMailer(NewFileMailer(dir))
+
+	user := models.User{Email: "reset@example.com", Name: "Reset"}
+	if err := SendPasswordReset(user, "http://localhost/password/reset?token=abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("expected one mail file, got %d", len(files))
+	}
+	content, _ := ioutil.ReadFile(dir + "/" + files[0].Name())
+	if !strings.Contains(string(content), "To: reset@example.com") || !strings.Contains(string(content), "token=abc") {
+		t.Fatalf("unexpected mail content:\n%s", content)
+	}
+}
+
+func Test_SMTPMailer_SendPasswordReset(t *testing.T) {
+	address, received := fakeSMTPServer(t)
+	host, port, _ := net.SplitHostPort(address)
+
+	smtpMailer, err := NewSMTPMailer(host, port, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetMailer(smtpMailer)
+
+	user := models.User{Email: "reset@example.com", Name: "Reset"}
+	if err := SendPasswordReset(user, "http://localhost/password/reset?token=abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	data := <-received
+	if !strings.Contains(data, "Subject: Reset your password") || !strings.Contains(data, "token=abc") {
+		t.Fatalf("unexpected smtp data:\n%s", data)
+	}
+}
+
+func Test_FileMailer_SendMagicLink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mailers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetMailer(NewFileMailer(dir))
+
+	user := models.User{Email: "magic@example.com", Name: "Magic"}
+	if err := SendMagicLink(user, "http://localhost/login/magic?token=xyz"); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("expected one mail file, got %d", len(files))
+	}
+	content, _ := ioutil.ReadFile(dir + "/" + files[0].Name())
+	if !strings.Contains(string(content), "To: magic@example.com") || !strings.Contains(string(content), "token=xyz") {
+		t.Fatalf("unexpected mail content:\n%s", content)
+	}
+}
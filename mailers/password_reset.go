@@ -0,0 +1,22 @@
+package mailers
+
+import (
+	"blog/models"
+
+	"github.com/gobuffalo/buffalo/render"
+)
+
+// SendPasswordReset - Mail the single use password reset link to the user
+func SendPasswordReset(user models.User, resetURL string) error {
+	m := newMessage(user.Email, "Reset your password")
+
+	data := render.Data{
+		"user":     user,
+		"resetURL": resetURL,
+	}
+	if err := m.AddBodies(data, r.HTML("password_reset.html"), r.Plain("password_reset.txt")); err != nil {
+		return err
+	}
+
+	return mailer.Send(m)
+}
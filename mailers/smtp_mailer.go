@@ -0,0 +1,16 @@
+package mailers
+
+import (
+	"github.com/gobuffalo/buffalo/mail"
+)
+
+// NewSMTPMailer - Mailer delivering through an SMTP server, point it to a local fake
+// SMTP server such as MailHog to inspect the mails during development
+func NewSMTPMailer(host string, port string, user string, password string) (Mailer, error) {
+	sender, err := mail.NewSMTPSender(host, port, user, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return sender, nil
+}
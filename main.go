@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"log"
 
 	"blog/actions"
+	"blog/scheduler"
 )
 
 // main is the starting point for your Buffalo application.
@@ -14,6 +16,8 @@ import (
 // application that is. :)
 func main() {
 	app := actions.App()
+	// scheduled posts go live while the server runs
+	go scheduler.NewPublisherFromEnv(app.Logger).Run(context.Background())
 	if err := app.Serve(); err != nil {
 		log.Fatal(err)
 	}
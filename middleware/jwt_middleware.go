@@ -5,8 +5,8 @@ import (
 	"blog/utils"
 	"net/http"
 	"strings"
+	"time"
 
-	"github.com/dgrijalva/jwt-go/v4"
 	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/buffalo/render"
 	"github.com/gobuffalo/pop/v5"
@@ -25,7 +25,7 @@ func JWTMiddleware(next buffalo.Handler) buffalo.Handler {
 
 		splitToken := strings.SplitAfter(authorizationHeader, "Bearer")
 		var jwtToken string
-		if len(authorizationHeader) >= 2 {
+		if len(splitToken) >= 2 {
 			jwtToken = strings.TrimSpace(splitToken[1])
 		} else {
 			unauthResponse := ErrorResponse{
@@ -35,30 +35,68 @@ func JWTMiddleware(next buffalo.Handler) buffalo.Handler {
 
 			return c.Render(http.StatusUnauthorized, render.JSON(unauthResponse))
 		}
-		claims := &jwt.StandardClaims{}
-		jwtKey, readErr := utils.ReadJWTKey()
+		database := c.Value("tx").(*pop.Connection)
+		// personal access tokens are opaque, they are looked up instead of parsed
+		if strings.HasPrefix(jwtToken, models.PersonalAccessTokenPrefix) {
+			return personalAccessTokenAuth(c, database, jwtToken, next)
+		}
+		keyRing, ringErr := utils.CurrentKeyRing()
 
-		if readErr != nil {
-			return c.Error(http.StatusInternalServerError, readErr)
+		if ringErr != nil {
+			return c.Error(http.StatusInternalServerError, ringErr)
 		}
-		token, err := jwt.ParseWithClaims(jwtToken, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
+		claims, err := utils.ParseAccessToken(jwtToken, keyRing)
 
-		if !token.Valid || err != nil {
+		if err != nil {
 			unauthResponse := ErrorResponse{Code: http.StatusUnauthorized, Message: "Invalid JWT token"}
 
 			return c.Render(http.StatusUnauthorized, render.JSON(unauthResponse))
 		}
-		// verify the email is available in DB
-		database := c.Value("tx").(*pop.Connection)
+		// reject tokens that have been logged out
+		revoked, revokedErr := models.IsTokenRevoked(database, claims.ID)
+		if revokedErr != nil {
+			return c.Error(http.StatusInternalServerError, revokedErr)
+		}
+		if revoked {
+			unauthResponse := ErrorResponse{Code: http.StatusUnauthorized, Message: "The JWT token has been revoked"}
+			return c.Render(http.StatusUnauthorized, render.JSON(unauthResponse))
+		}
+		// verify the user is available in DB
 		tokenUser := &models.User{}
-		dbErr := database.Find(tokenUser, claims.ID)
+		dbErr := database.Find(tokenUser, claims.Subject)
 		if dbErr != nil {
 			unauthResponse := ErrorResponse{Code: http.StatusUnauthorized, Message: "Invalid User ID"}
 			return c.Render(http.StatusUnauthorized, render.JSON(unauthResponse))
 		}
+		// tokens of a session end with the session, the others with the revocation time of the user
+		if claims.SessionID == "" && tokenUser.IssuedBeforeRevocation(claims.IssuedAt.Time) {
+			unauthResponse := ErrorResponse{Code: http.StatusUnauthorized, Message: "The JWT token has been revoked"}
+			return c.Render(http.StatusUnauthorized, render.JSON(unauthResponse))
+		}
+
+		// tokens of a revoked third party client are rejected
+		if claims.ClientID != "" {
+			if _, clientErr := models.FindOAuthClient(database, claims.ClientID); clientErr != nil {
+				unauthResponse := ErrorResponse{Code: http.StatusUnauthorized, Message: "The OAuth client has been revoked"}
+				return c.Render(http.StatusUnauthorized, render.JSON(unauthResponse))
+			}
+		}
+
+		// tokens of a revoked session are rejected, the activity of the session is recorded
+		if claims.SessionID != "" {
+			session := &models.UserSession{}
+			if sessionErr := database.Find(session, claims.SessionID); sessionErr != nil || !session.IsActive() {
+				unauthResponse := ErrorResponse{Code: http.StatusUnauthorized, Message: "The session has been revoked"}
+				return c.Render(http.StatusUnauthorized, render.JSON(unauthResponse))
+			}
+			if touchErr := session.Touch(database, time.Now()); touchErr != nil {
+				return c.Error(http.StatusInternalServerError, touchErr)
+			}
+			c.Set("authSession", *session)
+		}
 
+		c.Set("authClaims", claims.StandardClaims)
+		c.Set("authScopes", claims.Scopes())
 		c.Set("authUser", *tokenUser)
 
 		middlewareErr := next(c)
@@ -67,3 +105,28 @@ func JWTMiddleware(next buffalo.Handler) buffalo.Handler {
 		return middlewareErr
 	}
 }
+
+// personalAccessTokenAuth - authenticate the owner of an active personal access token
+func personalAccessTokenAuth(c buffalo.Context, database *pop.Connection, plainToken string, next buffalo.Handler) error {
+	accessToken, findErr := models.FindPersonalAccessToken(database, plainToken)
+	if findErr != nil || !accessToken.IsActive(time.Now()) {
+		unauthResponse := ErrorResponse{Code: http.StatusUnauthorized, Message: "Invalid personal access token"}
+		return c.Render(http.StatusUnauthorized, render.JSON(unauthResponse))
+	}
+
+	tokenUser := &models.User{}
+	if dbErr := database.Find(tokenUser, accessToken.UserID); dbErr != nil {
+		unauthResponse := ErrorResponse{Code: http.StatusUnauthorized, Message: "Invalid User ID"}
+		return c.Render(http.StatusUnauthorized, render.JSON(unauthResponse))
+	}
+
+	if err := accessToken.Touch(database, time.Now()); err != nil {
+		return c.Error(http.StatusInternalServerError, err)
+	}
+
+	c.Set("authPersonalAccessToken", *accessToken)
+	c.Set("authScopes", accessToken.ScopeList())
+	c.Set("authUser", *tokenUser)
+
+	return next(c)
+}
@@ -2,16 +2,16 @@ package middleware
 
 import (
 	"blog/models"
+	"blog/policies"
 	"blog/utils"
 	"net/http"
-	"strings"
 
 	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/buffalo/render"
 	"github.com/gobuffalo/pop/v5"
 )
 
-// PostGuardMiddleware - check for the ownership of the post
+// PostGuardMiddleware - ask the policies whether the caller may update or delete the post
 func PostGuardMiddleware(next buffalo.Handler) buffalo.Handler {
 	return func(c buffalo.Context) error {
 		authUser := c.Value("authUser").(models.User)
@@ -20,13 +20,18 @@ func PostGuardMiddleware(next buffalo.Handler) buffalo.Handler {
 
 		db := c.Value("tx").(*pop.Connection)
 
-		queryError := db.Eager().Find(post, c.Param("post_id"))
+		queryError := db.Scope(models.PostsNotDeleted).Eager().Find(post, c.Param("post_id"))
 		errorResponse := utils.NewErrorResponse(http.StatusUnauthorized, "post", "Unauthorized access")
 
 		if queryError != nil {
 			return c.Render(http.StatusUnauthorized, render.JSON(errorResponse))
 		}
-		if strings.Compare(authUser.ID.String(), post.User.ID.String()) != 0 {
+
+		action := policies.ActionUpdate
+		if c.Request().Method == http.MethodDelete {
+			action = policies.ActionDelete
+		}
+		if !policies.Can(authUser, action, post) {
 			return c.Render(http.StatusUnauthorized, render.JSON(errorResponse))
 		}
 		return next(c)
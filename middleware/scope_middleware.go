@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"blog/utils"
+	"fmt"
+	"net/http"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+)
+
+// ScopeMiddleware - only let tokens granted every given scope through, must run after JWTMiddleware
+func ScopeMiddleware(scopes ...string) buffalo.MiddlewareFunc {
+	return func(next buffalo.Handler) buffalo.Handler {
+		return func(c buffalo.Context) error {
+			grantedScopes, _ := c.Value("authScopes").([]string)
+
+			for _, scope := range scopes {
+				if !utils.HasScope(grantedScopes, scope) {
+					errorResponse := utils.NewErrorResponse(http.StatusForbidden, "scope", fmt.Sprintf("The token is missing the %s scope", scope))
+					return c.Render(http.StatusForbidden, render.JSON(errorResponse))
+				}
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"blog/models"
+	"blog/utils"
+	"net/http"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+)
+
+// VerifiedEmailMiddleware - only let users with a verified email address through, must run after JWTMiddleware
+func VerifiedEmailMiddleware(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		authUser := c.Value("authUser").(models.User)
+
+		if !authUser.IsEmailVerified() {
+			errorResponse := utils.NewErrorResponse(http.StatusForbidden, "email", "Please verify your email address first")
+			return c.Render(http.StatusForbidden, render.JSON(errorResponse))
+		}
+
+		return next(c)
+	}
+}
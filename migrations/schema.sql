@@ -15,6 +15,305 @@
 /*!40101 SET @OLD_SQL_MODE=@@SQL_MODE, SQL_MODE='NO_AUTO_VALUE_ON_ZERO' */;
 /*!40111 SET @OLD_SQL_NOTES=@@SQL_NOTES, SQL_NOTES=0 */;
 
+--
+-- Table structure for table `audit_events`
+--
+
+DROP TABLE IF EXISTS `audit_events`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `audit_events` (
+  `id` char(36) NOT NULL,
+  `action` varchar(64) NOT NULL,
+  `actor_id` char(36) DEFAULT NULL,
+  `ip_address` varchar(45) NOT NULL DEFAULT '',
+  `user_agent` varchar(512) NOT NULL DEFAULT '',
+  `target_type` varchar(64) NOT NULL DEFAULT '',
+  `target_id` varchar(255) NOT NULL DEFAULT '',
+  `changes` text NOT NULL,
+  `metadata` text NOT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `audit_events_created_at_idx` (`created_at`),
+  KEY `audit_events_action_created_at_idx` (`action`,`created_at`),
+  KEY `audit_events_actor_id_created_at_idx` (`actor_id`,`created_at`),
+  KEY `audit_events_target_type_target_id_idx` (`target_type`,`target_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `categories`
+--
+
+DROP TABLE IF EXISTS `categories`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `categories` (
+  `id` char(36) NOT NULL,
+  `parent_id` char(36) DEFAULT NULL,
+  `name` varchar(255) NOT NULL,
+  `slug` varchar(255) NOT NULL,
+  `created_at` datetime NOT NULL,
+  `updated_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `categories_slug_idx` (`slug`),
+  KEY `fk_category_parent_id` (`parent_id`),
+  CONSTRAINT `fk_category_parent_id` FOREIGN KEY (`parent_id`) REFERENCES `categories` (`id`) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `login_attempts`
+--
+
+DROP TABLE IF EXISTS `login_attempts`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `login_attempts` (
+  `id` char(36) NOT NULL,
+  `attempt_key` varchar(255) NOT NULL,
+  `failures` int(11) NOT NULL DEFAULT '0',
+  `last_failed_at` datetime DEFAULT NULL,
+  `locked_until` datetime DEFAULT NULL,
+  `created_at` datetime NOT NULL,
+  `updated_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `login_attempts_attempt_key_idx` (`attempt_key`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `magic_link_tokens`
+--
+
+DROP TABLE IF EXISTS `magic_link_tokens`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `magic_link_tokens` (
+  `id` char(36) NOT NULL,
+  `user_id` char(36) NOT NULL,
+  `email` varchar(255) NOT NULL,
+  `token_hash` varchar(64) NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `used_at` datetime DEFAULT NULL,
+  `created_at` datetime NOT NULL,
+  `updated_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `magic_link_tokens_token_hash_idx` (`token_hash`),
+  KEY `fk_magic_link_token_user_id` (`user_id`),
+  CONSTRAINT `fk_magic_link_token_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `mfa_recovery_codes`
+--
+
+DROP TABLE IF EXISTS `mfa_recovery_codes`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `mfa_recovery_codes` (
+  `id` char(36) NOT NULL,
+  `user_id` char(36) NOT NULL,
+  `code_hash` varchar(64) NOT NULL,
+  `used_at` datetime DEFAULT NULL,
+  `created_at` datetime NOT NULL,
+  `updated_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `mfa_recovery_codes_user_id_code_hash_idx` (`user_id`,`code_hash`),
+  CONSTRAINT `fk_mfa_recovery_code_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `oauth_authorization_codes`
+--
+
+DROP TABLE IF EXISTS `oauth_authorization_codes`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `oauth_authorization_codes` (
+  `id` char(36) NOT NULL,
+  `oauth_client_id` char(36) NOT NULL,
+  `user_id` char(36) NOT NULL,
+  `code_hash` varchar(64) NOT NULL,
+  `redirect_uri` text NOT NULL,
+  `scopes` varchar(255) NOT NULL,
+  `code_challenge` varchar(128) NOT NULL,
+  `family_id` char(36) DEFAULT NULL,
+  `expires_at` datetime NOT NULL,
+  `used_at` datetime DEFAULT NULL,
+  `created_at` datetime NOT NULL,
+  `updated_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `oauth_authorization_codes_code_hash_idx` (`code_hash`),
+  KEY `fk_oauth_authorization_code_oauth_client_id` (`oauth_client_id`),
+  KEY `fk_oauth_authorization_code_user_id` (`user_id`),
+  CONSTRAINT `fk_oauth_authorization_code_oauth_client_id` FOREIGN KEY (`oauth_client_id`) REFERENCES `oauth_clients` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_oauth_authorization_code_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `oauth_clients`
+--
+
+DROP TABLE IF EXISTS `oauth_clients`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `oauth_clients` (
+  `id` char(36) NOT NULL,
+  `user_id` char(36) NOT NULL,
+  `name` varchar(255) NOT NULL,
+  `client_id` varchar(64) NOT NULL,
+  `client_secret_hash` varchar(64) NOT NULL DEFAULT '',
+  `redirect_uris` text NOT NULL,
+  `scopes` varchar(255) NOT NULL,
+  `confidential` tinyint(1) NOT NULL DEFAULT '0',
+  `revoked_at` datetime DEFAULT NULL,
+  `created_at` datetime NOT NULL,
+  `updated_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `oauth_clients_client_id_idx` (`client_id`),
+  KEY `fk_oauth_client_user_id` (`user_id`),
+  CONSTRAINT `fk_oauth_client_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `oidc_auth_requests`
+--
+
+DROP TABLE IF EXISTS `oidc_auth_requests`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `oidc_auth_requests` (
+  `id` char(36) NOT NULL,
+  `state_hash` varchar(64) NOT NULL,
+  `provider` varchar(64) NOT NULL,
+  `nonce` varchar(255) NOT NULL,
+  `code_verifier` varchar(255) NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `created_at` datetime NOT NULL,
+  `updated_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `oidc_auth_requests_state_hash_idx` (`state_hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `password_reset_tokens`
+--
+
+DROP TABLE IF EXISTS `password_reset_tokens`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `password_reset_tokens` (
+  `id` char(36) NOT NULL,
+  `user_id` char(36) NOT NULL,
+  `token_hash` varchar(64) NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `used_at` datetime DEFAULT NULL,
+  `created_at` datetime NOT NULL,
+  `updated_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `password_reset_tokens_token_hash_idx` (`token_hash`),
+  KEY `fk_password_reset_token_user_id` (`user_id`),
+  CONSTRAINT `fk_password_reset_token_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `personal_access_tokens`
+--
+
+DROP TABLE IF EXISTS `personal_access_tokens`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `personal_access_tokens` (
+  `id` char(36) NOT NULL,
+  `user_id` char(36) NOT NULL,
+  `name` varchar(255) NOT NULL,
+  `token_prefix` varchar(16) NOT NULL,
+  `token_hash` varchar(64) NOT NULL,
+  `expires_at` datetime DEFAULT NULL,
+  `last_used_at` datetime DEFAULT NULL,
+  `revoked_at` datetime DEFAULT NULL,
+  `created_at` datetime NOT NULL,
+  `updated_at` datetime NOT NULL,
+  `scopes` varchar(255) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `personal_access_tokens_token_hash_idx` (`token_hash`),
+  KEY `fk_personal_access_token_user_id` (`user_id`),
+  CONSTRAINT `fk_personal_access_token_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `post_revisions`
+--
+
+DROP TABLE IF EXISTS `post_revisions`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `post_revisions` (
+  `id` char(36) NOT NULL,
+  `post_id` char(36) NOT NULL,
+  `user_id` char(36) DEFAULT NULL,
+  `number` int(11) NOT NULL,
+  `title` varchar(255) NOT NULL,
+  `description` text NOT NULL,
+  `restored_from` int(11) DEFAULT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `post_revisions_post_id_number_idx` (`post_id`,`number`),
+  KEY `post_revisions_created_at_idx` (`created_at`),
+  KEY `fk_post_revision_user_id` (`user_id`),
+  CONSTRAINT `fk_post_revision_post_id` FOREIGN KEY (`post_id`) REFERENCES `posts` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_post_revision_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `post_slug_redirects`
+--
+
+DROP TABLE IF EXISTS `post_slug_redirects`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `post_slug_redirects` (
+  `id` char(36) NOT NULL,
+  `post_id` char(36) NOT NULL,
+  `slug` varchar(255) NOT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `post_slug_redirects_slug_idx` (`slug`),
+  KEY `fk_post_slug_redirect_post_id` (`post_id`),
+  CONSTRAINT `fk_post_slug_redirect_post_id` FOREIGN KEY (`post_id`) REFERENCES `posts` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `post_tags`
+--
+
+DROP TABLE IF EXISTS `post_tags`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `post_tags` (
+  `id` char(36) NOT NULL,
+  `post_id` char(36) NOT NULL,
+  `tag_id` char(36) NOT NULL,
+  `created_at` datetime NOT NULL,
+  `updated_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `post_tags_post_id_tag_id_idx` (`post_id`,`tag_id`),
+  KEY `fk_post_tag_tag_id` (`tag_id`),
+  CONSTRAINT `fk_post_tag_post_id` FOREIGN KEY (`post_id`) REFERENCES `posts` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_post_tag_tag_id` FOREIGN KEY (`tag_id`) REFERENCES `tags` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Table structure for table `posts`
 --
@@ -27,15 +326,71 @@ CREATE TABLE `posts` (
   `user_id` char(36) NOT NULL,
   `title` varchar(255) NOT NULL,
   `description` text NOT NULL,
-  `published_at` datetime NOT NULL,
+  `published_at` datetime DEFAULT NULL,
   `created_at` datetime NOT NULL,
   `updated_at` datetime NOT NULL,
+  `status` varchar(16) NOT NULL DEFAULT 'draft',
+  `deleted_at` datetime DEFAULT NULL,
+  `slug` varchar(255) NOT NULL DEFAULT '',
+  `category_id` char(36) DEFAULT NULL,
   PRIMARY KEY (`id`),
+  UNIQUE KEY `posts_slug_idx` (`slug`),
   KEY `fk_post_user_id` (`user_id`),
+  KEY `fk_post_category_id` (`category_id`),
+  KEY `posts_status_published_at_idx` (`status`,`published_at`),
+  KEY `posts_deleted_at_idx` (`deleted_at`),
+  CONSTRAINT `fk_post_category_id` FOREIGN KEY (`category_id`) REFERENCES `categories` (`id`) ON DELETE SET NULL,
   CONSTRAINT `fk_post_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
+--
+-- Table structure for table `refresh_tokens`
+--
+
+DROP TABLE IF EXISTS `refresh_tokens`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `refresh_tokens` (
+  `id` char(36) NOT NULL,
+  `user_id` char(36) NOT NULL,
+  `family_id` char(36) NOT NULL,
+  `token_hash` varchar(64) NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `rotated_at` datetime DEFAULT NULL,
+  `revoked_at` datetime DEFAULT NULL,
+  `created_at` datetime NOT NULL,
+  `updated_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `refresh_tokens_token_hash_idx` (`token_hash`),
+  KEY `refresh_tokens_family_id_idx` (`family_id`),
+  KEY `fk_refresh_token_user_id` (`user_id`),
+  CONSTRAINT `fk_refresh_token_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `revoked_tokens`
+--
+
+DROP TABLE IF EXISTS `revoked_tokens`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `revoked_tokens` (
+  `id` char(36) NOT NULL,
+  `jti` varchar(64) NOT NULL,
+  `user_id` char(36) NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `created_at` datetime NOT NULL,
+  `updated_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `revoked_tokens_jti_idx` (`jti`),
+  KEY `revoked_tokens_expires_at_idx` (`expires_at`),
+  KEY `fk_revoked_token_user_id` (`user_id`),
+  CONSTRAINT `fk_revoked_token_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Table structure for table `schema_migration`
 --
@@ -49,6 +404,74 @@ CREATE TABLE `schema_migration` (
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
+--
+-- Table structure for table `tags`
+--
+
+DROP TABLE IF EXISTS `tags`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `tags` (
+  `id` char(36) NOT NULL,
+  `name` varchar(255) NOT NULL,
+  `slug` varchar(255) NOT NULL,
+  `created_at` datetime NOT NULL,
+  `updated_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `tags_slug_idx` (`slug`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `user_identities`
+--
+
+DROP TABLE IF EXISTS `user_identities`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `user_identities` (
+  `id` char(36) NOT NULL,
+  `user_id` char(36) NOT NULL,
+  `provider` varchar(64) NOT NULL,
+  `subject` varchar(255) NOT NULL,
+  `email` varchar(255) NOT NULL,
+  `created_at` datetime NOT NULL,
+  `updated_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `user_identities_provider_subject_idx` (`provider`,`subject`),
+  KEY `fk_user_identity_user_id` (`user_id`),
+  CONSTRAINT `fk_user_identity_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Table structure for table `user_sessions`
+--
+
+DROP TABLE IF EXISTS `user_sessions`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `user_sessions` (
+  `id` char(36) NOT NULL,
+  `user_id` char(36) NOT NULL,
+  `family_id` char(36) NOT NULL,
+  `user_agent` varchar(512) NOT NULL DEFAULT '',
+  `ip_address` varchar(45) NOT NULL DEFAULT '',
+  `last_seen_at` datetime NOT NULL,
+  `revoked_at` datetime DEFAULT NULL,
+  `created_at` datetime NOT NULL,
+  `updated_at` datetime NOT NULL,
+  `oauth_client_id` char(36) DEFAULT NULL,
+  `scopes` varchar(255) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `user_sessions_family_id_idx` (`family_id`),
+  KEY `fk_user_session_user_id` (`user_id`),
+  KEY `fk_user_session_oauth_client_id` (`oauth_client_id`),
+  CONSTRAINT `fk_user_session_oauth_client_id` FOREIGN KEY (`oauth_client_id`) REFERENCES `oauth_clients` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_user_session_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Table structure for table `users`
 --
@@ -63,6 +486,15 @@ CREATE TABLE `users` (
   `name` varchar(255) NOT NULL,
   `created_at` datetime NOT NULL,
   `updated_at` datetime NOT NULL,
+  `tokens_revoked_at` datetime DEFAULT NULL,
+  `email_verified_at` datetime DEFAULT NULL,
+  `email_verification_sent_at` datetime DEFAULT NULL,
+  `totp_secret` varchar(255) DEFAULT NULL,
+  `totp_enabled_at` datetime DEFAULT NULL,
+  `totp_last_step` bigint(20) NOT NULL DEFAULT '0',
+  `role` varchar(255) NOT NULL DEFAULT 'author',
+  `deletion_scheduled_at` datetime DEFAULT NULL,
+  `deletion_posts_strategy` varchar(16) NOT NULL DEFAULT '',
   PRIMARY KEY (`id`),
   UNIQUE KEY `users_email_idx` (`email`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PostsStrategyDelete - the posts are deleted together with the account
+	PostsStrategyDelete = "delete"
+	// PostsStrategyReassign - the posts are kept and handed to the deleted user placeholder
+	PostsStrategyReassign = "reassign"
+)
+
+// DeletedUserEmail - email of the placeholder that keeps the posts of deleted accounts
+const DeletedUserEmail = "deleted-user@users.invalid"
+
+// IsDeletionScheduled - the user asked for the account to be deleted
+func (u *User) IsDeletionScheduled() bool {
+	return u.DeletionScheduledAt.Valid
+}
+
+// ScheduleDeletion - delete the account at the given time unless the user cancels before
+func (u *User) ScheduleDeletion(tx *pop.Connection, postsStrategy string, at time.Time) error {
+	if postsStrategy != PostsStrategyDelete && postsStrategy != PostsStrategyReassign {
+		return errors.Errorf("unknown posts strategy %q", postsStrategy)
+	}
+	u.DeletionScheduledAt = nulls.NewTime(at)
+	u.DeletionPostsStrategy = postsStrategy
+
+	return tx.UpdateColumns(u, "deletion_scheduled_at", "deletion_posts_strategy", "updated_at")
+}
+
+// CancelDeletion - keep the account
+func (u *User) CancelDeletion(tx *pop.Connection) error {
+	u.DeletionScheduledAt = nulls.Time{}
+	u.DeletionPostsStrategy = ""
+
+	return tx.UpdateColumns(u, "deletion_scheduled_at", "deletion_posts_strategy", "updated_at")
+}
+
+// Purge - delete the account, the posts follow the chosen strategy and every other
+// record of the user is removed by the cascading foreign keys
+func (u *User) Purge(tx *pop.Connection) error {
+	if u.DeletionPostsStrategy == PostsStrategyReassign {
+		placeholder, err := DeletedUserPlaceholder(tx)
+		if err != nil {
+			return err
+		}
+		if err := tx.RawQuery("UPDATE posts SET user_id = ? WHERE user_id = ?", placeholder.ID, u.ID).Exec(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return tx.Destroy(u)
+}
+
+// DeletedUserPlaceholder - find or create the user owning the posts of deleted accounts,
+// its password can not be matched so nobody can log in as the placeholder
+func DeletedUserPlaceholder(tx *pop.Connection) (*User, error) {
+	placeholder := &User{}
+	exists, err := tx.Where("email = ?", DeletedUserEmail).Exists(placeholder)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if exists {
+		return placeholder, tx.Where("email = ?", DeletedUserEmail).First(placeholder)
+	}
+
+	placeholder = &User{
+		Email:    DeletedUserEmail,
+		Password: NoPassword,
+		Name:     "Deleted user",
+		Role:     RoleReader,
+	}
+
+	return placeholder, tx.Create(placeholder)
+}
+
+// PurgeDueUsers - delete every account whose grace period is over
+func PurgeDueUsers(tx *pop.Connection, now time.Time) (int, error) {
+	users := &Users{}
+	if err := tx.Where("deletion_scheduled_at <= ?", now).All(users); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	for i := range *users {
+		if err := (*users)[i].Purge(tx); err != nil {
+			return i, err
+		}
+	}
+
+	return len(*users), nil
+}
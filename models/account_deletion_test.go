@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+func (ms *ModelSuite) Test_User_Purge_ReassignsPosts() {
+	user := &User{Email: "purge@example.com", Password: "secret", Name: "Purge"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+	post := &Post{Title: "Kept", Description: "Kept after the deletion", UserID: user.ID}
+	ms.NoError(DB.Create(post))
+
+	ms.NoError(user.ScheduleDeletion(DB, PostsStrategyReassign, time.Now().Add(-time.Minute)))
+	purged, err := PurgeDueUsers(DB, time.Now())
+	ms.NoError(err)
+	ms.Equal(1, purged)
+
+	placeholder, err := DeletedUserPlaceholder(DB)
+	ms.NoError(err)
+	kept := &Post{}
+	ms.NoError(DB.Find(kept, post.ID))
+	ms.Equal(placeholder.ID, kept.UserID)
+}
+
+func (ms *ModelSuite) Test_User_Purge_DeletesPosts() {
+	user := &User{Email: "purge-posts@example.com", Password: "secret", Name: "Purge"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+	post := &Post{Title: "Gone", Description: "Deleted with the account", UserID: user.ID}
+	ms.NoError(DB.Create(post))
+
+	ms.NoError(user.ScheduleDeletion(DB, PostsStrategyDelete, time.Now().Add(time.Hour)))
+	purged, err := PurgeDueUsers(DB, time.Now())
+	ms.NoError(err)
+	ms.Equal(0, purged)
+
+	ms.NoError(user.Purge(DB))
+	count, err := DB.Where("id = ?", post.ID).Count(&Post{})
+	ms.NoError(err)
+	ms.Equal(0, count)
+}
@@ -0,0 +1,271 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	// AuditLoginSucceeded - a user logged in, the method is in the metadata
+	AuditLoginSucceeded = "auth.login.succeeded"
+	// AuditLoginFailed - a log in was rejected, the email and the reason are in the metadata
+	AuditLoginFailed = "auth.login.failed"
+	// AuditRegistered - a user registered
+	AuditRegistered = "auth.registered"
+	// AuditTokenIssued - a personal access token or an OAuth token was issued
+	AuditTokenIssued = "token.issued"
+	// AuditTokenRevoked - a token, a session or every token of a user was revoked
+	AuditTokenRevoked = "token.revoked"
+	// AuditPostCreated - a post was created
+	AuditPostCreated = "post.created"
+	// AuditPostUpdated - a post was updated
+	AuditPostUpdated = "post.updated"
+	// AuditPostDeleted - a post was moved to the trash
+	AuditPostDeleted = "post.deleted"
+	// AuditPostRestored - a post was taken out of the trash
+	AuditPostRestored = "post.restored"
+)
+
+// ErrAuditEventAppendOnly - audit events are never changed or removed once written
+var ErrAuditEventAppendOnly = errors.New("audit events are append-only")
+
+// auditExportBatchSize - events read per query while exporting
+const auditExportBatchSize = 500
+
+// auditIgnoredFields - bookkeeping fields left out of every diff
+var auditIgnoredFields = []string{"created_at", "updated_at"}
+
+// AuditChange - The value of a field before and after the event
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditChanges - The changed fields of the target, keyed by their JSON name
+type AuditChanges map[string]AuditChange
+
+// Value stores the changes as JSON
+func (c AuditChanges) Value() (driver.Value, error) {
+	return marshalAuditJSON(c)
+}
+
+// Scan reads the changes from JSON
+func (c *AuditChanges) Scan(src interface{}) error {
+	return unmarshalAuditJSON(src, c)
+}
+
+// AuditMetadata - Details of the event that are not a change of the target
+type AuditMetadata map[string]string
+
+// Value stores the metadata as JSON
+func (m AuditMetadata) Value() (driver.Value, error) {
+	return marshalAuditJSON(m)
+}
+
+// Scan reads the metadata from JSON
+func (m *AuditMetadata) Scan(src interface{}) error {
+	return unmarshalAuditJSON(src, m)
+}
+
+// AuditEvent is used by pop to map your audit_events database table to your go code.
+// Events are append-only, the actor is kept as a plain ID so the trail survives the
+// deletion of the account.
+type AuditEvent struct {
+	ID         uuid.UUID     `json:"id" db:"id"`
+	Action     string        `json:"action" db:"action"`
+	ActorID    nulls.UUID    `json:"actor_id" db:"actor_id"`
+	IPAddress  string        `json:"ip_address" db:"ip_address"`
+	UserAgent  string        `json:"user_agent" db:"user_agent"`
+	TargetType string        `json:"target_type" db:"target_type"`
+	TargetID   string        `json:"target_id" db:"target_id"`
+	Changes    AuditChanges  `json:"changes" db:"changes"`
+	Metadata   AuditMetadata `json:"metadata" db:"metadata"`
+	CreatedAt  time.Time     `json:"created_at" db:"created_at"`
+}
+
+// String is not required by pop and may be deleted
+func (e AuditEvent) String() string {
+	je, _ := json.Marshal(e)
+	return string(je)
+}
+
+// AuditEvents is not required by pop and may be deleted
+type AuditEvents []AuditEvent
+
+// BeforeUpdate refuses to change a recorded event
+func (e *AuditEvent) BeforeUpdate(tx *pop.Connection) error {
+	return ErrAuditEventAppendOnly
+}
+
+// BeforeDestroy refuses to remove a recorded event
+func (e *AuditEvent) BeforeDestroy(tx *pop.Connection) error {
+	return ErrAuditEventAppendOnly
+}
+
+// RecordAuditEvent - append the event to the audit trail
+func RecordAuditEvent(tx *pop.Connection, event *AuditEvent) error {
+	if len(event.UserAgent) > 512 {
+		event.UserAgent = event.UserAgent[:512]
+	}
+	if event.Changes == nil {
+		event.Changes = AuditChanges{}
+	}
+	if event.Metadata == nil {
+		event.Metadata = AuditMetadata{}
+	}
+
+	return errors.WithStack(tx.Create(event))
+}
+
+// DiffAudit - the fields that differ between two states of a target, compared through their
+// JSON form so hidden fields like password hashes never reach the trail. A nil before or
+// after records a creation or a deletion.
+func DiffAudit(before interface{}, after interface{}, ignored ...string) AuditChanges {
+	beforeFields, afterFields := auditFields(before), auditFields(after)
+	for _, field := range append(ignored, auditIgnoredFields...) {
+		delete(beforeFields, field)
+		delete(afterFields, field)
+	}
+
+	changes := AuditChanges{}
+	for field, beforeValue := range beforeFields {
+		if afterValue, ok := afterFields[field]; !ok || !reflect.DeepEqual(beforeValue, afterValue) {
+			changes[field] = AuditChange{Before: beforeValue, After: afterFields[field]}
+		}
+	}
+	for field, afterValue := range afterFields {
+		if _, ok := beforeFields[field]; !ok {
+			changes[field] = AuditChange{After: afterValue}
+		}
+	}
+
+	return changes
+}
+
+// AuditFilter - Narrows the audit trail, empty fields match everything
+type AuditFilter struct {
+	Action     string
+	ActorID    string
+	TargetType string
+	TargetID   string
+	IPAddress  string
+	Since      time.Time
+	Until      time.Time
+}
+
+// Apply - add the conditions of the filter to the query
+func (f AuditFilter) Apply(query *pop.Query) *pop.Query {
+	if f.Action != "" {
+		query = query.Where("action = ?", f.Action)
+	}
+	if f.ActorID != "" {
+		query = query.Where("actor_id = ?", f.ActorID)
+	}
+	if f.TargetType != "" {
+		query = query.Where("target_type = ?", f.TargetType)
+	}
+	if f.TargetID != "" {
+		query = query.Where("target_id = ?", f.TargetID)
+	}
+	if f.IPAddress != "" {
+		query = query.Where("ip_address = ?", f.IPAddress)
+	}
+	if !f.Since.IsZero() {
+		query = query.Where("created_at >= ?", f.Since)
+	}
+	if !f.Until.IsZero() {
+		query = query.Where("created_at < ?", f.Until)
+	}
+
+	return query
+}
+
+// FailedLogInIPs - the client IPs the user failed to log in from since the given time
+func FailedLogInIPs(tx *pop.Connection, userID uuid.UUID, since time.Time) ([]string, error) {
+	rows := []struct {
+		IPAddress string `db:"ip_address"`
+	}{}
+	if err := tx.RawQuery(
+		"SELECT DISTINCT ip_address FROM audit_events WHERE action = ? AND target_id = ? AND created_at >= ?",
+		AuditLoginFailed, userID.String(), since,
+	).All(&rows); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	ips := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ips = append(ips, row.IPAddress)
+	}
+
+	return ips, nil
+}
+
+// ExportAuditEvents - write the matching events as newline delimited JSON, oldest first, reading
+// them in batches so the whole trail never sits in memory. Returns the number of events written.
+func ExportAuditEvents(tx *pop.Connection, filter AuditFilter, w io.Writer) (int, error) {
+	encoder := json.NewEncoder(w)
+	exported := 0
+	for page := 1; ; page++ {
+		events := AuditEvents{}
+		query := filter.Apply(tx.Paginate(page, auditExportBatchSize))
+		if err := query.Order("created_at asc, id asc").All(&events); err != nil {
+			return exported, errors.WithStack(err)
+		}
+		for _, event := range events {
+			if err := encoder.Encode(event); err != nil {
+				return exported, errors.WithStack(err)
+			}
+			exported++
+		}
+		if len(events) < auditExportBatchSize {
+			return exported, nil
+		}
+	}
+}
+
+func auditFields(value interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if value == nil {
+		return fields
+	}
+	if reflected := reflect.ValueOf(value); reflected.Kind() == reflect.Ptr && reflected.IsNil() {
+		return fields
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fields
+	}
+	json.Unmarshal(encoded, &fields)
+
+	return fields
+}
+
+func marshalAuditJSON(value interface{}) (driver.Value, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return string(encoded), nil
+}
+
+func unmarshalAuditJSON(src interface{}, target interface{}) error {
+	switch value := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(value, target)
+	case string:
+		return json.Unmarshal([]byte(value), target)
+	}
+
+	return errors.Errorf("unsupported audit column type %T", src)
+}
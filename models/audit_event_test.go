@@ -0,0 +1,54 @@
+package models
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gofrs/uuid"
+)
+
+func (ms *ModelSuite) Test_AuditEvent_AppendOnly() {
+	event := &AuditEvent{Action: AuditLoginFailed, TargetType: "user", Metadata: AuditMetadata{"email": "x@example.com"}}
+	ms.NoError(RecordAuditEvent(DB, event))
+
+	event.Action = AuditLoginSucceeded
+	ms.Error(DB.Update(event))
+	ms.Error(DB.Destroy(event))
+
+	stored := &AuditEvent{}
+	ms.NoError(DB.Find(stored, event.ID))
+	ms.Equal(AuditLoginFailed, stored.Action)
+	ms.Equal("x@example.com", stored.Metadata["email"])
+}
+
+func (ms *ModelSuite) Test_DiffAudit() {
+	before := &Post{ID: uuid.Must(uuid.NewV4()), Title: "Before", Description: "Same"}
+	after := *before
+	after.Title = "After"
+
+	changes := DiffAudit(before, &after, "user")
+	ms.Len(changes, 1)
+	ms.Equal("Before", changes["title"].Before)
+	ms.Equal("After", changes["title"].After)
+
+	created := DiffAudit(nil, &User{Email: "new@example.com", Password: "secret"})
+	ms.Equal("new@example.com", created["email"].After)
+	_, leaked := created["password"]
+	ms.False(leaked)
+}
+
+func (ms *ModelSuite) Test_ExportAuditEvents() {
+	actorID := uuid.Must(uuid.NewV4())
+	ms.NoError(RecordAuditEvent(DB, &AuditEvent{Action: AuditPostCreated, ActorID: nulls.NewUUID(actorID), TargetType: "post"}))
+	ms.NoError(RecordAuditEvent(DB, &AuditEvent{Action: AuditPostDeleted, ActorID: nulls.NewUUID(actorID), TargetType: "post"}))
+	ms.NoError(RecordAuditEvent(DB, &AuditEvent{Action: AuditLoginFailed, TargetType: "user"}))
+
+	out := &bytes.Buffer{}
+	exported, err := ExportAuditEvents(DB, AuditFilter{ActorID: actorID.String()}, out)
+	ms.NoError(err)
+	ms.Equal(2, exported)
+	ms.Equal(2, strings.Count(out.String(), "\n"))
+	ms.Contains(out.String(), AuditPostDeleted)
+	ms.NotContains(out.String(), AuditLoginFailed)
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"blog/utils"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// Category is used by pop to map your categories database table to your go code.
+// Categories form a tree, a post sits in at most one of them.
+type Category struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	ParentID  nulls.UUID `json:"parent_id" db:"parent_id"`
+	Name      string     `json:"name" db:"name"`
+	Slug      string     `json:"slug" db:"slug"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	Children  Categories `json:"children,omitempty" db:"-"`
+}
+
+// String is not required by pop and may be deleted
+func (c Category) String() string {
+	jc, _ := json.Marshal(c)
+	return string(jc)
+}
+
+// Categories is not required by pop and may be deleted
+type Categories []Category
+
+// BeforeValidate - the slug always follows the name
+func (c *Category) BeforeValidate(tx *pop.Connection) error {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Slug = utils.Slugify(c.Name)
+
+	return nil
+}
+
+// BeforeSave - saves without validation get the slug as well
+func (c *Category) BeforeSave(tx *pop.Connection) error {
+	return c.BeforeValidate(tx)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (c *Category) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	verrs := validate.Validate(
+		&validators.StringLengthInRange{Field: c.Name, Name: "name", Min: 1, Max: 64},
+		&validators.StringIsPresent{Field: c.Slug, Name: "name", Message: "The name must contain a letter or a digit."},
+	)
+
+	if c.Slug != "" {
+		taken, err := tx.Where("slug = ? AND id != ?", c.Slug, c.ID).Exists(&Category{})
+		if err != nil {
+			return verrs, errors.WithStack(err)
+		}
+		if taken {
+			verrs.Add("name", "The category "+c.Name+" already exists.")
+		}
+	}
+
+	if c.ParentID.Valid {
+		// a category cannot move below itself, the tree would turn into a loop
+		descendants, err := CategoryDescendantIDs(tx, c.ID)
+		if err != nil {
+			return verrs, err
+		}
+		for _, id := range descendants {
+			if id == c.ParentID.UUID {
+				verrs.Add("parent_id", "A category cannot be placed below itself.")
+				return verrs, nil
+			}
+		}
+
+		exists, err := tx.Where("id = ?", c.ParentID.UUID).Exists(&Category{})
+		if err != nil {
+			return verrs, errors.WithStack(err)
+		}
+		if !exists {
+			verrs.Add("parent_id", "The parent category does not exist.")
+		}
+	}
+
+	return verrs, nil
+}
+
+// FindCategoryByIDOrSlug - find the category by its ID or its slug
+func FindCategoryByIDOrSlug(tx *pop.Connection, idOrSlug string) (*Category, error) {
+	category := &Category{}
+	if id, err := uuid.FromString(idOrSlug); err == nil {
+		return category, tx.Find(category, id)
+	}
+
+	return category, tx.Where("slug = ?", idOrSlug).First(category)
+}
+
+// CategoryDescendantIDs - the ID of the category followed by the IDs of every category below it
+func CategoryDescendantIDs(tx *pop.Connection, id uuid.UUID) ([]uuid.UUID, error) {
+	categories := Categories{}
+	if err := tx.Select("id", "parent_id").All(&categories); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	ids := []uuid.UUID{id}
+	for i := 0; i < len(ids); i++ {
+		for _, category := range categories {
+			if category.ParentID.Valid && category.ParentID.UUID == ids[i] {
+				ids = append(ids, category.ID)
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+// CategoryTree - nest the categories below their parents, the roots are returned in the order
+// of the list. Categories whose parent is missing from the list become roots.
+func CategoryTree(categories Categories) Categories {
+	children := map[uuid.UUID]Categories{}
+	known := map[uuid.UUID]bool{}
+	for _, category := range categories {
+		known[category.ID] = true
+	}
+	for _, category := range categories {
+		if category.ParentID.Valid && known[category.ParentID.UUID] {
+			children[category.ParentID.UUID] = append(children[category.ParentID.UUID], category)
+		}
+	}
+
+	var nest func(category Category) Category
+	nest = func(category Category) Category {
+		for _, child := range children[category.ID] {
+			category.Children = append(category.Children, nest(child))
+		}
+		return category
+	}
+
+	roots := Categories{}
+	for _, category := range categories {
+		if !category.ParentID.Valid || !known[category.ParentID.UUID] {
+			roots = append(roots, nest(category))
+		}
+	}
+
+	return roots
+}
+
+// DeleteCategory - remove the category, its subcategories and its posts move up to its parent
+func DeleteCategory(tx *pop.Connection, category *Category) error {
+	if err := tx.RawQuery("UPDATE categories SET parent_id = ? WHERE parent_id = ?", category.ParentID, category.ID).Exec(); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := tx.RawQuery("UPDATE posts SET category_id = ? WHERE category_id = ?", category.ParentID, category.ID).Exec(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(tx.Destroy(category))
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"github.com/gobuffalo/nulls"
+	"github.com/gofrs/uuid"
+)
+
+func (ms *ModelSuite) Test_Category_TreeAndDelete() {
+	news := &Category{Name: "News"}
+	verrs, err := DB.ValidateAndCreate(news)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.Equal("news", news.Slug)
+
+	local := &Category{Name: "Local", ParentID: nulls.NewUUID(news.ID)}
+	ms.NoError(DB.Create(local))
+	city := &Category{Name: "City", ParentID: nulls.NewUUID(local.ID)}
+	ms.NoError(DB.Create(city))
+
+	ids, err := CategoryDescendantIDs(DB, news.ID)
+	ms.NoError(err)
+	ms.ElementsMatch(ids, []uuid.UUID{news.ID, local.ID, city.ID})
+
+	// news cannot move below its own grandchild
+	news.ParentID = nulls.NewUUID(city.ID)
+	verrs, err = DB.ValidateAndUpdate(news)
+	ms.NoError(err)
+	ms.True(verrs.HasAny())
+	news.ParentID = nulls.UUID{}
+
+	categories := Categories{}
+	ms.NoError(DB.Order("name asc").All(&categories))
+	tree := CategoryTree(categories)
+	ms.Len(tree, 1)
+	ms.Equal("News", tree[0].Name)
+	ms.Equal("City", tree[0].Children[0].Children[0].Name)
+
+	user := &User{Email: "categorized@example.com", Password: "secret", Name: "Categorized"}
+	_, err = user.Create(DB)
+	ms.NoError(err)
+	post := &Post{Title: "Categorized", Description: "Body", UserID: user.ID, CategoryID: nulls.NewUUID(local.ID)}
+	ms.NoError(DB.Create(post))
+
+	// the children and posts of a deleted category move up to its parent
+	ms.NoError(DeleteCategory(DB, local))
+	ms.NoError(DB.Reload(city))
+	ms.Equal(news.ID, city.ParentID.UUID)
+	ms.NoError(DB.Reload(post))
+	ms.Equal(news.ID, post.CategoryID.UUID)
+}
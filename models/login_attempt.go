@@ -0,0 +1,81 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// LoginAttempt is used by pop to map your login_attempts database table to your go code.
+// Each row counts the recent failed log ins of an account or a client IP.
+type LoginAttempt struct {
+	ID           uuid.UUID  `json:"id" db:"id"`
+	AttemptKey   string     `json:"attempt_key" db:"attempt_key"`
+	Failures     int        `json:"failures" db:"failures"`
+	LastFailedAt nulls.Time `json:"last_failed_at" db:"last_failed_at"`
+	LockedUntil  nulls.Time `json:"locked_until" db:"locked_until"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (a LoginAttempt) String() string {
+	ja, _ := json.Marshal(a)
+	return string(ja)
+}
+
+// LoginAttempts is not required by pop and may be deleted
+type LoginAttempts []LoginAttempt
+
+// FindLoginAttempt - look up the counter of the key, an unknown key has no failures
+func FindLoginAttempt(tx *pop.Connection, key string) (*LoginAttempt, error) {
+	attempt := &LoginAttempt{AttemptKey: key}
+	err := tx.Where("attempt_key = ?", key).First(attempt)
+	if errors.Cause(err) == sql.ErrNoRows {
+		return attempt, nil
+	}
+
+	return attempt, err
+}
+
+// RecordLoginFailure - count a failed log in, failures older than the window start a new count
+func RecordLoginFailure(tx *pop.Connection, key string, now time.Time, window time.Duration) (*LoginAttempt, error) {
+	// concurrent failures must all be counted, so the counter is incremented by the database
+	err := tx.RawQuery(
+		`INSERT INTO login_attempts (id, attempt_key, failures, last_failed_at, created_at, updated_at) VALUES (?, ?, 1, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE failures = IF(last_failed_at IS NULL OR last_failed_at < ?, 1, failures + 1), last_failed_at = VALUES(last_failed_at), updated_at = VALUES(updated_at)`,
+		uuid.Must(uuid.NewV4()), key, now, now, now, now.Add(-window),
+	).Exec()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return FindLoginAttempt(tx, key)
+}
+
+// LockLoginAttempt - refuse log ins of the key until the given time
+func LockLoginAttempt(tx *pop.Connection, key string, until time.Time) error {
+	return tx.RawQuery(
+		"UPDATE login_attempts SET locked_until = ?, updated_at = ? WHERE attempt_key = ?",
+		until, time.Now(), key,
+	).Exec()
+}
+
+// ResetLoginAttempt - forget the failures and the lockout of the key
+func ResetLoginAttempt(tx *pop.Connection, key string) error {
+	return tx.RawQuery("DELETE FROM login_attempts WHERE attempt_key = ?", key).Exec()
+}
+
+// PruneLoginAttempts - remove the counters whose failures are older than the window and whose
+// lockout is over, they would start from zero anyway
+func PruneLoginAttempts(tx *pop.Connection, now time.Time, window time.Duration) (int, error) {
+	return tx.RawQuery(
+		"DELETE FROM login_attempts WHERE (last_failed_at IS NULL OR last_failed_at < ?) AND (locked_until IS NULL OR locked_until < ?)",
+		now.Add(-window), now,
+	).ExecWithCount()
+}
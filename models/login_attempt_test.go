@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gobuffalo/nulls"
+)
+
+func (ms *ModelSuite) Test_LoginAttempt_RecordFailure() {
+	now := time.Now().Truncate(time.Second)
+
+	attempt, err := RecordLoginFailure(DB, "account:attempt@example.com", now, time.Minute)
+	ms.NoError(err)
+	ms.Equal(1, attempt.Failures)
+
+	attempt, err = RecordLoginFailure(DB, "account:attempt@example.com", now.Add(time.Second), time.Minute)
+	ms.NoError(err)
+	ms.Equal(2, attempt.Failures)
+
+	// failures outside of the window start a new count
+	attempt, err = RecordLoginFailure(DB, "account:attempt@example.com", now.Add(2*time.Minute), time.Minute)
+	ms.NoError(err)
+	ms.Equal(1, attempt.Failures)
+
+	ms.NoError(ResetLoginAttempt(DB, "account:attempt@example.com"))
+	attempt, err = FindLoginAttempt(DB, "account:attempt@example.com")
+	ms.NoError(err)
+	ms.Equal(0, attempt.Failures)
+}
+
+func (ms *ModelSuite) Test_LoginAttempt_Prune() {
+	now := time.Now().Truncate(time.Second)
+
+	stale := &LoginAttempt{AttemptKey: "ip:10.0.0.1", Failures: 2, LastFailedAt: nulls.NewTime(now.Add(-time.Hour))}
+	recent := &LoginAttempt{AttemptKey: "ip:10.0.0.2", Failures: 1, LastFailedAt: nulls.NewTime(now.Add(-time.Second))}
+	locked := &LoginAttempt{AttemptKey: "ip:10.0.0.3", Failures: 5, LastFailedAt: nulls.NewTime(now.Add(-time.Hour)), LockedUntil: nulls.NewTime(now.Add(time.Minute))}
+	for _, attempt := range []*LoginAttempt{stale, recent, locked} {
+		ms.NoError(DB.Create(attempt))
+	}
+
+	pruned, err := PruneLoginAttempts(DB, now, 15*time.Minute)
+	ms.NoError(err)
+	ms.Equal(1, pruned)
+
+	attempt, err := FindLoginAttempt(DB, "ip:10.0.0.1")
+	ms.NoError(err)
+	ms.Equal(0, attempt.Failures)
+}
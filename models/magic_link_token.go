@@ -0,0 +1,93 @@
+package models
+
+import (
+	"blog/utils"
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// ErrMagicLinkTokenInvalid - the magic link is unknown, expired, already used or was sent to another email
+var ErrMagicLinkTokenInvalid = errors.New("magic link token is invalid or expired")
+
+// MagicLinkToken is used by pop to map your magic_link_tokens database table to your go code.
+// The token is bound to the email it was sent to, changing the email invalidates it.
+type MagicLinkToken struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"-" db:"user_id"`
+	Email     string     `json:"email" db:"email"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    nulls.Time `json:"used_at" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (t MagicLinkToken) String() string {
+	jt, _ := json.Marshal(t)
+	return string(jt)
+}
+
+// MagicLinkTokens is not required by pop and may be deleted
+type MagicLinkTokens []MagicLinkToken
+
+// IssueMagicLinkToken - invalidate pending magic links of the user and create one for the current email
+func IssueMagicLinkToken(tx *pop.Connection, user *User, ttl time.Duration) (string, error) {
+	now := time.Now()
+	if err := tx.RawQuery(
+		"UPDATE magic_link_tokens SET used_at = ?, updated_at = ? WHERE user_id = ? AND used_at IS NULL",
+		now, now, user.ID,
+	).Exec(); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	plainToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	magicLinkToken := &MagicLinkToken{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: utils.HashToken(plainToken),
+		ExpiresAt: now.Add(ttl),
+	}
+
+	return plainToken, tx.Create(magicLinkToken)
+}
+
+// ConsumeMagicLinkToken - mark the magic link as used and return its user, a link can only be
+// redeemed once and only while the user still has the email it was sent to
+func ConsumeMagicLinkToken(tx *pop.Connection, plainToken string) (*User, error) {
+	magicLinkToken := &MagicLinkToken{}
+	if err := tx.Where("token_hash = ?", utils.HashToken(plainToken)).First(magicLinkToken); err != nil {
+		return nil, ErrMagicLinkTokenInvalid
+	}
+
+	now := time.Now()
+	consumed, err := tx.RawQuery(
+		"UPDATE magic_link_tokens SET used_at = ?, updated_at = ? WHERE id = ? AND used_at IS NULL AND expires_at > ?",
+		now, now, magicLinkToken.ID, now,
+	).ExecWithCount()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if consumed == 0 {
+		return nil, ErrMagicLinkTokenInvalid
+	}
+
+	user := &User{}
+	if err := tx.Find(user, magicLinkToken.UserID); err != nil {
+		return nil, ErrMagicLinkTokenInvalid
+	}
+	if user.Email != magicLinkToken.Email {
+		return nil, ErrMagicLinkTokenInvalid
+	}
+
+	return user, nil
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+func (ms *ModelSuite) Test_MagicLinkToken_SingleUse() {
+	user := &User{Email: "magic@example.com", Password: "secret", Name: "Magic"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+
+	plainToken, err := IssueMagicLinkToken(DB, user, time.Minute)
+	ms.NoError(err)
+
+	redeemed, err := ConsumeMagicLinkToken(DB, plainToken)
+	ms.NoError(err)
+	ms.Equal(user.ID, redeemed.ID)
+
+	_, err = ConsumeMagicLinkToken(DB, plainToken)
+	ms.Equal(ErrMagicLinkTokenInvalid, err)
+}
+
+func (ms *ModelSuite) Test_MagicLinkToken_BoundToEmail() {
+	user := &User{Email: "magic-bound@example.com", Password: "secret", Name: "Magic"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+
+	plainToken, err := IssueMagicLinkToken(DB, user, time.Minute)
+	ms.NoError(err)
+
+	user.ChangeEmail("magic-changed@example.com")
+	ms.NoError(DB.UpdateColumns(user, "email"))
+
+	_, err = ConsumeMagicLinkToken(DB, plainToken)
+	ms.Equal(ErrMagicLinkTokenInvalid, err)
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"blog/utils"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// recoveryCodeCount - number of recovery codes handed out when 2FA is enabled
+const recoveryCodeCount = 10
+
+// MFARecoveryCode is used by pop to map your mfa_recovery_codes database table to your go code.
+type MFARecoveryCode struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"-" db:"user_id"`
+	CodeHash  string     `json:"-" db:"code_hash"`
+	UsedAt    nulls.Time `json:"used_at" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (c MFARecoveryCode) String() string {
+	jc, _ := json.Marshal(c)
+	return string(jc)
+}
+
+// TableName overrides the table name pop derives from the struct name
+func (c MFARecoveryCode) TableName() string {
+	return "mfa_recovery_codes"
+}
+
+// MFARecoveryCodes is not required by pop and may be deleted
+type MFARecoveryCodes []MFARecoveryCode
+
+// normalizeRecoveryCode - recovery codes are typed by hand, ignore case, spaces and dashes
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.Replace(code, "-", "", -1)
+	return strings.Replace(code, " ", "", -1)
+}
+
+// GenerateRecoveryCodes - replace the recovery codes of the user, the plain codes are only returned once
+func GenerateRecoveryCodes(tx *pop.Connection, userID uuid.UUID) ([]string, error) {
+	if err := DeleteRecoveryCodes(tx, userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buffer := make([]byte, 5)
+		if _, err := rand.Read(buffer); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(buffer))
+		code = fmt.Sprintf("%s-%s", code[:4], code[4:])
+
+		recoveryCode := &MFARecoveryCode{
+			UserID:   userID,
+			CodeHash: utils.HashToken(normalizeRecoveryCode(code)),
+		}
+		if err := tx.Create(recoveryCode); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// ConsumeRecoveryCode - use up one recovery code of the user, false when it is unknown or already used
+func ConsumeRecoveryCode(tx *pop.Connection, userID uuid.UUID, code string) (bool, error) {
+	now := time.Now()
+	consumed, err := tx.RawQuery(
+		"UPDATE mfa_recovery_codes SET used_at = ?, updated_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL",
+		now, now, userID, utils.HashToken(normalizeRecoveryCode(code)),
+	).ExecWithCount()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return consumed == 1, nil
+}
+
+// DeleteRecoveryCodes - remove every recovery code of the user
+func DeleteRecoveryCodes(tx *pop.Connection, userID uuid.UUID) error {
+	return tx.RawQuery("DELETE FROM mfa_recovery_codes WHERE user_id = ?", userID).Exec()
+}
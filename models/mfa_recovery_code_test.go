@@ -0,0 +1,22 @@
+package models
+
+import "strings"
+
+func (ms *ModelSuite) Test_MFARecoveryCode_SingleUse() {
+	user := &User{Email: "recovery@example.com", Password: "secret", Name: "Recovery"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+
+	codes, err := GenerateRecoveryCodes(DB, user.ID)
+	ms.NoError(err)
+	ms.Len(codes, recoveryCodeCount)
+
+	// codes are typed by hand, case and dashes do not matter
+	consumed, err := ConsumeRecoveryCode(DB, user.ID, strings.ToUpper(strings.Replace(codes[0], "-", "", -1)))
+	ms.NoError(err)
+	ms.True(consumed)
+
+	consumed, err = ConsumeRecoveryCode(DB, user.ID, codes[0])
+	ms.NoError(err)
+	ms.False(consumed)
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"blog/utils"
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// ErrOAuthCodeInvalid - the authorization code is unknown, expired or was issued to another client
+var ErrOAuthCodeInvalid = errors.New("authorization code is invalid or expired")
+
+// ErrOAuthCodeReplayed - the authorization code was already exchanged
+var ErrOAuthCodeReplayed = errors.New("authorization code has already been used")
+
+// OAuthAuthorizationCode is used by pop to map your oauth_authorization_codes database table to your go code.
+// The code remembers what the user consented to until the client exchanges it for tokens,
+// the refresh token family it was exchanged for is kept so a replayed code can revoke it.
+type OAuthAuthorizationCode struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	OAuthClientID uuid.UUID  `json:"-" db:"oauth_client_id"`
+	UserID        uuid.UUID  `json:"-" db:"user_id"`
+	CodeHash      string     `json:"-" db:"code_hash"`
+	RedirectURI   string     `json:"redirect_uri" db:"redirect_uri"`
+	Scopes        string     `json:"scopes" db:"scopes"`
+	CodeChallenge string     `json:"-" db:"code_challenge"`
+	FamilyID      nulls.UUID `json:"-" db:"family_id"`
+	ExpiresAt     time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt        nulls.Time `json:"used_at" db:"used_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// TableName overrides the table name pop derives from the struct name
+func (a OAuthAuthorizationCode) TableName() string {
+	return "oauth_authorization_codes"
+}
+
+// String is not required by pop and may be deleted
+func (a OAuthAuthorizationCode) String() string {
+	ja, _ := json.Marshal(a)
+	return string(ja)
+}
+
+// ScopeList - The scopes the user consented to
+func (a *OAuthAuthorizationCode) ScopeList() []string {
+	return utils.ParseScopes(a.Scopes)
+}
+
+// IssueOAuthAuthorizationCode - record the consent of the user and return the plain code for the redirect
+func IssueOAuthAuthorizationCode(tx *pop.Connection, client *OAuthClient, userID uuid.UUID, redirectURI string, scopes []string, codeChallenge string, ttl time.Duration) (string, error) {
+	plainCode, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	authorizationCode := &OAuthAuthorizationCode{
+		OAuthClientID: client.ID,
+		UserID:        userID,
+		CodeHash:      utils.HashToken(plainCode),
+		RedirectURI:   redirectURI,
+		Scopes:        utils.FormatScopes(scopes),
+		CodeChallenge: codeChallenge,
+		ExpiresAt:     time.Now().Add(ttl),
+	}
+
+	return plainCode, errors.WithStack(tx.Create(authorizationCode))
+}
+
+// ConsumeOAuthAuthorizationCode - mark the code of the client as used, a code is only exchanged once.
+// A second exchange returns ErrOAuthCodeReplayed together with the code.
+func ConsumeOAuthAuthorizationCode(tx *pop.Connection, client *OAuthClient, plainCode string) (*OAuthAuthorizationCode, error) {
+	authorizationCode := &OAuthAuthorizationCode{}
+	if err := tx.Where("code_hash = ? AND oauth_client_id = ?", utils.HashToken(plainCode), client.ID).First(authorizationCode); err != nil {
+		return nil, ErrOAuthCodeInvalid
+	}
+	if authorizationCode.UsedAt.Valid {
+		return authorizationCode, ErrOAuthCodeReplayed
+	}
+
+	now := time.Now()
+	consumed, err := tx.RawQuery(
+		"UPDATE oauth_authorization_codes SET used_at = ?, updated_at = ? WHERE id = ? AND used_at IS NULL AND expires_at > ?",
+		now, now, authorizationCode.ID, now,
+	).ExecWithCount()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if consumed == 0 {
+		return nil, ErrOAuthCodeInvalid
+	}
+	authorizationCode.UsedAt = nulls.NewTime(now)
+
+	return authorizationCode, nil
+}
+
+// RecordFamily - remember the refresh token family the code was exchanged for
+func (a *OAuthAuthorizationCode) RecordFamily(tx *pop.Connection, familyID uuid.UUID) error {
+	a.FamilyID = nulls.NewUUID(familyID)
+
+	return tx.UpdateColumns(a, "family_id", "updated_at")
+}
@@ -0,0 +1,190 @@
+package models
+
+import (
+	"blog/utils"
+	"crypto/subtle"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// OAuthClientIDPrefix - marks the public identifier of a third party client
+const OAuthClientIDPrefix = "blog_client_"
+
+// ErrOAuthClientInvalid - the client is unknown, revoked or failed to authenticate
+var ErrOAuthClientInvalid = errors.New("OAuth client is invalid")
+
+// OAuthClient is used by pop to map your oauth_clients database table to your go code.
+// A client is a third party app registered by a user, confidential clients authenticate
+// with a secret while public clients (browser or native apps) rely on PKCE alone.
+type OAuthClient struct {
+	ID               uuid.UUID  `json:"id" db:"id"`
+	UserID           uuid.UUID  `json:"-" db:"user_id"`
+	Name             string     `json:"name" db:"name"`
+	ClientID         string     `json:"client_id" db:"client_id"`
+	ClientSecretHash string     `json:"-" db:"client_secret_hash"`
+	RedirectURIs     string     `json:"redirect_uris" db:"redirect_uris"`
+	Scopes           string     `json:"scopes" db:"scopes"`
+	Confidential     bool       `json:"confidential" db:"confidential"`
+	RevokedAt        nulls.Time `json:"revoked_at" db:"revoked_at"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// TableName overrides the table name pop derives from the struct name
+func (c OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// String is not required by pop and may be deleted
+func (c OAuthClient) String() string {
+	jc, _ := json.Marshal(c)
+	return string(jc)
+}
+
+// OAuthClients is not required by pop and may be deleted
+type OAuthClients []OAuthClient
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (c *OAuthClient) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	verrs := validate.Validate(
+		&validators.StringLengthInRange{Field: c.Name, Name: "name", Min: 1, Max: 255},
+		&validators.StringIsPresent{Field: c.RedirectURIs, Name: "redirect_uris", Message: "At least one redirect URI is required."},
+		&validators.StringIsPresent{Field: c.Scopes, Name: "scopes", Message: "At least one scope is required."},
+	)
+	if err := utils.ValidateDelegableScopes(c.ScopeList()); err != nil {
+		verrs.Add("scopes", err.Error())
+	}
+	for _, redirectURI := range c.RedirectURIList() {
+		if !isValidRedirectURI(redirectURI) {
+			verrs.Add("redirect_uris", redirectURI+" must be an absolute https URL without fragment, http is only allowed for localhost.")
+		}
+	}
+
+	return verrs, nil
+}
+
+// isValidRedirectURI - RFC 6749 3.1.2, absolute and without fragment, plain http only for native apps on loopback
+func isValidRedirectURI(redirectURI string) bool {
+	parsed, err := url.Parse(redirectURI)
+	if err != nil || !parsed.IsAbs() || parsed.Fragment != "" || parsed.Host == "" {
+		return false
+	}
+
+	switch parsed.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := parsed.Hostname()
+		return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	}
+
+	return false
+}
+
+// RedirectURIList - The registered redirect URIs
+func (c *OAuthClient) RedirectURIList() []string {
+	return strings.Fields(c.RedirectURIs)
+}
+
+// AllowsRedirectURI - the redirect URI was registered, compared as an exact string
+func (c *OAuthClient) AllowsRedirectURI(redirectURI string) bool {
+	for _, registered := range c.RedirectURIList() {
+		if registered == redirectURI {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ScopeList - The scopes the client may ask for
+func (c *OAuthClient) ScopeList() []string {
+	return utils.ParseScopes(c.Scopes)
+}
+
+// AllowsScopes - every requested scope was registered for the client
+func (c *OAuthClient) AllowsScopes(scopes []string) bool {
+	for _, scope := range scopes {
+		if !utils.HasScope(c.ScopeList(), scope) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsActive - the client has not been revoked
+func (c *OAuthClient) IsActive() bool {
+	return !c.RevokedAt.Valid
+}
+
+// CheckSecret - the secret of a confidential client matches, public clients have none
+func (c *OAuthClient) CheckSecret(secret string) bool {
+	if !c.Confidential || secret == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(utils.HashToken(secret)), []byte(c.ClientSecretHash)) == 1
+}
+
+// Revoke - disable the client and end every session it was granted
+func (c *OAuthClient) Revoke(tx *pop.Connection) error {
+	now := time.Now()
+	c.RevokedAt = nulls.NewTime(now)
+	if err := tx.UpdateColumns(c, "revoked_at", "updated_at"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := tx.RawQuery(
+		"UPDATE refresh_tokens SET revoked_at = ?, updated_at = ? WHERE revoked_at IS NULL AND family_id IN (SELECT family_id FROM user_sessions WHERE oauth_client_id = ?)",
+		now, now, c.ID,
+	).Exec(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(tx.RawQuery(
+		"UPDATE user_sessions SET revoked_at = ?, updated_at = ? WHERE oauth_client_id = ? AND revoked_at IS NULL",
+		now, now, c.ID,
+	).Exec())
+}
+
+// CreateOAuthClient - validate and store a new client, the plain secret of a confidential
+// client is only returned here
+func CreateOAuthClient(tx *pop.Connection, client *OAuthClient) (string, *validate.Errors, error) {
+	publicID, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", nil, errors.WithStack(err)
+	}
+	client.ClientID = OAuthClientIDPrefix + publicID[:24]
+
+	plainSecret := ""
+	if client.Confidential {
+		if plainSecret, err = utils.GenerateOpaqueToken(); err != nil {
+			return "", nil, errors.WithStack(err)
+		}
+		client.ClientSecretHash = utils.HashToken(plainSecret)
+	}
+
+	verrs, err := tx.ValidateAndCreate(client)
+
+	return plainSecret, verrs, err
+}
+
+// FindOAuthClient - look up an active client by its public identifier
+func FindOAuthClient(tx *pop.Connection, clientID string) (*OAuthClient, error) {
+	client := &OAuthClient{}
+	if err := tx.Where("client_id = ? AND revoked_at IS NULL", clientID).First(client); err != nil {
+		return nil, ErrOAuthClientInvalid
+	}
+
+	return client, nil
+}
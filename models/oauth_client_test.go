@@ -0,0 +1,54 @@
+package models
+
+import (
+	"blog/utils"
+	"time"
+)
+
+func (ms *ModelSuite) Test_OAuthClient_Validate() {
+	owner := &User{Email: "oauth-client@example.com", Password: "secret", Name: "Owner"}
+	_, err := owner.Create(DB)
+	ms.NoError(err)
+
+	client := &OAuthClient{
+		UserID:       owner.ID,
+		Name:         "Partner",
+		RedirectURIs: "http://partner.example.com/callback https://partner.example.com/#fragment",
+		Scopes:       utils.ScopeAccountAdmin,
+	}
+	_, verrs, err := CreateOAuthClient(DB, client)
+	ms.NoError(err)
+	ms.True(verrs.HasAny())
+	ms.Len(verrs.Get("redirect_uris"), 2)
+	ms.Len(verrs.Get("scopes"), 1)
+
+	client.RedirectURIs = "https://partner.example.com/callback http://127.0.0.1:8080/callback"
+	client.Scopes = utils.ScopePostsRead
+	client.Confidential = true
+	secret, verrs, err := CreateOAuthClient(DB, client)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.True(client.CheckSecret(secret))
+	ms.False(client.CheckSecret("wrong"))
+	ms.True(client.AllowsRedirectURI("http://127.0.0.1:8080/callback"))
+	ms.False(client.AllowsRedirectURI("https://partner.example.com/callback/other"))
+}
+
+func (ms *ModelSuite) Test_OAuthAuthorizationCode_SingleUse() {
+	owner := &User{Email: "oauth-code@example.com", Password: "secret", Name: "Owner"}
+	_, err := owner.Create(DB)
+	ms.NoError(err)
+	client := &OAuthClient{UserID: owner.ID, Name: "Partner", RedirectURIs: "https://partner.example.com/cb", Scopes: utils.ScopePostsRead}
+	_, _, err = CreateOAuthClient(DB, client)
+	ms.NoError(err)
+
+	plainCode, err := IssueOAuthAuthorizationCode(DB, client, owner.ID, "https://partner.example.com/cb", client.ScopeList(), "challenge", time.Minute)
+	ms.NoError(err)
+
+	authorizationCode, err := ConsumeOAuthAuthorizationCode(DB, client, plainCode)
+	ms.NoError(err)
+	ms.Equal(owner.ID, authorizationCode.UserID)
+
+	_, err = ConsumeOAuthAuthorizationCode(DB, client, plainCode)
+	ms.Equal(ErrOAuthCodeReplayed, err)
+}
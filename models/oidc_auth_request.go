@@ -0,0 +1,75 @@
+package models
+
+import (
+	"blog/utils"
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// ErrOIDCAuthRequestInvalid - the state is unknown, expired, already used or belongs to another provider
+var ErrOIDCAuthRequestInvalid = errors.New("OpenID Connect state is invalid or expired")
+
+// OIDCAuthRequest is used by pop to map your oidc_auth_requests database table to your go code.
+// It keeps the nonce and PKCE verifier of a log in started at a provider until its callback.
+type OIDCAuthRequest struct {
+	ID           uuid.UUID `json:"id" db:"id"`
+	StateHash    string    `json:"-" db:"state_hash"`
+	Provider     string    `json:"provider" db:"provider"`
+	Nonce        string    `json:"-" db:"nonce"`
+	CodeVerifier string    `json:"-" db:"code_verifier"`
+	ExpiresAt    time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// TableName overrides the table name pop derives from the struct name
+func (a OIDCAuthRequest) TableName() string {
+	return "oidc_auth_requests"
+}
+
+// String is not required by pop and may be deleted
+func (a OIDCAuthRequest) String() string {
+	ja, _ := json.Marshal(a)
+	return string(ja)
+}
+
+// CreateOIDCAuthRequest - remember the nonce and code verifier of a new log in, the returned
+// plain state is only sent to the provider
+func CreateOIDCAuthRequest(tx *pop.Connection, provider string, nonce string, codeVerifier string, ttl time.Duration) (string, error) {
+	state, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	request := &OIDCAuthRequest{
+		StateHash:    utils.HashToken(state),
+		Provider:     provider,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(ttl),
+	}
+
+	return state, errors.WithStack(tx.Create(request))
+}
+
+// ConsumeOIDCAuthRequest - look the log in up by its state and delete it, a state is only accepted once
+func ConsumeOIDCAuthRequest(tx *pop.Connection, provider string, state string) (*OIDCAuthRequest, error) {
+	request := &OIDCAuthRequest{}
+	if err := tx.Where("state_hash = ?", utils.HashToken(state)).First(request); err != nil {
+		return nil, ErrOIDCAuthRequestInvalid
+	}
+
+	deleted, err := tx.RawQuery("DELETE FROM oidc_auth_requests WHERE id = ?", request.ID).ExecWithCount()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if deleted == 0 || request.Provider != provider || !request.ExpiresAt.After(time.Now()) {
+		return nil, ErrOIDCAuthRequestInvalid
+	}
+
+	return request, nil
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"blog/utils"
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// ErrPasswordResetTokenInvalid - the reset token is unknown, expired or already used
+var ErrPasswordResetTokenInvalid = errors.New("password reset token is invalid or expired")
+
+// PasswordResetToken is used by pop to map your password_reset_tokens database table to your go code.
+type PasswordResetToken struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"-" db:"user_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    nulls.Time `json:"used_at" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (t PasswordResetToken) String() string {
+	jt, _ := json.Marshal(t)
+	return string(jt)
+}
+
+// PasswordResetTokens is not required by pop and may be deleted
+type PasswordResetTokens []PasswordResetToken
+
+// IssuePasswordResetToken - invalidate pending reset tokens of the user and create a new one
+func IssuePasswordResetToken(tx *pop.Connection, userID uuid.UUID, ttl time.Duration) (string, error) {
+	now := time.Now()
+	if err := tx.RawQuery(
+		"UPDATE password_reset_tokens SET used_at = ?, updated_at = ? WHERE user_id = ? AND used_at IS NULL",
+		now, now, userID,
+	).Exec(); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	plainToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	resetToken := &PasswordResetToken{
+		UserID:    userID,
+		TokenHash: utils.HashToken(plainToken),
+		ExpiresAt: now.Add(ttl),
+	}
+
+	return plainToken, tx.Create(resetToken)
+}
+
+// ConsumePasswordResetToken - mark the reset token as used and return it, a token can only be consumed once
+func ConsumePasswordResetToken(tx *pop.Connection, plainToken string) (*PasswordResetToken, error) {
+	resetToken := &PasswordResetToken{}
+	if err := tx.Where("token_hash = ?", utils.HashToken(plainToken)).First(resetToken); err != nil {
+		return nil, ErrPasswordResetTokenInvalid
+	}
+
+	now := time.Now()
+	consumed, err := tx.RawQuery(
+		"UPDATE password_reset_tokens SET used_at = ?, updated_at = ? WHERE id = ? AND used_at IS NULL AND expires_at > ?",
+		now, now, resetToken.ID, now,
+	).ExecWithCount()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if consumed == 0 {
+		return nil, ErrPasswordResetTokenInvalid
+	}
+	resetToken.UsedAt = nulls.NewTime(now)
+
+	return resetToken, nil
+}
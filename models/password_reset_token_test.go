@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+func (ms *ModelSuite) Test_PasswordResetToken_SingleUse() {
+	user := &User{Email: "reset@example.com", Password: "secret", Name: "Reset"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+
+	plainToken, err := IssuePasswordResetToken(DB, user.ID, time.Hour)
+	ms.NoError(err)
+
+	resetToken, err := ConsumePasswordResetToken(DB, plainToken)
+	ms.NoError(err)
+	ms.Equal(user.ID, resetToken.UserID)
+
+	_, err = ConsumePasswordResetToken(DB, plainToken)
+	ms.Equal(ErrPasswordResetTokenInvalid, err)
+}
+
+func (ms *ModelSuite) Test_PasswordResetToken_Expired() {
+	user := &User{Email: "expired@example.com", Password: "secret", Name: "Expired"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+
+	plainToken, err := IssuePasswordResetToken(DB, user.ID, -time.Minute)
+	ms.NoError(err)
+
+	_, err = ConsumePasswordResetToken(DB, plainToken)
+	ms.Equal(ErrPasswordResetTokenInvalid, err)
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"blog/utils"
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// PersonalAccessTokenPrefix - marks a bearer token as a personal access token rather than a JWT
+const PersonalAccessTokenPrefix = "blog_pat_"
+
+// personalAccessTokenTouchInterval - last_used_at is only written once per interval
+const personalAccessTokenTouchInterval = time.Minute
+
+// PersonalAccessToken is used by pop to map your personal_access_tokens database table to your go code.
+type PersonalAccessToken struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	UserID      uuid.UUID  `json:"-" db:"user_id"`
+	Name        string     `json:"name" db:"name"`
+	TokenPrefix string     `json:"token_prefix" db:"token_prefix"`
+	TokenHash   string     `json:"-" db:"token_hash"`
+	Scopes      string     `json:"scopes" db:"scopes"`
+	ExpiresAt   nulls.Time `json:"expires_at" db:"expires_at"`
+	LastUsedAt  nulls.Time `json:"last_used_at" db:"last_used_at"`
+	RevokedAt   nulls.Time `json:"revoked_at" db:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (t PersonalAccessToken) String() string {
+	jt, _ := json.Marshal(t)
+	return string(jt)
+}
+
+// PersonalAccessTokens is not required by pop and may be deleted
+type PersonalAccessTokens []PersonalAccessToken
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (t *PersonalAccessToken) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	verrs := validate.Validate(
+		&validators.StringLengthInRange{Field: t.Name, Name: "name", Min: 1, Max: 255},
+		&validators.StringIsPresent{Field: t.Scopes, Name: "scopes", Message: "At least one scope is required."},
+	)
+	if err := utils.ValidateScopes(t.ScopeList()); err != nil {
+		verrs.Add("scopes", err.Error())
+	}
+
+	return verrs, nil
+}
+
+// ScopeList - The scopes granted to the token
+func (t *PersonalAccessToken) ScopeList() []string {
+	return utils.ParseScopes(t.Scopes)
+}
+
+// IsActive - the token is neither revoked nor expired
+func (t *PersonalAccessToken) IsActive(now time.Time) bool {
+	if t.RevokedAt.Valid {
+		return false
+	}
+
+	return !t.ExpiresAt.Valid || now.Before(t.ExpiresAt.Time)
+}
+
+// Touch - record the usage of the token, throttled so every request does not write
+func (t *PersonalAccessToken) Touch(tx *pop.Connection, now time.Time) error {
+	if t.LastUsedAt.Valid && now.Sub(t.LastUsedAt.Time) < personalAccessTokenTouchInterval {
+		return nil
+	}
+	t.LastUsedAt = nulls.NewTime(now)
+
+	return tx.UpdateColumns(t, "last_used_at")
+}
+
+// Revoke - the token can no longer be used
+func (t *PersonalAccessToken) Revoke(tx *pop.Connection) error {
+	t.RevokedAt = nulls.NewTime(time.Now())
+
+	return tx.UpdateColumns(t, "revoked_at", "updated_at")
+}
+
+// RevokeUserPersonalAccessTokens - revoke every personal access token of the user
+func RevokeUserPersonalAccessTokens(tx *pop.Connection, userID uuid.UUID) error {
+	now := time.Now()
+
+	return tx.RawQuery(
+		"UPDATE personal_access_tokens SET revoked_at = ?, updated_at = ? WHERE user_id = ? AND revoked_at IS NULL",
+		now, now, userID,
+	).Exec()
+}
+
+// CreatePersonalAccessToken - validate and store a new token, the plain token is only returned here
+func CreatePersonalAccessToken(tx *pop.Connection, accessToken *PersonalAccessToken) (string, *validate.Errors, error) {
+	secret, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", nil, errors.WithStack(err)
+	}
+	plainToken := PersonalAccessTokenPrefix + secret
+
+	accessToken.TokenHash = utils.HashToken(plainToken)
+	accessToken.TokenPrefix = plainToken[:len(PersonalAccessTokenPrefix)+6]
+
+	verrs, err := tx.ValidateAndCreate(accessToken)
+
+	return plainToken, verrs, err
+}
+
+// FindPersonalAccessToken - look up a personal access token by its plain value
+func FindPersonalAccessToken(tx *pop.Connection, plainToken string) (*PersonalAccessToken, error) {
+	accessToken := &PersonalAccessToken{}
+	err := tx.Where("token_hash = ?", utils.HashToken(plainToken)).First(accessToken)
+
+	return accessToken, err
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+)
+
+func (ms *ModelSuite) Test_PersonalAccessToken_Create() {
+	user := &User{Email: "pat@example.com", Password: "secret", Name: "PAT"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+
+	accessToken := &PersonalAccessToken{UserID: user.ID, Name: "ci", Scopes: "posts:read"}
+	plainToken, verrs, err := CreatePersonalAccessToken(DB, accessToken)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.True(strings.HasPrefix(plainToken, PersonalAccessTokenPrefix))
+	ms.NotEqual(plainToken, accessToken.TokenHash)
+
+	found, err := FindPersonalAccessToken(DB, plainToken)
+	ms.NoError(err)
+	ms.Equal(accessToken.ID, found.ID)
+	ms.True(found.IsActive(time.Now()))
+
+	ms.NoError(found.Revoke(DB))
+	ms.False(found.IsActive(time.Now()))
+}
+
+func (ms *ModelSuite) Test_PersonalAccessToken_IsActive() {
+	now := time.Now()
+	expired := &PersonalAccessToken{ExpiresAt: nulls.NewTime(now.Add(-time.Minute))}
+	ms.False(expired.IsActive(now))
+
+	forever := &PersonalAccessToken{}
+	ms.True(forever.IsActive(now))
+}
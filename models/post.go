@@ -2,24 +2,46 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 
+	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v5"
 	"github.com/gobuffalo/validate/v3"
 	"github.com/gobuffalo/validate/v3/validators"
 	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
 )
 
+const (
+	// PostStatusDraft - only the author and the editors see the post
+	PostStatusDraft = "draft"
+	// PostStatusScheduled - published by the scheduler once PublishedAt has passed
+	PostStatusScheduled = "scheduled"
+	// PostStatusPublished - everyone sees the post
+	PostStatusPublished = "published"
+	// PostStatusArchived - taken down after being published, hidden like a draft
+	PostStatusArchived = "archived"
+)
+
+// PostStatuses - every status of the publishing workflow
+var PostStatuses = []string{PostStatusDraft, PostStatusScheduled, PostStatusPublished, PostStatusArchived}
+
 // Post is used by pop to map your posts database table to your go code.
 type Post struct {
-	ID          uuid.UUID `json:"id" db:"id"`
-	Title       string    `json:"title" db:"title" form:"title"`
-	Description string    `json:"description" db:"description" form:"description"`
-	PublishedAt time.Time `json:"published_at" db:"published_at"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
-	UserID      uuid.UUID `json:"-" db:"user_id"`
-	User        *User     `json:"user" belongs_to:"user"`
+	ID          uuid.UUID  `json:"id" db:"id"`
+	Title       string     `json:"title" db:"title" form:"title"`
+	Slug        string     `json:"slug" db:"slug"`
+	Description string     `json:"description" db:"description" form:"description"`
+	Status      string     `json:"status" db:"status"`
+	PublishedAt nulls.Time `json:"published_at" db:"published_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt   nulls.Time `json:"deleted_at" db:"deleted_at"`
+	CategoryID  nulls.UUID `json:"category_id" db:"category_id" form:"category_id"`
+	UserID      uuid.UUID  `json:"-" db:"user_id"`
+	User        *User      `json:"user" belongs_to:"user"`
+	Tags        Tags       `json:"tags" many_to_many:"post_tags" order_by:"name asc"`
 }
 
 // String is not required by pop and may be deleted
@@ -40,7 +62,30 @@ func (p Posts) String() string {
 // Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
 // This method is not required and may be deleted.
 func (p *Post) Validate(tx *pop.Connection) (*validate.Errors, error) {
-	return validate.NewErrors(), nil
+	verrs := validate.Validate(
+		&validators.StringInclusion{Field: p.Status, Name: "status", List: PostStatuses, Message: fmt.Sprintf("The status must be one of %v", PostStatuses)},
+	)
+	if p.Slug != "" {
+		// the former permalinks of a post keep pointing at it
+		retired, err := tx.Where("slug = ? AND post_id <> ?", p.Slug, p.ID).Exists(&PostSlugRedirect{})
+		if err != nil {
+			return verrs, errors.WithStack(err)
+		}
+		if retired {
+			verrs.Add("slug", "The slug is a former permalink of another post.")
+		}
+	}
+	if p.CategoryID.Valid {
+		exists, err := tx.Where("id = ?", p.CategoryID.UUID).Exists(&Category{})
+		if err != nil {
+			return verrs, errors.WithStack(err)
+		}
+		if !exists {
+			verrs.Add("category_id", "The category does not exist.")
+		}
+	}
+
+	return verrs, nil
 }
 
 // ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
@@ -58,3 +103,171 @@ func (p *Post) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
 		&validators.StringLengthInRange{Field: "Title", Name: "title", Min: 3, Max: 255},
 	), nil
 }
+
+// BeforeValidate - new posts start as drafts
+func (p *Post) BeforeValidate(tx *pop.Connection) error {
+	if p.Status == "" {
+		p.Status = PostStatusDraft
+	}
+
+	return nil
+}
+
+// BeforeCreate - posts created without validation start as drafts as well, every post gets
+// a unique slug from its title
+func (p *Post) BeforeCreate(tx *pop.Connection) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.Must(uuid.NewV4())
+	}
+	if p.Slug == "" {
+		slug, err := uniquePostSlug(tx, p.Title, p.ID)
+		if err != nil {
+			return err
+		}
+		p.Slug = slug
+	}
+
+	return p.BeforeValidate(tx)
+}
+
+// SyncSlug - give the post a new slug when its title no longer matches the current one, the
+// former slug redirects to the post from then on. Call it before saving a changed title.
+func (p *Post) SyncSlug(tx *pop.Connection) error {
+	if slugMatchesTitle(p.Slug, p.Title) {
+		return nil
+	}
+
+	slug, err := uniquePostSlug(tx, p.Title, p.ID)
+	if err != nil {
+		return err
+	}
+	if p.Slug != "" {
+		if err := tx.Create(&PostSlugRedirect{PostID: p.ID, Slug: p.Slug}); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	// a title changed back takes its former slug back
+	if err := tx.RawQuery("DELETE FROM post_slug_redirects WHERE post_id = ? AND slug = ?", p.ID, slug).Exec(); err != nil {
+		return errors.WithStack(err)
+	}
+	p.Slug = slug
+
+	return nil
+}
+
+// FindPostByIDOrSlug - scope the query and find the post by its ID or its current slug
+func FindPostByIDOrSlug(q *pop.Query, post *Post, idOrSlug string) error {
+	if id, err := uuid.FromString(idOrSlug); err == nil {
+		return q.Find(post, id)
+	}
+
+	return q.Where("slug = ?", idOrSlug).First(post)
+}
+
+// IsPublished - the post is visible to everyone
+func (p *Post) IsPublished() bool {
+	return p.Status == PostStatusPublished
+}
+
+// Publish - publish the post at the given time, a time after now schedules it instead
+func (p *Post) Publish(tx *pop.Connection, at time.Time, now time.Time) error {
+	p.Status = PostStatusPublished
+	if at.After(now) {
+		p.Status = PostStatusScheduled
+	}
+	p.PublishedAt = nulls.NewTime(at)
+
+	return tx.UpdateColumns(p, "status", "published_at", "updated_at")
+}
+
+// Unpublish - take the post back to a draft, or archive it when it should stay retired
+func (p *Post) Unpublish(tx *pop.Connection, status string) error {
+	p.Status = status
+	if status == PostStatusDraft {
+		p.PublishedAt = nulls.Time{}
+	}
+
+	return tx.UpdateColumns(p, "status", "published_at", "updated_at")
+}
+
+// IsDeleted - the post is in the trash
+func (p *Post) IsDeleted() bool {
+	return p.DeletedAt.Valid
+}
+
+// SoftDelete - move the post to the trash, it stays restorable until it is purged
+func (p *Post) SoftDelete(tx *pop.Connection, now time.Time) error {
+	p.DeletedAt = nulls.NewTime(now)
+
+	return tx.UpdateColumns(p, "deleted_at", "updated_at")
+}
+
+// Restore - take the post out of the trash
+func (p *Post) Restore(tx *pop.Connection) error {
+	p.DeletedAt = nulls.Time{}
+
+	return tx.UpdateColumns(p, "deleted_at", "updated_at")
+}
+
+// PostsNotDeleted - scope every post query that must not see the trash
+func PostsNotDeleted(q *pop.Query) *pop.Query {
+	return q.Where("deleted_at IS NULL")
+}
+
+// PostsTagged - scope a post query to the posts carrying the tag with the slug
+func PostsTagged(slug string) pop.ScopeFunc {
+	return func(q *pop.Query) *pop.Query {
+		return q.Where("id IN (SELECT post_tags.post_id FROM post_tags JOIN tags ON tags.id = post_tags.tag_id WHERE tags.slug = ?)", slug)
+	}
+}
+
+// PostsInCategories - scope a post query to the posts in one of the categories
+func PostsInCategories(ids []uuid.UUID) pop.ScopeFunc {
+	return func(q *pop.Query) *pop.Query {
+		args := make([]interface{}, 0, len(ids))
+		for _, id := range ids {
+			args = append(args, id)
+		}
+		return q.Where("category_id IN (?)", args...)
+	}
+}
+
+// PostsInTrash - scope a post query to the trash
+func PostsInTrash(q *pop.Query) *pop.Query {
+	return q.Where("deleted_at IS NOT NULL")
+}
+
+// PurgeDeletedPosts - remove the posts that went to the trash before the cutoff for good,
+// their revisions go with them. Returns how many posts were purged.
+func PurgeDeletedPosts(tx *pop.Connection, cutoff time.Time) (int, error) {
+	return tx.RawQuery("DELETE FROM posts WHERE deleted_at IS NOT NULL AND deleted_at < ?", cutoff).ExecWithCount()
+}
+
+// PublishDuePosts - publish the scheduled posts whose time has come, returns how many were published
+func PublishDuePosts(tx *pop.Connection, now time.Time) (int, error) {
+	due := Posts{}
+	// posts in the trash wait for their restore
+	if err := tx.Scope(PostsNotDeleted).Where("status = ? AND published_at <= ?", PostStatusScheduled, now).All(&due); err != nil {
+		return 0, err
+	}
+
+	for i := range due {
+		before := due[i]
+		due[i].Status = PostStatusPublished
+		if err := tx.UpdateColumns(&due[i], "status", "updated_at"); err != nil {
+			return i, err
+		}
+		// the scheduler has no actor, the event is recorded without one
+		if err := RecordAuditEvent(tx, &AuditEvent{
+			Action:     AuditPostUpdated,
+			TargetType: "post",
+			TargetID:   due[i].ID.String(),
+			Changes:    DiffAudit(&before, &due[i], "user"),
+			Metadata:   AuditMetadata{"reason": "scheduled_publish"},
+		}); err != nil {
+			return i, err
+		}
+	}
+
+	return len(due), nil
+}
@@ -0,0 +1,160 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// ErrPostRevisionImmutable - revisions are never changed once written, only pruned
+var ErrPostRevisionImmutable = errors.New("post revisions are immutable")
+
+// PostRevision is used by pop to map your post_revisions database table to your go code.
+// A revision is a snapshot of the content of a post after a save, numbered from 1 per post.
+type PostRevision struct {
+	ID           uuid.UUID  `json:"id" db:"id"`
+	PostID       uuid.UUID  `json:"post_id" db:"post_id"`
+	UserID       nulls.UUID `json:"user_id" db:"user_id"`
+	Number       int        `json:"number" db:"number"`
+	Title        string     `json:"title" db:"title"`
+	Description  string     `json:"description" db:"description"`
+	RestoredFrom nulls.Int  `json:"restored_from" db:"restored_from"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+}
+
+// String is not required by pop and may be deleted
+func (r PostRevision) String() string {
+	jr, _ := json.Marshal(r)
+	return string(jr)
+}
+
+// PostRevisions is not required by pop and may be deleted
+type PostRevisions []PostRevision
+
+// BeforeUpdate refuses to change a recorded revision
+func (r *PostRevision) BeforeUpdate(tx *pop.Connection) error {
+	return ErrPostRevisionImmutable
+}
+
+// RecordPostRevision - snapshot the content of the post as its next revision, editorID is the
+// user who saved it
+func RecordPostRevision(tx *pop.Connection, post *Post, editorID nulls.UUID) (*PostRevision, error) {
+	return recordPostRevision(tx, post, editorID, nulls.Int{})
+}
+
+// RestorePostRevision - put the content of the revision back into the post, the restore is
+// recorded as a new revision pointing at the restored one
+func RestorePostRevision(tx *pop.Connection, post *Post, revision *PostRevision, editorID nulls.UUID) (*PostRevision, error) {
+	if revision.PostID != post.ID {
+		return nil, errors.New("the revision belongs to another post")
+	}
+
+	post.Title, post.Description = revision.Title, revision.Description
+	if err := post.SyncSlug(tx); err != nil {
+		return nil, err
+	}
+	if err := SavePostWithUniqueSlug(tx, post, func() error {
+		return tx.UpdateColumns(post, "title", "slug", "description", "updated_at")
+	}); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return recordPostRevision(tx, post, editorID, nulls.NewInt(revision.Number))
+}
+
+func recordPostRevision(tx *pop.Connection, post *Post, editorID nulls.UUID, restoredFrom nulls.Int) (*PostRevision, error) {
+	latest, err := latestPostRevisionNumber(tx, post.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	revision := &PostRevision{
+		PostID:       post.ID,
+		UserID:       editorID,
+		Number:       latest + 1,
+		Title:        post.Title,
+		Description:  post.Description,
+		RestoredFrom: restoredFrom,
+	}
+
+	return revision, errors.WithStack(tx.Create(revision))
+}
+
+// EnsureInitialPostRevision - snapshot a post written before revisions existed, so its first
+// edit can still be undone. Posts that already have revisions are left alone.
+func EnsureInitialPostRevision(tx *pop.Connection, post *Post) error {
+	latest, err := latestPostRevisionNumber(tx, post.ID)
+	if err != nil || latest > 0 {
+		return err
+	}
+
+	_, err = RecordPostRevision(tx, post, nulls.NewUUID(post.UserID))
+	return err
+}
+
+// FindPostRevision - the revision of the post with the given number
+func FindPostRevision(tx *pop.Connection, postID uuid.UUID, number int) (*PostRevision, error) {
+	revision := &PostRevision{}
+	if err := tx.Where("post_id = ? AND number = ?", postID, number).First(revision); err != nil {
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+// PrunePostRevisions - apply the retention policy: keep the newest keep revisions of every post
+// (0 keeps them all) and drop the revisions created before cutoff (zero keeps them all). The
+// newest revision of a post always survives. Returns how many revisions were removed.
+func PrunePostRevisions(tx *pop.Connection, keep int, cutoff time.Time) (int, error) {
+	if keep <= 0 && cutoff.IsZero() {
+		return 0, nil
+	}
+
+	type latestRevision struct {
+		PostID uuid.UUID `db:"post_id"`
+		Number int       `db:"number"`
+	}
+	latest := []latestRevision{}
+	if err := tx.RawQuery("SELECT post_id, MAX(number) AS number FROM post_revisions GROUP BY post_id").All(&latest); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	pruned := 0
+	for _, post := range latest {
+		condition, args := "created_at < ?", []interface{}{post.PostID, post.Number, cutoff}
+		switch {
+		case keep > 0 && !cutoff.IsZero():
+			condition, args = "number <= ? OR created_at < ?", append(args[:2], post.Number-keep, cutoff)
+		case keep > 0:
+			condition, args = "number <= ?", append(args[:2], post.Number-keep)
+		}
+
+		deleted, err := tx.RawQuery("DELETE FROM post_revisions WHERE post_id = ? AND number < ? AND ("+condition+")", args...).ExecWithCount()
+		if err != nil {
+			return pruned, errors.WithStack(err)
+		}
+		pruned += deleted
+	}
+
+	return pruned, nil
+}
+
+func latestPostRevisionNumber(tx *pop.Connection, postID uuid.UUID) (int, error) {
+	latest := struct {
+		Number int `db:"number"`
+	}{}
+	query := "SELECT COALESCE(MAX(number), 0) AS number FROM post_revisions WHERE post_id = ?"
+	// concurrent saves of the post would read the same number, the locking read sees the
+	// committed revisions and holds the next save off until this transaction ends. SQLite
+	// serializes its writers on its own.
+	if tx.Dialect.Name() == "mysql" {
+		query += " FOR UPDATE"
+	}
+	err := tx.RawQuery(query, postID).First(&latest)
+
+	return latest.Number, errors.WithStack(err)
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gobuffalo/nulls"
+)
+
+func (ms *ModelSuite) revisedPost(revisions int) *Post {
+	user := &User{Email: "revisions@example.com", Password: "secret", Name: "Revisions"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+
+	post := &Post{Title: "Revised", Description: "v1", UserID: user.ID}
+	ms.NoError(DB.Create(post))
+	for i := 1; i <= revisions; i++ {
+		post.Description = "v" + string(rune('0'+i))
+		_, err := RecordPostRevision(DB, post, nulls.NewUUID(user.ID))
+		ms.NoError(err)
+	}
+
+	return post
+}
+
+func (ms *ModelSuite) Test_PostRevision_NumberingAndRestore() {
+	post := ms.revisedPost(3)
+
+	first, err := FindPostRevision(DB, post.ID, 1)
+	ms.NoError(err)
+	ms.Equal("v1", first.Description)
+
+	first.Title = "Changed"
+	ms.Error(DB.Update(first))
+
+	restored, err := RestorePostRevision(DB, post, first, nulls.UUID{})
+	ms.NoError(err)
+	ms.Equal(4, restored.Number)
+	ms.Equal(1, restored.RestoredFrom.Int)
+
+	stored := &Post{}
+	ms.NoError(DB.Find(stored, post.ID))
+	ms.Equal("v1", stored.Description)
+
+	// a post saved before revisions existed gets its snapshot once
+	ms.NoError(EnsureInitialPostRevision(DB, post))
+	count, err := DB.Where("post_id = ?", post.ID).Count(&PostRevision{})
+	ms.NoError(err)
+	ms.Equal(4, count)
+}
+
+func (ms *ModelSuite) Test_PrunePostRevisions() {
+	post := ms.revisedPost(5)
+
+	pruned, err := PrunePostRevisions(DB, 2, time.Time{})
+	ms.NoError(err)
+	ms.Equal(3, pruned)
+
+	revisions := PostRevisions{}
+	ms.NoError(DB.Where("post_id = ?", post.ID).Order("number asc").All(&revisions))
+	ms.Len(revisions, 2)
+	ms.Equal(4, revisions[0].Number)
+
+	// the newest revision survives any age
+	pruned, err = PrunePostRevisions(DB, 0, time.Now().Add(time.Hour))
+	ms.NoError(err)
+	ms.Equal(1, pruned)
+	count, err := DB.Where("post_id = ?", post.ID).Count(&PostRevision{})
+	ms.NoError(err)
+	ms.Equal(1, count)
+}
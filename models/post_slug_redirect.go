@@ -0,0 +1,168 @@
+package models
+
+import (
+	"blog/utils"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// reservedPostSlugs - path segments of the post routes a slug must not shadow
+var reservedPostSlugs = map[string]bool{"create": true, "trash": true}
+
+const (
+	// postSlugAttempts - how often a post is saved before a slug taken concurrently is given up on
+	postSlugAttempts = 3
+	// postSlugRandomSuffixes - the range of the suffix a post moves to after losing its slug
+	postSlugRandomSuffixes = 1000000
+)
+
+// PostSlugRedirect is used by pop to map your post_slug_redirects database table to your go code.
+// A former slug of a post, kept so that shared links keep working after a title change.
+type PostSlugRedirect struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	PostID    uuid.UUID `json:"post_id" db:"post_id"`
+	Slug      string    `json:"slug" db:"slug"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// String is not required by pop and may be deleted
+func (r PostSlugRedirect) String() string {
+	jr, _ := json.Marshal(r)
+	return string(jr)
+}
+
+// PostSlugRedirects is not required by pop and may be deleted
+type PostSlugRedirects []PostSlugRedirect
+
+// FindPostSlugRedirect - the redirect of a former slug
+func FindPostSlugRedirect(tx *pop.Connection, slug string) (*PostSlugRedirect, error) {
+	redirect := &PostSlugRedirect{}
+	if err := tx.Where("slug = ?", slug).First(redirect); err != nil {
+		return nil, err
+	}
+
+	return redirect, nil
+}
+
+// BackfillPostSlugs - replace the placeholder slugs of the posts written before slugs existed,
+// which the migration set to their ID. Returns how many posts got a slug.
+func BackfillPostSlugs(tx *pop.Connection) (int, error) {
+	posts := Posts{}
+	if err := tx.Where("slug = id").All(&posts); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	for i := range posts {
+		slug, err := uniquePostSlug(tx, posts[i].Title, posts[i].ID)
+		if err != nil {
+			return i, err
+		}
+		posts[i].Slug = slug
+		if err := tx.UpdateColumns(&posts[i], "slug"); err != nil {
+			return i, errors.WithStack(err)
+		}
+	}
+
+	return len(posts), nil
+}
+
+// uniquePostSlug - the slug of the title, suffixed with -2, -3... while another post uses it
+// now or used it before. Titles without any transliterable character fall back to "post".
+func uniquePostSlug(tx *pop.Connection, title string, postID uuid.UUID) (string, error) {
+	return postSlugFrom(tx, title, postID, 1)
+}
+
+// SavePostWithUniqueSlug - run the save of the post, when a concurrent save took the slug first
+// the post moves to a free slug with a random suffix and the save runs again. uniquePostSlug
+// can not see the posts saved by transactions that are still open.
+func SavePostWithUniqueSlug(tx *pop.Connection, post *Post, save func() error) error {
+	err := save()
+	for attempt := 1; attempt < postSlugAttempts && isPostSlugConflict(err); attempt++ {
+		suffix, randErr := rand.Int(rand.Reader, big.NewInt(postSlugRandomSuffixes))
+		if randErr != nil {
+			return errors.WithStack(randErr)
+		}
+		slug, slugErr := postSlugFrom(tx, post.Title, post.ID, 2+int(suffix.Int64()))
+		if slugErr != nil {
+			return slugErr
+		}
+		post.Slug = slug
+		err = save()
+	}
+
+	return err
+}
+
+// isPostSlugConflict - the error comes from the unique index on the slug of the posts, MySQL
+// names the index and SQLite the column
+func isPostSlugConflict(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := errors.Cause(err).Error()
+
+	return strings.Contains(message, "posts_slug_idx") || strings.Contains(message, "posts.slug")
+}
+
+// postSlugFrom - the first free slug of the title, trying the collision suffixes from the given one
+func postSlugFrom(tx *pop.Connection, title string, postID uuid.UUID, firstSuffix int) (string, error) {
+	base := postSlugBase(title)
+
+	for suffix := firstSuffix; ; suffix++ {
+		candidate := base
+		if suffix > 1 {
+			candidate = fmt.Sprintf("%s-%d", base, suffix)
+		}
+		// a slug that parses as a UUID would be looked up as an ID
+		if _, err := uuid.FromString(candidate); err == nil || reservedPostSlugs[candidate] {
+			continue
+		}
+
+		taken, err := tx.Where("slug = ? AND id <> ?", candidate, postID).Exists(&Post{})
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		if !taken {
+			taken, err = tx.Where("slug = ? AND post_id <> ?", candidate, postID).Exists(&PostSlugRedirect{})
+			if err != nil {
+				return "", errors.WithStack(err)
+			}
+		}
+		if !taken {
+			return candidate, nil
+		}
+	}
+}
+
+// slugMatchesTitle - the slug was built from the title, with or without a collision suffix
+func slugMatchesTitle(slug string, title string) bool {
+	base := postSlugBase(title)
+	if slug == base {
+		return true
+	}
+
+	if !strings.HasPrefix(slug, base+"-") {
+		return false
+	}
+	suffix, err := strconv.Atoi(strings.TrimPrefix(slug, base+"-"))
+
+	return err == nil && suffix > 1
+}
+
+// postSlugBase - the slug of the title before any collision suffix
+func postSlugBase(title string) string {
+	if base := utils.Slugify(title); base != "" {
+		return base
+	}
+
+	return "post"
+}
@@ -0,0 +1,69 @@
+package models
+
+func (ms *ModelSuite) Test_Post_SlugCollisionsAndRedirects() {
+	user := &User{Email: "slugs@example.com", Password: "secret", Name: "Slugs"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+
+	first := &Post{Title: "Crème Brûlée", Description: "Body", UserID: user.ID}
+	ms.NoError(DB.Create(first))
+	ms.Equal("creme-brulee", first.Slug)
+
+	second := &Post{Title: "Creme brulee!", Description: "Body", UserID: user.ID}
+	ms.NoError(DB.Create(second))
+	ms.Equal("creme-brulee-2", second.Slug)
+
+	reserved := &Post{Title: "Trash", Description: "Body", UserID: user.ID}
+	ms.NoError(DB.Create(reserved))
+	ms.Equal("trash-2", reserved.Slug)
+
+	// a new title moves the post, the former slug redirects and is never handed out again
+	first.Title = "Tarte Tatin"
+	ms.NoError(first.SyncSlug(DB))
+	ms.NoError(DB.Update(first))
+	ms.Equal("tarte-tatin", first.Slug)
+
+	redirect, err := FindPostSlugRedirect(DB, "creme-brulee")
+	ms.NoError(err)
+	ms.Equal(first.ID, redirect.PostID)
+
+	third := &Post{Title: "Crème brûlée", Description: "Body", UserID: user.ID}
+	ms.NoError(DB.Create(third))
+	ms.Equal("creme-brulee-3", third.Slug)
+
+	// the title changed back takes its former slug back
+	first.Title = "Crème Brûlée"
+	ms.NoError(first.SyncSlug(DB))
+	ms.Equal("creme-brulee", first.Slug)
+	_, err = FindPostSlugRedirect(DB, "creme-brulee")
+	ms.Error(err)
+
+	found := &Post{}
+	ms.NoError(FindPostByIDOrSlug(DB.Q(), found, "creme-brulee-2"))
+	ms.Equal(second.ID, found.ID)
+}
+
+func (ms *ModelSuite) Test_Post_SlugTakenConcurrently() {
+	user := &User{Email: "slug-race@example.com", Password: "secret", Name: "Slug Race"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+
+	first := &Post{Title: "Race", Description: "Body", UserID: user.ID}
+	ms.NoError(DB.Create(first))
+
+	// the slug picked before a concurrent save committed the same one
+	second := &Post{Title: "Race", Description: "Body", UserID: user.ID, Slug: first.Slug}
+	ms.NoError(SavePostWithUniqueSlug(DB, second, func() error { return DB.Create(second) }))
+	ms.NotEqual(first.Slug, second.Slug)
+	ms.True(slugMatchesTitle(second.Slug, second.Title))
+
+	// the former permalink of another post can not be taken
+	first.Title = "Race Over"
+	ms.NoError(first.SyncSlug(DB))
+	ms.NoError(DB.Update(first))
+	second.Slug = "race"
+	verrs, err := DB.ValidateAndUpdate(second)
+	ms.NoError(err)
+	ms.True(verrs.HasAny())
+	ms.NotEmpty(verrs.Get("slug"))
+}
@@ -1,5 +1,77 @@
 package models
 
+import "time"
+
 func (ms *ModelSuite) Test_Post() {
 	ms.Fail("This test needs to be implemented!")
 }
+
+func (ms *ModelSuite) Test_Post_PublishingWorkflow() {
+	user := &User{Email: "workflow@example.com", Password: "secret", Name: "Workflow"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+
+	now := time.Date(2021, 5, 10, 9, 0, 0, 0, time.UTC)
+	post := &Post{Title: "Scheduled", Description: "Body", UserID: user.ID}
+	verrs, err := DB.ValidateAndCreate(post)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.Equal(PostStatusDraft, post.Status)
+
+	ms.NoError(post.Publish(DB, now.Add(time.Hour), now))
+	ms.Equal(PostStatusScheduled, post.Status)
+
+	published, err := PublishDuePosts(DB, now.Add(time.Minute))
+	ms.NoError(err)
+	ms.Equal(0, published)
+
+	published, err = PublishDuePosts(DB, now.Add(time.Hour))
+	ms.NoError(err)
+	ms.Equal(1, published)
+
+	event := &AuditEvent{}
+	ms.NoError(DB.Where("action = ? AND target_id = ?", AuditPostUpdated, post.ID.String()).First(event))
+	ms.False(event.ActorID.Valid)
+	ms.Equal("scheduled_publish", event.Metadata["reason"])
+	ms.Equal(PostStatusPublished, event.Changes["status"].After)
+
+	stored := &Post{}
+	ms.NoError(DB.Find(stored, post.ID))
+	ms.True(stored.IsPublished())
+
+	ms.NoError(stored.Unpublish(DB, PostStatusDraft))
+	ms.NoError(DB.Find(stored, post.ID))
+	ms.Equal(PostStatusDraft, stored.Status)
+	ms.False(stored.PublishedAt.Valid)
+}
+
+func (ms *ModelSuite) Test_Post_TrashAndPurge() {
+	user := &User{Email: "trash@example.com", Password: "secret", Name: "Trash"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+
+	now := time.Date(2021, 5, 20, 9, 0, 0, 0, time.UTC)
+	post := &Post{Title: "Trashed", Description: "Body", UserID: user.ID}
+	ms.NoError(DB.Create(post))
+	ms.NoError(post.Publish(DB, now.Add(-time.Hour), now.Add(-2*time.Hour)))
+	ms.NoError(post.SoftDelete(DB, now))
+
+	count, err := DB.Scope(PostsNotDeleted).Where("id = ?", post.ID).Count(&Post{})
+	ms.NoError(err)
+	ms.Equal(0, count)
+
+	// scheduled posts in the trash are not published
+	published, err := PublishDuePosts(DB, now)
+	ms.NoError(err)
+	ms.Equal(0, published)
+
+	purged, err := PurgeDeletedPosts(DB, now)
+	ms.NoError(err)
+	ms.Equal(0, purged)
+
+	ms.NoError(post.Restore(DB))
+	ms.NoError(post.SoftDelete(DB, now.AddDate(0, 0, -31)))
+	purged, err = PurgeDeletedPosts(DB, now.AddDate(0, 0, -30))
+	ms.NoError(err)
+	ms.Equal(1, purged)
+}
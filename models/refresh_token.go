@@ -0,0 +1,129 @@
+package models
+
+import (
+	"blog/utils"
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// ErrRefreshTokenReplayed - the refresh token was already exchanged or revoked
+var ErrRefreshTokenReplayed = errors.New("refresh token has already been used")
+
+// RefreshToken is used by pop to map your refresh_tokens database table to your go code.
+// Only the hash of the opaque token is persisted, every rotation creates a new row
+// within the same family so a replayed token can revoke all of its descendants.
+type RefreshToken struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"-" db:"user_id"`
+	FamilyID  uuid.UUID  `json:"family_id" db:"family_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	RotatedAt nulls.Time `json:"rotated_at" db:"rotated_at"`
+	RevokedAt nulls.Time `json:"revoked_at" db:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (t RefreshToken) String() string {
+	jt, _ := json.Marshal(t)
+	return string(jt)
+}
+
+// RefreshTokens is not required by pop and may be deleted
+type RefreshTokens []RefreshToken
+
+// IsActive - the token has not been rotated, revoked or expired
+func (t *RefreshToken) IsActive() bool {
+	return !t.RotatedAt.Valid && !t.RevokedAt.Valid && time.Now().Before(t.ExpiresAt)
+}
+
+// IsReplayed - the token was already exchanged or revoked, presenting it again means it leaked
+func (t *RefreshToken) IsReplayed() bool {
+	return t.RotatedAt.Valid || t.RevokedAt.Valid
+}
+
+// IssueRefreshToken - create a refresh token within the given family and return its plain value
+func IssueRefreshToken(tx *pop.Connection, userID uuid.UUID, familyID uuid.UUID) (string, *RefreshToken, error) {
+	plainToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", nil, errors.WithStack(err)
+	}
+
+	refreshToken := &RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(plainToken),
+		ExpiresAt: time.Now().Add(utils.RefreshTokenTTL()),
+	}
+
+	if err := tx.Create(refreshToken); err != nil {
+		return "", nil, errors.WithStack(err)
+	}
+
+	return plainToken, refreshToken, nil
+}
+
+// FindRefreshToken - look up a refresh token by its plain value
+func FindRefreshToken(tx *pop.Connection, plainToken string) (*RefreshToken, error) {
+	refreshToken := &RefreshToken{}
+	err := tx.Where("token_hash = ?", utils.HashToken(plainToken)).First(refreshToken)
+
+	return refreshToken, err
+}
+
+// Rotate - mark the token as exchanged and issue its successor in the same family
+func (t *RefreshToken) Rotate(tx *pop.Connection) (string, *RefreshToken, error) {
+	now := time.Now()
+	// only one concurrent request may win the exchange, the loser is treated as a replay
+	rotated, err := tx.RawQuery(
+		"UPDATE refresh_tokens SET rotated_at = ?, updated_at = ? WHERE id = ? AND rotated_at IS NULL AND revoked_at IS NULL",
+		now, now, t.ID,
+	).ExecWithCount()
+	if err != nil {
+		return "", nil, errors.WithStack(err)
+	}
+	if rotated == 0 {
+		return "", nil, ErrRefreshTokenReplayed
+	}
+	t.RotatedAt = nulls.NewTime(now)
+
+	return IssueRefreshToken(tx, t.UserID, t.FamilyID)
+}
+
+// RevokeRefreshTokenFamily - revoke every token descending from the same log in and end its session
+func RevokeRefreshTokenFamily(tx *pop.Connection, familyID uuid.UUID) error {
+	now := time.Now()
+	if err := tx.RawQuery(
+		"UPDATE refresh_tokens SET revoked_at = ?, updated_at = ? WHERE family_id = ? AND revoked_at IS NULL",
+		now, now, familyID,
+	).Exec(); err != nil {
+		return err
+	}
+
+	return tx.RawQuery(
+		"UPDATE user_sessions SET revoked_at = ?, updated_at = ? WHERE family_id = ? AND revoked_at IS NULL",
+		now, now, familyID,
+	).Exec()
+}
+
+// RevokeUserRefreshTokens - revoke every refresh token of the user and end all sessions
+func RevokeUserRefreshTokens(tx *pop.Connection, userID uuid.UUID) error {
+	now := time.Now()
+	if err := tx.RawQuery(
+		"UPDATE refresh_tokens SET revoked_at = ?, updated_at = ? WHERE user_id = ? AND revoked_at IS NULL",
+		now, now, userID,
+	).Exec(); err != nil {
+		return err
+	}
+
+	return tx.RawQuery(
+		"UPDATE user_sessions SET revoked_at = ?, updated_at = ? WHERE user_id = ? AND revoked_at IS NULL",
+		now, now, userID,
+	).Exec()
+}
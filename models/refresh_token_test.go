@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+func (ms *ModelSuite) Test_RefreshToken_Rotate() {
+	user := &User{Email: "rotate@example.com", Password: "secret", Name: "Rotate"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+
+	familyID := uuid.Must(uuid.NewV4())
+	plainToken, refreshToken, err := IssueRefreshToken(DB, user.ID, familyID)
+	ms.NoError(err)
+	ms.True(refreshToken.IsActive())
+
+	found, err := FindRefreshToken(DB, plainToken)
+	ms.NoError(err)
+	ms.Equal(refreshToken.ID, found.ID)
+
+	_, next, err := found.Rotate(DB)
+	ms.NoError(err)
+	ms.Equal(familyID, next.FamilyID)
+	ms.True(found.IsReplayed())
+
+	_, _, err = found.Rotate(DB)
+	ms.Equal(ErrRefreshTokenReplayed, err)
+}
+
+func (ms *ModelSuite) Test_RefreshToken_IsActive() {
+	expired := &RefreshToken{ExpiresAt: time.Now().Add(-time.Minute)}
+	ms.False(expired.IsActive())
+	ms.False(expired.IsReplayed())
+}
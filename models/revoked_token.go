@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// RevokedToken is used by pop to map your revoked_tokens database table to your go code.
+// An entry is only needed until the revoked access token would have expired on its own.
+type RevokedToken struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	JTI       string    `json:"jti" db:"jti"`
+	UserID    uuid.UUID `json:"-" db:"user_id"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (t RevokedToken) String() string {
+	jt, _ := json.Marshal(t)
+	return string(jt)
+}
+
+// RevokedTokens is not required by pop and may be deleted
+type RevokedTokens []RevokedToken
+
+// RevokeToken - add the token ID to the revocation list until the token expires
+func RevokeToken(tx *pop.Connection, jti string, userID uuid.UUID, expiresAt time.Time) error {
+	exists, err := tx.Where("jti = ?", jti).Exists(&RevokedToken{})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if exists {
+		return nil
+	}
+
+	return tx.Create(&RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// IsTokenRevoked - check whether the token ID is on the revocation list
+func IsTokenRevoked(tx *pop.Connection, jti string) (bool, error) {
+	return tx.Where("jti = ?", jti).Exists(&RevokedToken{})
+}
+
+// ClaimToken - revoke a single use token on its first use, false when it was used already.
+// The unique jti index settles concurrent uses of the same token.
+func ClaimToken(tx *pop.Connection, jti string, userID uuid.UUID, expiresAt time.Time) (bool, error) {
+	createErr := tx.Create(&RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	})
+	if createErr == nil {
+		return true, nil
+	}
+
+	used, err := IsTokenRevoked(tx, jti)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	if used {
+		return false, nil
+	}
+
+	return false, errors.WithStack(createErr)
+}
+
+// PruneRevokedTokens - remove entries of tokens that have expired by now
+func PruneRevokedTokens(tx *pop.Connection, now time.Time) (int, error) {
+	return tx.RawQuery("DELETE FROM revoked_tokens WHERE expires_at < ?", now).ExecWithCount()
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+func (ms *ModelSuite) Test_RevokedToken_Prune() {
+	user := &User{Email: "revoked@example.com", Password: "secret", Name: "Revoked"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+
+	expiredJTI := uuid.Must(uuid.NewV4()).String()
+	activeJTI := uuid.Must(uuid.NewV4()).String()
+	ms.NoError(RevokeToken(DB, expiredJTI, user.ID, time.Now().Add(-time.Hour)))
+	ms.NoError(RevokeToken(DB, activeJTI, user.ID, time.Now().Add(time.Hour)))
+
+	pruned, err := PruneRevokedTokens(DB, time.Now())
+	ms.NoError(err)
+	ms.Equal(1, pruned)
+
+	revoked, err := IsTokenRevoked(DB, activeJTI)
+	ms.NoError(err)
+	ms.True(revoked)
+
+	revoked, err = IsTokenRevoked(DB, expiredJTI)
+	ms.NoError(err)
+	ms.False(revoked)
+}
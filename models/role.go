@@ -0,0 +1,26 @@
+package models
+
+const (
+	// RoleAdmin - manages every post and user
+	RoleAdmin = "admin"
+	// RoleEditor - writes own posts and edits the posts of everyone
+	RoleEditor = "editor"
+	// RoleAuthor - writes own posts
+	RoleAuthor = "author"
+	// RoleReader - only reads posts
+	RoleReader = "reader"
+)
+
+// Roles - every role a user can be assigned
+var Roles = []string{RoleAdmin, RoleEditor, RoleAuthor, RoleReader}
+
+// IsValidRole - the role is one of Roles
+func IsValidRole(role string) bool {
+	for _, known := range Roles {
+		if known == role {
+			return true
+		}
+	}
+
+	return false
+}
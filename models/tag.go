@@ -0,0 +1,206 @@
+package models
+
+import (
+	"blog/utils"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// Tag is used by pop to map your tags database table to your go code.
+// Tags group posts freely, a post has any number of them through post_tags.
+type Tag struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Slug      string    `json:"slug" db:"slug"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// PostTag is used by pop to map your post_tags database table to your go code.
+type PostTag struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	PostID    uuid.UUID `json:"post_id" db:"post_id"`
+	TagID     uuid.UUID `json:"tag_id" db:"tag_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (t Tag) String() string {
+	jt, _ := json.Marshal(t)
+	return string(jt)
+}
+
+// UnmarshalJSON - a tag is given either as an object or by its name alone, so a post payload
+// may carry "tags": ["go", "web"]
+func (t *Tag) UnmarshalJSON(data []byte) error {
+	name := ""
+	if err := json.Unmarshal(data, &name); err == nil {
+		*t = Tag{Name: name}
+		return nil
+	}
+
+	type tag Tag
+	return json.Unmarshal(data, (*tag)(t))
+}
+
+// Tags is not required by pop and may be deleted
+type Tags []Tag
+
+// Names - the names of the tags in order
+func (t Tags) Names() []string {
+	names := make([]string, 0, len(t))
+	for _, tag := range t {
+		names = append(names, tag.Name)
+	}
+
+	return names
+}
+
+// BeforeValidate - the slug always follows the name
+func (t *Tag) BeforeValidate(tx *pop.Connection) error {
+	t.Name = strings.TrimSpace(t.Name)
+	t.Slug = utils.Slugify(t.Name)
+
+	return nil
+}
+
+// BeforeSave - saves without validation get the slug as well
+func (t *Tag) BeforeSave(tx *pop.Connection) error {
+	return t.BeforeValidate(tx)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (t *Tag) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	verrs := validate.Validate(
+		&validators.StringLengthInRange{Field: t.Name, Name: "name", Min: 1, Max: 64},
+		&validators.StringIsPresent{Field: t.Slug, Name: "name", Message: "The name must contain a letter or a digit."},
+	)
+	if t.Slug == "" {
+		return verrs, nil
+	}
+
+	taken, err := tx.Where("slug = ? AND id != ?", t.Slug, t.ID).Exists(&Tag{})
+	if err != nil {
+		return verrs, errors.WithStack(err)
+	}
+	if taken {
+		verrs.Add("name", "The tag "+t.Name+" already exists, merge the tags instead.")
+	}
+
+	return verrs, nil
+}
+
+// FindTagByIDOrSlug - find the tag by its ID or its slug
+func FindTagByIDOrSlug(tx *pop.Connection, idOrSlug string) (*Tag, error) {
+	tag := &Tag{}
+	if id, err := uuid.FromString(idOrSlug); err == nil {
+		return tag, tx.Find(tag, id)
+	}
+
+	return tag, tx.Where("slug = ?", idOrSlug).First(tag)
+}
+
+// ResolveTags - the tags of the names, missing ones are created. Names that share a slug
+// resolve to the same tag, the order of the first occurrence is kept.
+func ResolveTags(tx *pop.Connection, names []string) (Tags, *validate.Errors, error) {
+	tags := Tags{}
+	resolved := map[string]bool{}
+
+	for _, name := range names {
+		tag := &Tag{Name: name}
+		tag.BeforeValidate(tx)
+		if resolved[tag.Slug] {
+			continue
+		}
+
+		err := tx.Where("slug = ?", tag.Slug).First(tag)
+		if err != nil && errors.Cause(err) != sql.ErrNoRows {
+			return nil, nil, errors.WithStack(err)
+		}
+		if err != nil {
+			verrs, err := tx.ValidateAndCreate(tag)
+			if err != nil || verrs.HasAny() {
+				return nil, verrs, errors.WithStack(err)
+			}
+		}
+
+		resolved[tag.Slug] = true
+		tags = append(tags, *tag)
+	}
+
+	return tags, validate.NewErrors(), nil
+}
+
+// SetPostTags - replace the tags of the post
+func SetPostTags(tx *pop.Connection, post *Post, tags Tags) error {
+	if err := tx.RawQuery("DELETE FROM post_tags WHERE post_id = ?", post.ID).Exec(); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, tag := range tags {
+		if err := tx.Create(&PostTag{PostID: post.ID, TagID: tag.ID}); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	post.Tags = tags
+
+	return nil
+}
+
+// CountTagPosts - how many posts outside the trash carry each tag, tags without posts are absent.
+// With a reader only the published posts and the posts of the reader are counted, without one
+// every post is.
+func CountTagPosts(tx *pop.Connection, readerID nulls.UUID) (map[uuid.UUID]int, error) {
+	rows := []struct {
+		TagID uuid.UUID `db:"tag_id"`
+		Count int       `db:"count"`
+	}{}
+	query := "SELECT post_tags.tag_id, COUNT(*) AS count FROM post_tags " +
+		"JOIN posts ON posts.id = post_tags.post_id WHERE posts.deleted_at IS NULL"
+	args := []interface{}{}
+	if readerID.Valid {
+		query += " AND (posts.status = ? OR posts.user_id = ?)"
+		args = append(args, PostStatusPublished, readerID.UUID)
+	}
+	if err := tx.RawQuery(query+" GROUP BY post_tags.tag_id", args...).All(&rows); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	counts := map[uuid.UUID]int{}
+	for _, row := range rows {
+		counts[row.TagID] = row.Count
+	}
+
+	return counts, nil
+}
+
+// MergeTags - move every post of the source tag to the target tag and remove the source tag.
+// Run it inside a transaction, returns how many posts were moved.
+func MergeTags(tx *pop.Connection, source *Tag, target *Tag) (int, error) {
+	if source.ID == target.ID {
+		return 0, errors.New("a tag cannot be merged into itself")
+	}
+
+	// posts carrying both tags keep the one they already have on the target
+	if err := tx.RawQuery(
+		"DELETE FROM post_tags WHERE tag_id = ? AND post_id IN (SELECT post_id FROM (SELECT post_id FROM post_tags WHERE tag_id = ?) AS tagged)",
+		source.ID, target.ID,
+	).Exec(); err != nil {
+		return 0, errors.WithStack(err)
+	}
+	moved, err := tx.RawQuery("UPDATE post_tags SET tag_id = ?, updated_at = ? WHERE tag_id = ?", target.ID, time.Now(), source.ID).ExecWithCount()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return moved, errors.WithStack(tx.Destroy(source))
+}
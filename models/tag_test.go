@@ -0,0 +1,56 @@
+package models
+
+import (
+	"github.com/gobuffalo/nulls"
+	"github.com/gofrs/uuid"
+)
+
+func (ms *ModelSuite) Test_Tag_ResolveAndMerge() {
+	user := &User{Email: "tagger@example.com", Password: "secret", Name: "Tagger"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+
+	tags, verrs, err := ResolveTags(DB, []string{"Go", "golang", " go ", "Web"})
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.Equal([]string{"Go", "golang", "Web"}, tags.Names())
+
+	_, verrs, err = ResolveTags(DB, []string{"!!!"})
+	ms.NoError(err)
+	ms.True(verrs.HasAny())
+
+	first := &Post{Title: "First", Description: "Body", UserID: user.ID}
+	ms.NoError(DB.Create(first))
+	ms.NoError(SetPostTags(DB, first, Tags{tags[0], tags[1]}))
+	second := &Post{Title: "Second", Description: "Body", UserID: user.ID}
+	ms.NoError(DB.Create(second))
+	ms.NoError(SetPostTags(DB, second, Tags{tags[1], tags[2]}))
+
+	counts, err := CountTagPosts(DB, nulls.UUID{})
+	ms.NoError(err)
+	ms.Equal(1, counts[tags[0].ID])
+	ms.Equal(2, counts[tags[1].ID])
+
+	// both posts are drafts, another reader sees none of them
+	counts, err = CountTagPosts(DB, nulls.NewUUID(uuid.Must(uuid.NewV4())))
+	ms.NoError(err)
+	ms.Empty(counts)
+	counts, err = CountTagPosts(DB, nulls.NewUUID(user.ID))
+	ms.NoError(err)
+	ms.Equal(2, counts[tags[1].ID])
+
+	// the first post carries both tags, it keeps a single one
+	moved, err := MergeTags(DB, &tags[1], &tags[0])
+	ms.NoError(err)
+	ms.Equal(1, moved)
+
+	counts, err = CountTagPosts(DB, nulls.UUID{})
+	ms.NoError(err)
+	ms.Equal(2, counts[tags[0].ID])
+	_, err = FindTagByIDOrSlug(DB, "golang")
+	ms.Error(err)
+
+	loaded := &Post{}
+	ms.NoError(DB.Eager("Tags").Find(loaded, second.ID))
+	ms.Equal([]string{"Go", "Web"}, loaded.Tags.Names())
+}
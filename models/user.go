@@ -1,27 +1,41 @@
 package models
 
 import (
+	"blog/utils"
 	"encoding/json"
 	"strings"
 	"time"
 
+	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v5"
 	"github.com/gobuffalo/validate/v3"
 	"github.com/gobuffalo/validate/v3/validators"
 	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// NoPassword - the stored password of accounts that never set one, like those created through
+// a provider. No hasher matches it, a password is set through the password reset.
+const NoPassword = "!"
+
 // User is used by pop to map your .model.Name.Proper.Pluralize.Underscore database table to your go code.
 type User struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	Email     string    `json:"email" db:"email"`
-	Password  string    `json:"-" db:"password"`
-	Name      string    `json:"name" db:"name"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
-	BlogPosts Posts     `json:"posts" has_many:"posts"`
+	ID                      uuid.UUID    `json:"id" db:"id"`
+	Email                   string       `json:"email" db:"email"`
+	Password                string       `json:"-" db:"password"`
+	Name                    string       `json:"name" db:"name"`
+	Role                    string       `json:"role" db:"role"`
+	DeletionScheduledAt     nulls.Time   `json:"deletion_scheduled_at" db:"deletion_scheduled_at"`
+	DeletionPostsStrategy   string       `json:"-" db:"deletion_posts_strategy"`
+	CreatedAt               time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt               time.Time    `json:"updated_at" db:"updated_at"`
+	TokensRevokedAt         nulls.Time   `json:"-" db:"tokens_revoked_at"`
+	EmailVerifiedAt         nulls.Time   `json:"email_verified_at" db:"email_verified_at"`
+	EmailVerificationSentAt nulls.Time   `json:"-" db:"email_verification_sent_at"`
+	TOTPSecret              nulls.String `json:"-" db:"totp_secret"`
+	TOTPEnabledAt           nulls.Time   `json:"totp_enabled_at" db:"totp_enabled_at"`
+	TOTPLastStep            int64        `json:"-" db:"totp_last_step"`
+	BlogPosts               Posts        `json:"posts" has_many:"posts"`
 }
 
 // String is not required by pop and may be deleted
@@ -63,28 +77,215 @@ func (u *User) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
 }
 
 // ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
-// This method is not required and may be deleted.
 func (u *User) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
-	return validate.NewErrors(), nil
+	verrs := validate.Validate(
+		&validators.EmailIsPresent{Field: u.Email, Name: "email"},
+		&validators.StringLengthInRange{Field: u.Name, Name: "name", Min: 3, Max: 255},
+	)
+
+	taken, err := tx.Where("email = ? AND id != ?", u.Email, u.ID).Exists(&User{})
+	if err != nil {
+		return verrs, errors.WithStack(err)
+	}
+	if taken {
+		verrs.Add("email", "The email has been taken.")
+	}
+
+	return verrs, nil
+}
+
+// ChangeEmail - replace the email address, a new address has to be verified again
+func (u *User) ChangeEmail(email string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == u.Email {
+		return false
+	}
+	u.Email = email
+	u.EmailVerifiedAt = nulls.Time{}
+
+	return true
 }
 
 // Create - create user with hashed password
 func (u *User) Create(tx *pop.Connection) (*validate.Errors, error) {
 	u.Email = strings.ToLower(u.Email)
+	if u.Role == "" {
+		u.Role = RoleAuthor
+	}
 	// check email is exist
 	verrs := validate.NewErrors()
 
-	hashedPassword, bcryptErr := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
-
-	// if the bcrypt has any error
-	if bcryptErr != nil {
+	// if the hashing has any error
+	if hashErr := u.hashPassword(u.Password); hashErr != nil {
 		verrs.Add("password", "There is a problem when performing the password hashing.")
 
-		return verrs, errors.WithStack(bcryptErr)
+		return verrs, errors.WithStack(hashErr)
 	}
-	u.Password = string(hashedPassword)
 
 	// create user
 
 	return verrs, tx.Create(u)
 }
+
+// UpdatePassword - hash and store a new password
+func (u *User) UpdatePassword(tx *pop.Connection, password string) error {
+	if err := u.hashPassword(password); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return tx.UpdateColumns(u, "password", "updated_at")
+}
+
+// hashPassword - replace the password with its hash of the preferred hasher
+func (u *User) hashPassword(password string) error {
+	hashedPassword, hashErr := utils.Passwords.Hash(password)
+	if hashErr != nil {
+		return hashErr
+	}
+	u.Password = hashedPassword
+
+	return nil
+}
+
+// CheckPassword - verify the password, a hash of an older algorithm or weaker parameters
+// is replaced so the user base moves to the preferred hasher on log in
+func (u *User) CheckPassword(tx *pop.Connection, password string) (bool, error) {
+	if !u.HasPassword() {
+		return false, nil
+	}
+	ok, needsRehash, err := utils.Passwords.Verify(u.Password, password)
+	if !ok || err != nil {
+		return false, err
+	}
+	if needsRehash {
+		return true, u.UpdatePassword(tx, password)
+	}
+
+	return true, nil
+}
+
+// HasPassword - the user set a password, accounts created through a provider have none
+func (u *User) HasPassword() bool {
+	return u.Password != "" && u.Password != NoPassword
+}
+
+// HasRole - the user was assigned the role
+func (u *User) HasRole(role string) bool {
+	return u.Role == role
+}
+
+// AssignRole - replace the role of the user
+func (u *User) AssignRole(tx *pop.Connection, role string) error {
+	if !IsValidRole(role) {
+		return errors.Errorf("unknown role %q", role)
+	}
+	u.Role = role
+
+	return tx.UpdateColumns(u, "role", "updated_at")
+}
+
+// RevokeAllTokens - invalidate every access token issued until now and revoke all refresh tokens
+// and personal access tokens
+func (u *User) RevokeAllTokens(tx *pop.Connection) error {
+	u.TokensRevokedAt = nulls.NewTime(time.Now())
+	if err := tx.UpdateColumns(u, "tokens_revoked_at", "updated_at"); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := RevokeUserPersonalAccessTokens(tx, u.ID); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return RevokeUserRefreshTokens(tx, u.ID)
+}
+
+// IssuedBeforeRevocation - the token was issued before the user revoked all tokens
+func (u *User) IssuedBeforeRevocation(issuedAt time.Time) bool {
+	if !u.TokensRevokedAt.Valid {
+		return false
+	}
+	// the revocation time is stored with second precision, a token from the same second counts as revoked
+	return !issuedAt.Truncate(time.Second).After(u.TokensRevokedAt.Time.Truncate(time.Second))
+}
+
+// IsEmailVerified - the user proved the ownership of the email address
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt.Valid
+}
+
+// MarkEmailVerified - record the email address as verified
+func (u *User) MarkEmailVerified(tx *pop.Connection) error {
+	if u.IsEmailVerified() {
+		return nil
+	}
+	u.EmailVerifiedAt = nulls.NewTime(time.Now())
+
+	return tx.UpdateColumns(u, "email_verified_at", "updated_at")
+}
+
+// MarkEmailVerificationSent - remember when the last verification mail went out
+func (u *User) MarkEmailVerificationSent(tx *pop.Connection) error {
+	u.EmailVerificationSentAt = nulls.NewTime(time.Now())
+
+	return tx.UpdateColumns(u, "email_verification_sent_at", "updated_at")
+}
+
+// EmailVerificationRetryIn - how long until another verification mail may be sent
+func (u *User) EmailVerificationRetryIn(interval time.Duration, now time.Time) time.Duration {
+	if !u.EmailVerificationSentAt.Valid {
+		return 0
+	}
+	retryIn := u.EmailVerificationSentAt.Time.Add(interval).Sub(now)
+	if retryIn < 0 {
+		return 0
+	}
+
+	return retryIn
+}
+
+// HasTOTP - the user completed the two factor enrollment
+func (u *User) HasTOTP() bool {
+	return u.TOTPEnabledAt.Valid && u.TOTPSecret.Valid
+}
+
+// StartTOTPEnrollment - store a new secret, it only protects the account once confirmed with a code
+func (u *User) StartTOTPEnrollment(tx *pop.Connection, secret string) error {
+	u.TOTPSecret = nulls.NewString(secret)
+	u.TOTPEnabledAt = nulls.Time{}
+	u.TOTPLastStep = 0
+
+	return tx.UpdateColumns(u, "totp_secret", "totp_enabled_at", "totp_last_step", "updated_at")
+}
+
+// VerifyTOTP - check a code against the stored secret, a code is only accepted once
+func (u *User) VerifyTOTP(tx *pop.Connection, code string, now time.Time) (bool, error) {
+	if !u.TOTPSecret.Valid {
+		return false, nil
+	}
+
+	step, ok := utils.ValidateTOTP(u.TOTPSecret.String, code, now)
+	if !ok || step <= u.TOTPLastStep {
+		return false, nil
+	}
+	u.TOTPLastStep = step
+
+	return true, tx.UpdateColumns(u, "totp_last_step", "updated_at")
+}
+
+// EnableTOTP - turn two factor authentication on after the first valid code
+func (u *User) EnableTOTP(tx *pop.Connection) error {
+	u.TOTPEnabledAt = nulls.NewTime(time.Now())
+
+	return tx.UpdateColumns(u, "totp_enabled_at", "updated_at")
+}
+
+// DisableTOTP - turn two factor authentication off and drop the recovery codes
+func (u *User) DisableTOTP(tx *pop.Connection) error {
+	u.TOTPSecret = nulls.String{}
+	u.TOTPEnabledAt = nulls.Time{}
+	u.TOTPLastStep = 0
+	if err := tx.UpdateColumns(u, "totp_secret", "totp_enabled_at", "totp_last_step", "updated_at"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return DeleteRecoveryCodes(tx, u.ID)
+}
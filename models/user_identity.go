@@ -0,0 +1,110 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// ErrIdentityEmailTaken - the provider did not verify an email that already belongs to an account,
+// linking it would let anyone claim the account by typing its email at the provider
+var ErrIdentityEmailTaken = errors.New("the email belongs to another account")
+
+// ErrIdentityEmailMissing - the provider did not share an email address
+var ErrIdentityEmailMissing = errors.New("the identity has no email address")
+
+// UserIdentity is used by pop to map your user_identities database table to your go code.
+// An identity is the account of the user at an external OpenID Connect provider.
+type UserIdentity struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	UserID    uuid.UUID `json:"-" db:"user_id"`
+	Provider  string    `json:"provider" db:"provider"`
+	Subject   string    `json:"subject" db:"subject"`
+	Email     string    `json:"email" db:"email"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (i UserIdentity) String() string {
+	ji, _ := json.Marshal(i)
+	return string(ji)
+}
+
+// UserIdentities is not required by pop and may be deleted
+type UserIdentities []UserIdentity
+
+// FindOrCreateUserForIdentity - the user linked to the provider subject. An unknown subject is
+// linked to the account with the same email when the provider verified it, otherwise a new
+// account without a usable password is created.
+func FindOrCreateUserForIdentity(tx *pop.Connection, provider string, subject string, email string, emailVerified bool, name string) (*User, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	user := &User{}
+
+	identity := &UserIdentity{}
+	if err := tx.Where("provider = ? AND subject = ?", provider, subject).First(identity); err == nil {
+		if err := tx.Find(user, identity.UserID); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if email != "" && identity.Email != email {
+			identity.Email = email
+			if err := tx.UpdateColumns(identity, "email", "updated_at"); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+
+		return user, nil
+	}
+
+	if email == "" {
+		return nil, ErrIdentityEmailMissing
+	}
+
+	exists, err := tx.Where("email = ?", email).Exists(user)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	switch {
+	case exists && !emailVerified:
+		return nil, ErrIdentityEmailTaken
+	case exists:
+		if err := tx.Where("email = ?", email).First(user); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err := user.MarkEmailVerified(tx); err != nil {
+			return nil, err
+		}
+	default:
+		if len(name) < 3 {
+			name = strings.SplitN(email, "@", 2)[0]
+		}
+		user = &User{
+			Email: email,
+			// log in only goes through the provider until the user resets a password
+			Password: NoPassword,
+			Name:     name,
+			Role:     RoleAuthor,
+		}
+		if emailVerified {
+			user.EmailVerifiedAt = nulls.NewTime(time.Now())
+		}
+		if err := tx.Create(user); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	identity = &UserIdentity{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  subject,
+		Email:    email,
+	}
+
+	return user, errors.WithStack(tx.Create(identity))
+}
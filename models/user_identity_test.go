@@ -0,0 +1,36 @@
+package models
+
+func (ms *ModelSuite) Test_FindOrCreateUserForIdentity_CreatesAndLinks() {
+	user, err := FindOrCreateUserForIdentity(DB, "company", "sub-1", "SSO@example.com", true, "SSO User")
+	ms.NoError(err)
+	ms.Equal("sso@example.com", user.Email)
+	ms.True(user.IsEmailVerified())
+	ms.Equal(RoleAuthor, user.Role)
+
+	again, err := FindOrCreateUserForIdentity(DB, "company", "sub-1", "sso@example.com", true, "SSO User")
+	ms.NoError(err)
+	ms.Equal(user.ID, again.ID)
+
+	// the account has no password, the marker never matches
+	ms.False(again.HasPassword())
+	ok, err := again.CheckPassword(DB, NoPassword)
+	ms.NoError(err)
+	ms.False(ok)
+}
+
+func (ms *ModelSuite) Test_FindOrCreateUserForIdentity_ExistingEmail() {
+	user := &User{Email: "linked@example.com", Password: "secret", Name: "Linked"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+
+	_, err = FindOrCreateUserForIdentity(DB, "company", "sub-2", "linked@example.com", false, "Linked")
+	ms.Equal(ErrIdentityEmailTaken, err)
+
+	linked, err := FindOrCreateUserForIdentity(DB, "company", "sub-2", "linked@example.com", true, "Linked")
+	ms.NoError(err)
+	ms.Equal(user.ID, linked.ID)
+
+	count, err := DB.Where("user_id = ?", user.ID).Count(&UserIdentity{})
+	ms.NoError(err)
+	ms.Equal(1, count)
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"blog/utils"
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// userSessionTouchInterval - last_seen_at is only written once per interval
+const userSessionTouchInterval = time.Minute
+
+// UserSession is used by pop to map your user_sessions database table to your go code.
+// Every log in starts a session that follows its refresh token family. Sessions granted
+// to a third party OAuth client remember the client and the scopes the user consented to.
+type UserSession struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	UserID        uuid.UUID  `json:"-" db:"user_id"`
+	FamilyID      uuid.UUID  `json:"-" db:"family_id"`
+	OAuthClientID nulls.UUID `json:"oauth_client_id" db:"oauth_client_id"`
+	Scopes        string     `json:"scopes" db:"scopes"`
+	UserAgent     string     `json:"user_agent" db:"user_agent"`
+	IPAddress     string     `json:"ip_address" db:"ip_address"`
+	LastSeenAt    time.Time  `json:"last_seen_at" db:"last_seen_at"`
+	RevokedAt     nulls.Time `json:"-" db:"revoked_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (s UserSession) String() string {
+	js, _ := json.Marshal(s)
+	return string(js)
+}
+
+// UserSessions is not required by pop and may be deleted
+type UserSessions []UserSession
+
+// CreateUserSession - record the device a refresh token family was issued to
+func CreateUserSession(tx *pop.Connection, userID uuid.UUID, familyID uuid.UUID, userAgent string, ipAddress string) (*UserSession, error) {
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+	session := &UserSession{
+		UserID:     userID,
+		FamilyID:   familyID,
+		UserAgent:  userAgent,
+		IPAddress:  ipAddress,
+		LastSeenAt: time.Now(),
+	}
+
+	return session, errors.WithStack(tx.Create(session))
+}
+
+// CreateOAuthSession - record the grant of a third party client, the session follows the
+// refresh token family issued to the client
+func CreateOAuthSession(tx *pop.Connection, userID uuid.UUID, familyID uuid.UUID, client *OAuthClient, scopes []string, ipAddress string) (*UserSession, error) {
+	session := &UserSession{
+		UserID:        userID,
+		FamilyID:      familyID,
+		OAuthClientID: nulls.NewUUID(client.ID),
+		Scopes:        utils.FormatScopes(scopes),
+		UserAgent:     client.Name,
+		IPAddress:     ipAddress,
+		LastSeenAt:    time.Now(),
+	}
+
+	return session, errors.WithStack(tx.Create(session))
+}
+
+// FindUserSessionByFamily - the session of a refresh token family
+func FindUserSessionByFamily(tx *pop.Connection, familyID uuid.UUID) (*UserSession, error) {
+	session := &UserSession{}
+	err := tx.Where("family_id = ?", familyID).First(session)
+
+	return session, err
+}
+
+// ActiveUserSessions - the sessions of the user that have not been revoked and whose
+// refresh tokens have not expired yet
+func ActiveUserSessions(tx *pop.Connection, userID uuid.UUID, now time.Time) (*UserSessions, error) {
+	sessions := &UserSessions{}
+	err := tx.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Where("family_id IN (SELECT family_id FROM refresh_tokens WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?)", userID, now).
+		Order("last_seen_at desc").
+		All(sessions)
+
+	return sessions, err
+}
+
+// IsOAuth - the session was granted to a third party client
+func (s *UserSession) IsOAuth() bool {
+	return s.OAuthClientID.Valid
+}
+
+// IsActive - the session has not been revoked
+func (s *UserSession) IsActive() bool {
+	return !s.RevokedAt.Valid
+}
+
+// Touch - record the activity of the session, throttled so every request does not write
+func (s *UserSession) Touch(tx *pop.Connection, now time.Time) error {
+	if now.Sub(s.LastSeenAt) < userSessionTouchInterval {
+		return nil
+	}
+	s.LastSeenAt = now
+
+	return tx.UpdateColumns(s, "last_seen_at")
+}
+
+// Revoke - end the session together with its refresh token family
+func (s *UserSession) Revoke(tx *pop.Connection) error {
+	s.RevokedAt = nulls.NewTime(time.Now())
+
+	return RevokeRefreshTokenFamily(tx, s.FamilyID)
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+func (ms *ModelSuite) Test_UserSession_Revoke() {
+	user := &User{Email: "session@example.com", Password: "secret", Name: "Session"}
+	_, err := user.Create(DB)
+	ms.NoError(err)
+
+	familyID := uuid.Must(uuid.NewV4())
+	_, _, err = IssueRefreshToken(DB, user.ID, familyID)
+	ms.NoError(err)
+	session, err := CreateUserSession(DB, user.ID, familyID, "test agent", "127.0.0.1")
+	ms.NoError(err)
+
+	active, err := ActiveUserSessions(DB, user.ID, time.Now())
+	ms.NoError(err)
+	ms.Len(*active, 1)
+
+	ms.NoError(session.Revoke(DB))
+	found, err := FindUserSessionByFamily(DB, familyID)
+	ms.NoError(err)
+	ms.False(found.IsActive())
+
+	active, err = ActiveUserSessions(DB, user.ID, time.Now())
+	ms.NoError(err)
+	ms.Len(*active, 0)
+}
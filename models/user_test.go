@@ -1,7 +1,32 @@
 package models
 
-import "testing"
+import (
+	"blog/utils"
+	"strings"
+	"testing"
+)
 
 func Test_User(t *testing.T) {
 	t.Fatal("This test needs to be implemented!")
 }
+
+func (ms *ModelSuite) Test_User_CheckPassword_Rehashes() {
+	legacyHash, err := utils.BcryptHasher{Cost: 4}.Hash("secret")
+	ms.NoError(err)
+
+	user := &User{Email: "rehash@example.com", Password: "secret", Name: "Rehash"}
+	_, err = user.Create(DB)
+	ms.NoError(err)
+	user.Password = legacyHash
+	ms.NoError(DB.UpdateColumns(user, "password"))
+
+	ok, err := user.CheckPassword(DB, "wrong")
+	ms.NoError(err)
+	ms.False(ok)
+	ms.Equal(legacyHash, user.Password)
+
+	ok, err = user.CheckPassword(DB, "secret")
+	ms.NoError(err)
+	ms.True(ok)
+	ms.True(strings.HasPrefix(user.Password, "$argon2id$"))
+}
@@ -0,0 +1,224 @@
+// Package oidctest runs a local OpenID Connect issuer to test the log in flow end to end.
+package oidctest
+
+import (
+	"blog/oidc"
+	"blog/utils"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go/v4"
+)
+
+// mockKeyID - kid of the signing key of the mock issuer
+const mockKeyID = "oidctest"
+
+// Identity - The account the next browser signs in with at the mock issuer
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// authorization - A pending authorization code
+type authorization struct {
+	identity      Identity
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+}
+
+// MockIssuer - Serves discovery, keys, an authorize endpoint that signs in Identity without
+// asking and a token endpoint that enforces PKCE
+type MockIssuer struct {
+	Server       *httptest.Server
+	ClientID     string
+	ClientSecret string
+	Identity     Identity
+
+	key            *rsa.PrivateKey
+	mutex          sync.Mutex
+	authorizations map[string]authorization
+}
+
+// NewMockIssuer - Start a mock issuer for the client, Close it when the test ends
+func NewMockIssuer(clientID, clientSecret string) *MockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	issuer := &MockIssuer{
+		ClientID:       clientID,
+		ClientSecret:   clientSecret,
+		key:            key,
+		authorizations: map[string]authorization{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", issuer.discovery)
+	mux.HandleFunc("/jwks", issuer.jwks)
+	mux.HandleFunc("/authorize", issuer.authorize)
+	mux.HandleFunc("/token", issuer.token)
+	issuer.Server = httptest.NewServer(mux)
+
+	return issuer
+}
+
+// URL - The issuer identifier
+func (m *MockIssuer) URL() string {
+	return m.Server.URL
+}
+
+// Close - Stop the server
+func (m *MockIssuer) Close() {
+	m.Server.Close()
+}
+
+// Provider - A provider configured against the mock issuer
+func (m *MockIssuer) Provider(name, redirectURL string) *oidc.Provider {
+	return &oidc.Provider{
+		Name:         name,
+		Issuer:       m.URL(),
+		ClientID:     m.ClientID,
+		ClientSecret: m.ClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"openid", "email", "profile"},
+	}
+}
+
+// SignIn - Follow an authorization URL like a browser would and return the callback URL
+// the issuer redirects to
+func (m *MockIssuer) SignIn(authorizationURL string) (*url.URL, error) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	response, err := client.Get(authorizationURL)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	return response.Location()
+}
+
+// SignIDToken - Sign an ID token with the key of the issuer
+func (m *MockIssuer) SignIDToken(claims *oidc.IDTokenClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = mockKeyID
+
+	return token.SignedString(m.key)
+}
+
+func (m *MockIssuer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, oidc.Discovery{
+		Issuer:                m.URL(),
+		AuthorizationEndpoint: m.URL() + "/authorize",
+		TokenEndpoint:         m.URL() + "/token",
+		JWKSURI:               m.URL() + "/jwks",
+	})
+}
+
+func (m *MockIssuer) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, utils.JWKSet{Keys: []utils.JWK{{
+		KeyType:   "RSA",
+		Use:       "sig",
+		Algorithm: "RS256",
+		KeyID:     mockKeyID,
+		N:         encodeBigInt(m.key.PublicKey.N),
+		E:         encodeBigInt(big.NewInt(int64(m.key.PublicKey.E))),
+	}}})
+}
+
+func (m *MockIssuer) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("client_id") != m.ClientID || query.Get("response_type") != "code" ||
+		query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	code, _ := utils.GenerateOpaqueToken()
+	m.mutex.Lock()
+	m.authorizations[code] = authorization{
+		identity:      m.Identity,
+		redirectURI:   query.Get("redirect_uri"),
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+	}
+	m.mutex.Unlock()
+
+	callback := redirectURI.Query()
+	callback.Set("code", code)
+	callback.Set("state", query.Get("state"))
+	redirectURI.RawQuery = callback.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (m *MockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	clientID, clientSecret, _ := r.BasicAuth()
+	if clientID != url.QueryEscape(m.ClientID) || clientSecret != url.QueryEscape(m.ClientSecret) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	m.mutex.Lock()
+	pending, ok := m.authorizations[r.PostForm.Get("code")]
+	delete(m.authorizations, r.PostForm.Get("code"))
+	m.mutex.Unlock()
+
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("redirect_uri") != pending.redirectURI ||
+		oidc.CodeChallenge(r.PostForm.Get("code_verifier")) != pending.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	idToken, err := m.SignIDToken(&oidc.IDTokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    m.URL(),
+			Subject:   pending.identity.Subject,
+			Audience:  jwt.ClaimStrings{m.ClientID},
+			ExpiresAt: jwt.At(now.Add(5 * time.Minute)),
+			IssuedAt:  jwt.At(now),
+		},
+		Nonce:         pending.nonce,
+		Email:         pending.identity.Email,
+		EmailVerified: pending.identity.EmailVerified,
+		Name:          pending.identity.Name,
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"access_token": "oidctest-access-token",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func encodeBigInt(value *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(value.Bytes())
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,297 @@
+package oidc
+
+import (
+	"blog/utils"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go/v4"
+	"github.com/gobuffalo/envy"
+	"github.com/pkg/errors"
+)
+
+// ErrUnknownProvider - the provider is not listed in OIDC_PROVIDERS
+var ErrUnknownProvider = errors.New("unknown OpenID Connect provider")
+
+// idTokenAlgorithms - signing algorithms accepted on ID tokens, never none or a shared secret
+var idTokenAlgorithms = []string{"RS256", "ES256", "EdDSA"}
+
+// Provider - An OpenID Connect identity provider the blog trusts as a relying party
+type Provider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	HTTPClient   *http.Client
+}
+
+// Discovery - The fields of the provider metadata document the log in flow needs
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// IDTokenClaims - The claims of an ID token the blog reads
+type IDTokenClaims struct {
+	jwt.StandardClaims
+	Nonce         string `json:"nonce"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+// tokenResponse - The token endpoint response, only the ID token is used
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// issuerMetadata - Discovery document and verification keys of an issuer
+type issuerMetadata struct {
+	discovery Discovery
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+// metadataTTL - how long discovery documents and keys are cached
+const metadataTTL = time.Hour
+
+var metadataCache = struct {
+	sync.Mutex
+	issuers map[string]*issuerMetadata
+}{issuers: map[string]*issuerMetadata{}}
+
+// ProviderNames - Names of the providers enabled with OIDC_PROVIDERS, comma separated
+func ProviderNames() []string {
+	names := []string{}
+	for _, name := range strings.Split(envy.Get("OIDC_PROVIDERS", ""), ",") {
+		if name = strings.TrimSpace(strings.ToLower(name)); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// ProviderFromEnv - Configure an enabled provider with OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID,
+// OIDC_<NAME>_CLIENT_SECRET, OIDC_<NAME>_REDIRECT_URL and OIDC_<NAME>_SCOPES
+func ProviderFromEnv(name string) (*Provider, error) {
+	name = strings.ToLower(name)
+	enabled := false
+	for _, providerName := range ProviderNames() {
+		if providerName == name {
+			enabled = true
+		}
+	}
+	if !enabled {
+		return nil, ErrUnknownProvider
+	}
+
+	prefix := "OIDC_" + strings.ToUpper(name) + "_"
+	provider := &Provider{
+		Name:         name,
+		Issuer:       strings.TrimSuffix(envy.Get(prefix+"ISSUER", ""), "/"),
+		ClientID:     envy.Get(prefix+"CLIENT_ID", ""),
+		ClientSecret: envy.Get(prefix+"CLIENT_SECRET", ""),
+		RedirectURL:  envy.Get(prefix+"REDIRECT_URL", ""),
+		Scopes:       strings.Fields(envy.Get(prefix+"SCOPES", "openid email profile")),
+	}
+	if provider.Issuer == "" || provider.ClientID == "" || provider.RedirectURL == "" {
+		return nil, errors.Errorf("OpenID Connect provider %s is missing its issuer, client id or redirect url", name)
+	}
+
+	return provider, nil
+}
+
+// NewCodeVerifier - Generate a PKCE code verifier (RFC 7636)
+func NewCodeVerifier() (string, error) {
+	return utils.GenerateOpaqueToken()
+}
+
+// CodeChallenge - Derive the S256 code challenge sent with the authorization request
+func CodeChallenge(verifier string) string {
+	return utils.PKCEChallenge(verifier)
+}
+
+// AuthorizationURL - Where to send the browser to sign in at the provider
+func (p *Provider) AuthorizationURL(state, nonce, codeChallenge string) (string, error) {
+	metadata, err := p.metadata(false)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.ClientID)
+	query.Set("redirect_uri", p.RedirectURL)
+	query.Set("scope", strings.Join(p.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(metadata.discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return metadata.discovery.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange - Redeem the authorization code at the token endpoint and verify the returned ID token
+func (p *Provider) Exchange(code, codeVerifier, nonce string) (*IDTokenClaims, error) {
+	metadata, err := p.metadata(false)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("client_id", p.ClientID)
+	form.Set("code_verifier", codeVerifier)
+
+	request, err := http.NewRequest(http.MethodPost, metadata.discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if p.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+
+	response, err := p.httpClient().Do(request)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer response.Body.Close()
+
+	tokens := &tokenResponse{}
+	if err := json.NewDecoder(response.Body).Decode(tokens); err != nil {
+		return nil, errors.Wrap(err, "unable to decode the token response")
+	}
+	if response.StatusCode != http.StatusOK || tokens.IDToken == "" {
+		return nil, errors.Errorf("token endpoint answered %d: %s %s", response.StatusCode, tokens.Error, tokens.ErrorDescription)
+	}
+
+	return p.VerifyIDToken(tokens.IDToken, nonce)
+}
+
+// VerifyIDToken - Check the signature, issuer, audience, expiry and nonce of an ID token
+func (p *Provider) VerifyIDToken(rawIDToken, nonce string) (*IDTokenClaims, error) {
+	claims := &IDTokenClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.verificationKey(kid)
+	}, jwt.WithValidMethods(idTokenAlgorithms), jwt.WithAudience(p.ClientID), jwt.WithIssuer(p.Issuer))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid ID token")
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("invalid ID token: missing subject")
+	}
+	if claims.ExpiresAt == nil {
+		return nil, errors.New("invalid ID token: missing expiry")
+	}
+	if nonce == "" || claims.Nonce != nonce {
+		return nil, errors.New("invalid ID token: nonce mismatch")
+	}
+
+	return claims, nil
+}
+
+// verificationKey - Look the kid up in the cached keys, refetch them once for a key rotation
+func (p *Provider) verificationKey(kid string) (interface{}, error) {
+	metadata, err := p.metadata(false)
+	if err != nil {
+		return nil, err
+	}
+	if key, ok := metadata.keys[kid]; ok {
+		return key, nil
+	}
+
+	if metadata, err = p.metadata(true); err != nil {
+		return nil, err
+	}
+	if key, ok := metadata.keys[kid]; ok {
+		return key, nil
+	}
+
+	return nil, errors.Errorf("unknown signing key %q", kid)
+}
+
+// metadata - Discovery document and keys of the issuer, fetched once per metadataTTL
+func (p *Provider) metadata(refresh bool) (*issuerMetadata, error) {
+	metadataCache.Lock()
+	defer metadataCache.Unlock()
+
+	cached, ok := metadataCache.issuers[p.Issuer]
+	if ok && !refresh && time.Since(cached.fetchedAt) < metadataTTL {
+		return cached, nil
+	}
+
+	discovery := Discovery{}
+	if err := p.getJSON(p.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	// the document must describe the configured issuer (OpenID Connect Discovery 4.3)
+	if strings.TrimSuffix(discovery.Issuer, "/") != p.Issuer {
+		return nil, errors.Errorf("discovery issuer %q does not match %q", discovery.Issuer, p.Issuer)
+	}
+
+	keySet := utils.JWKSet{}
+	if err := p.getJSON(discovery.JWKSURI, &keySet); err != nil {
+		return nil, err
+	}
+	keys := map[string]interface{}{}
+	for _, jwk := range keySet.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.PublicKey(); err == nil {
+			keys[jwk.KeyID] = key
+		}
+	}
+
+	cached = &issuerMetadata{discovery: discovery, keys: keys, fetchedAt: time.Now()}
+	metadataCache.issuers[p.Issuer] = cached
+
+	return cached, nil
+}
+
+func (p *Provider) getJSON(endpoint string, target interface{}) error {
+	response, err := p.httpClient().Get(endpoint)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(response.Body)
+		return errors.Errorf("GET %s answered %d: %s", endpoint, response.StatusCode, body)
+	}
+
+	return errors.Wrap(json.NewDecoder(response.Body).Decode(target), fmt.Sprintf("unable to decode %s", endpoint))
+}
+
+func (p *Provider) httpClient() *http.Client {
+	if p.HTTPClient != nil {
+		return p.HTTPClient
+	}
+
+	return &http.Client{Timeout: 10 * time.Second}
+}
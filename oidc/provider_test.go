@@ -0,0 +1,126 @@
+package oidc_test
+
+import (
+	"blog/oidc"
+	"blog/oidc/oidctest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go/v4"
+	"github.com/gobuffalo/envy"
+)
+
+func Test_Provider_AuthorizationCodeWithPKCE(t *testing.T) {
+	issuer := oidctest.NewMockIssuer("blog", "blog-secret")
+	defer issuer.Close()
+	issuer.Identity = oidctest.Identity{Subject: "42", Email: "sso@example.com", EmailVerified: true, Name: "SSO User"}
+	provider := issuer.Provider("company", "http://127.0.0.1:3000/callback")
+
+	verifier, _ := oidc.NewCodeVerifier()
+	authorizationURL, err := provider.AuthorizationURL("state-1", "nonce-1", oidc.CodeChallenge(verifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback, err := issuer.SignIn(authorizationURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if callback.Query().Get("state") != "state-1" {
+		t.Fatalf("unexpected state in %s", callback)
+	}
+	code := callback.Query().Get("code")
+
+	if _, err := provider.Exchange(code, "wrong-verifier", "nonce-1"); err == nil {
+		t.Fatal("expected the wrong code verifier to be rejected")
+	}
+
+	// the code was burnt by the failed exchange, sign in again
+	callback, _ = issuer.SignIn(authorizationURL)
+	claims, err := provider.Exchange(callback.Query().Get("code"), verifier, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "42" || claims.Email != "sso@example.com" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+}
+
+func Test_Provider_VerifyIDToken(t *testing.T) {
+	issuer := oidctest.NewMockIssuer("blog", "blog-secret")
+	defer issuer.Close()
+	provider := issuer.Provider("company", "http://127.0.0.1:3000/callback")
+
+	sign := func(mutate func(claims *oidc.IDTokenClaims)) string {
+		claims := &oidc.IDTokenClaims{
+			StandardClaims: jwt.StandardClaims{
+				Issuer:    issuer.URL(),
+				Subject:   "42",
+				Audience:  jwt.ClaimStrings{"blog"},
+				ExpiresAt: jwt.At(time.Now().Add(time.Minute)),
+			},
+			Nonce: "nonce-1",
+		}
+		mutate(claims)
+		token, err := issuer.SignIDToken(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	if _, err := provider.VerifyIDToken(sign(func(*oidc.IDTokenClaims) {}), "nonce-1"); err != nil {
+		t.Fatalf("expected a valid ID token, got %v", err)
+	}
+
+	cases := map[string]string{
+		"wrong nonce":    sign(func(*oidc.IDTokenClaims) {}),
+		"wrong audience": sign(func(c *oidc.IDTokenClaims) { c.Audience = jwt.ClaimStrings{"other"} }),
+		"wrong issuer":   sign(func(c *oidc.IDTokenClaims) { c.Issuer = "https://evil.example.com" }),
+		"expired":        sign(func(c *oidc.IDTokenClaims) { c.ExpiresAt = jwt.At(time.Now().Add(-time.Minute)) }),
+		"missing expiry": sign(func(c *oidc.IDTokenClaims) { c.ExpiresAt = nil }),
+	}
+	for name, token := range cases {
+		nonce := "nonce-1"
+		if name == "wrong nonce" {
+			nonce = "nonce-2"
+		}
+		if _, err := provider.VerifyIDToken(token, nonce); err == nil {
+			t.Errorf("%s: expected the ID token to be rejected", name)
+		}
+	}
+
+	// an unsigned token must never be accepted
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, &oidc.IDTokenClaims{
+		StandardClaims: jwt.StandardClaims{Issuer: issuer.URL(), Subject: "42", Audience: jwt.ClaimStrings{"blog"}, ExpiresAt: jwt.At(time.Now().Add(time.Minute))},
+		Nonce:          "nonce-1",
+	})
+	unsignedToken, _ := unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if _, err := provider.VerifyIDToken(unsignedToken, "nonce-1"); err == nil {
+		t.Error("expected the unsigned ID token to be rejected")
+	}
+}
+
+func Test_ProviderFromEnv(t *testing.T) {
+	envy.Temp(func() {
+		envy.Set("OIDC_PROVIDERS", "Company, other")
+		envy.Set("OIDC_COMPANY_ISSUER", "https://sso.example.com/")
+		envy.Set("OIDC_COMPANY_CLIENT_ID", "blog")
+		envy.Set("OIDC_COMPANY_REDIRECT_URL", "http://127.0.0.1:3000/callback")
+
+		provider, err := oidc.ProviderFromEnv("company")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if provider.Issuer != "https://sso.example.com" || strings.Join(provider.Scopes, " ") != "openid email profile" {
+			t.Fatalf("unexpected provider %+v", provider)
+		}
+
+		if _, err := oidc.ProviderFromEnv("other"); err == nil || err == oidc.ErrUnknownProvider {
+			t.Fatalf("expected a configuration error, got %v", err)
+		}
+		if _, err := oidc.ProviderFromEnv("missing"); err != oidc.ErrUnknownProvider {
+			t.Fatalf("expected ErrUnknownProvider, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package policies
+
+import (
+	"blog/models"
+)
+
+const (
+	// ActionRead - read a resource that is not public, like the audit trail
+	ActionRead = "read"
+	// ActionCreate - create a resource
+	ActionCreate = "create"
+	// ActionUpdate - update a resource
+	ActionUpdate = "update"
+	// ActionDelete - delete a resource
+	ActionDelete = "delete"
+	// ActionPublish - publish, schedule or unpublish a post
+	ActionPublish = "publish"
+	// ActionAssignRole - change the role of a user
+	ActionAssignRole = "assign_role"
+	// ActionUnlock - lift the log in lockout of a user
+	ActionUnlock = "unlock"
+)
+
+// Rule - grants the action on the resource to the user, a rule that does not apply returns false
+type Rule func(user models.User, action string, resource interface{}) bool
+
+// rules - the action is allowed as soon as one rule grants it
+var rules = []Rule{
+	adminRule,
+	postReaderRule,
+	postAuthorRule,
+	postOwnerRule,
+	postEditorRule,
+	taxonomyRule,
+}
+
+// Can - decide whether the user may perform the action on the resource
+func Can(user models.User, action string, resource interface{}) bool {
+	for _, rule := range rules {
+		if rule(user, action, resource) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// adminRule - admins may do everything
+func adminRule(user models.User, action string, resource interface{}) bool {
+	return user.HasRole(models.RoleAdmin)
+}
+
+// postReaderRule - published posts are read by everyone, the others by their author and the editors
+func postReaderRule(user models.User, action string, resource interface{}) bool {
+	post, isPost := resource.(*models.Post)
+	if !isPost || action != ActionRead {
+		return false
+	}
+
+	return post.IsPublished() || post.UserID == user.ID || user.HasRole(models.RoleEditor)
+}
+
+// postAuthorRule - everyone but readers may write posts
+func postAuthorRule(user models.User, action string, resource interface{}) bool {
+	_, isPost := resource.(*models.Post)
+
+	return isPost && action == ActionCreate && writesPosts(user)
+}
+
+// postOwnerRule - writers may update, publish and delete their own posts
+func postOwnerRule(user models.User, action string, resource interface{}) bool {
+	post, isPost := resource.(*models.Post)
+	if !isPost || (action != ActionUpdate && action != ActionPublish && action != ActionDelete) {
+		return false
+	}
+
+	return writesPosts(user) && post.UserID == user.ID
+}
+
+// postEditorRule - editors may update and publish the posts of everyone
+func postEditorRule(user models.User, action string, resource interface{}) bool {
+	_, isPost := resource.(*models.Post)
+
+	return isPost && (action == ActionUpdate || action == ActionPublish) && user.HasRole(models.RoleEditor)
+}
+
+// taxonomyRule - writers may create tags, editors manage the tags and the categories
+func taxonomyRule(user models.User, action string, resource interface{}) bool {
+	switch resource.(type) {
+	case *models.Tag:
+		if action == ActionCreate {
+			return writesPosts(user)
+		}
+		return (action == ActionUpdate || action == ActionDelete) && user.HasRole(models.RoleEditor)
+	case *models.Category:
+		return (action == ActionCreate || action == ActionUpdate || action == ActionDelete) && user.HasRole(models.RoleEditor)
+	}
+
+	return false
+}
+
+// writesPosts - the role is allowed to author posts
+func writesPosts(user models.User) bool {
+	return user.HasRole(models.RoleEditor) || user.HasRole(models.RoleAuthor)
+}
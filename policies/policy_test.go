@@ -0,0 +1,95 @@
+package policies
+
+import (
+	"blog/models"
+	"testing"
+
+	"github.com/gofrs/uuid"
+)
+
+func Test_Can_Posts(t *testing.T) {
+	owner := models.User{ID: uuid.Must(uuid.NewV4()), Role: models.RoleAuthor}
+	post := &models.Post{UserID: owner.ID}
+
+	cases := []struct {
+		name    string
+		user    models.User
+		action  string
+		allowed bool
+	}{
+		{"owner updates", owner, ActionUpdate, true},
+		{"owner deletes", owner, ActionDelete, true},
+		{"author updates others", models.User{ID: uuid.Must(uuid.NewV4()), Role: models.RoleAuthor}, ActionUpdate, false},
+		{"editor updates others", models.User{ID: uuid.Must(uuid.NewV4()), Role: models.RoleEditor}, ActionUpdate, true},
+		{"editor deletes others", models.User{ID: uuid.Must(uuid.NewV4()), Role: models.RoleEditor}, ActionDelete, false},
+		{"admin deletes others", models.User{ID: uuid.Must(uuid.NewV4()), Role: models.RoleAdmin}, ActionDelete, true},
+		{"reader creates", models.User{ID: uuid.Must(uuid.NewV4()), Role: models.RoleReader}, ActionCreate, false},
+		{"demoted owner updates", models.User{ID: owner.ID, Role: models.RoleReader}, ActionUpdate, false},
+		{"owner publishes", owner, ActionPublish, true},
+		{"author publishes others", models.User{ID: uuid.Must(uuid.NewV4()), Role: models.RoleAuthor}, ActionPublish, false},
+		{"editor publishes others", models.User{ID: uuid.Must(uuid.NewV4()), Role: models.RoleEditor}, ActionPublish, true},
+		{"owner reads draft", owner, ActionRead, true},
+		{"reader reads draft", models.User{ID: uuid.Must(uuid.NewV4()), Role: models.RoleReader}, ActionRead, false},
+		{"editor reads draft", models.User{ID: uuid.Must(uuid.NewV4()), Role: models.RoleEditor}, ActionRead, true},
+	}
+
+	for _, tc := range cases {
+		if allowed := Can(tc.user, tc.action, post); allowed != tc.allowed {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.allowed, allowed)
+		}
+	}
+}
+
+func Test_Can_ReadPublishedPost(t *testing.T) {
+	post := &models.Post{UserID: uuid.Must(uuid.NewV4()), Status: models.PostStatusPublished}
+
+	if !Can(models.User{ID: uuid.Must(uuid.NewV4()), Role: models.RoleReader}, ActionRead, post) {
+		t.Error("everyone must read published posts")
+	}
+}
+
+func Test_Can_Users(t *testing.T) {
+	target := &models.User{ID: uuid.Must(uuid.NewV4()), Role: models.RoleAuthor}
+
+	if Can(models.User{Role: models.RoleEditor}, ActionDelete, target) {
+		t.Error("editors must not delete users")
+	}
+	if !Can(models.User{Role: models.RoleAdmin}, ActionAssignRole, target) {
+		t.Error("admins must assign roles")
+	}
+	if Can(models.User{Role: models.RoleEditor}, ActionRead, &models.User{}) {
+		t.Error("editors must not list the users")
+	}
+	if !Can(models.User{Role: models.RoleAdmin}, ActionRead, &models.User{}) {
+		t.Error("admins must list the users")
+	}
+}
+
+func Test_Can_ReadAudit(t *testing.T) {
+	for _, role := range []string{models.RoleReader, models.RoleAuthor, models.RoleEditor} {
+		if Can(models.User{Role: role}, ActionRead, &models.AuditEvent{}) {
+			t.Errorf("%s must not read the audit trail", role)
+		}
+	}
+	if !Can(models.User{Role: models.RoleAdmin}, ActionRead, &models.AuditEvent{}) {
+		t.Error("admins must read the audit trail")
+	}
+}
+
+func Test_Can_Taxonomy(t *testing.T) {
+	author := models.User{ID: uuid.Must(uuid.NewV4()), Role: models.RoleAuthor}
+	editor := models.User{ID: uuid.Must(uuid.NewV4()), Role: models.RoleEditor}
+
+	if !Can(author, ActionCreate, &models.Tag{}) {
+		t.Error("authors must create tags")
+	}
+	if Can(author, ActionUpdate, &models.Tag{}) || Can(author, ActionCreate, &models.Category{}) {
+		t.Error("authors must not manage the taxonomy")
+	}
+	if !Can(editor, ActionUpdate, &models.Tag{}) || !Can(editor, ActionDelete, &models.Category{}) {
+		t.Error("editors must manage the taxonomy")
+	}
+	if Can(models.User{Role: models.RoleReader}, ActionCreate, &models.Tag{}) {
+		t.Error("readers must not create tags")
+	}
+}
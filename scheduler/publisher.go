@@ -0,0 +1,80 @@
+// Package scheduler runs the background jobs of the blog next to the web server.
+package scheduler
+
+import (
+	"blog/models"
+	"blog/utils"
+	"context"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+)
+
+// Store - Flips the scheduled posts whose time has come to published
+type Store interface {
+	// PublishDue - publish the posts scheduled at or before now, returns how many were published
+	PublishDue(now time.Time) (int, error)
+}
+
+// DatabaseStore - Store backed by the posts table
+type DatabaseStore struct {
+	DB *pop.Connection
+}
+
+// PublishDue - publish the due posts in one transaction
+func (s *DatabaseStore) PublishDue(now time.Time) (int, error) {
+	published := 0
+	err := s.DB.Transaction(func(tx *pop.Connection) error {
+		var publishErr error
+		published, publishErr = models.PublishDuePosts(tx, now)
+		return publishErr
+	})
+
+	return published, err
+}
+
+// Publisher - Publishes the scheduled posts every interval, the clock decides what is due
+type Publisher struct {
+	Store    Store
+	Clock    utils.Clock
+	Interval time.Duration
+	Logger   buffalo.Logger
+}
+
+// NewPublisherFromEnv - Configure the publisher with POST_SCHEDULER_INTERVAL, the ticks are
+// logged with the logger of the app
+func NewPublisherFromEnv(logger buffalo.Logger) *Publisher {
+	return &Publisher{
+		Store:    &DatabaseStore{DB: models.DB},
+		Clock:    utils.SystemClock,
+		Interval: utils.DurationFromEnv("POST_SCHEDULER_INTERVAL", time.Minute),
+		Logger:   logger,
+	}
+}
+
+// Tick - publish the posts due at the current time of the clock
+func (p *Publisher) Tick() (int, error) {
+	return p.Store.PublishDue(p.Clock.Now())
+}
+
+// Run - tick every interval until the context is done, a failed tick is logged and retried
+// on the next one
+func (p *Publisher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			published, err := p.Tick()
+			if err != nil {
+				p.Logger.Errorf("publishing the scheduled posts failed: %v", err)
+			} else if published > 0 {
+				p.Logger.Infof("published %d scheduled posts", published)
+			}
+		}
+	}
+}
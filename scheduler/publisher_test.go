@@ -0,0 +1,88 @@
+package scheduler
+
+import (
+	"blog/utils"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/logger"
+)
+
+// memoryStore - a post scheduled at publishAt that is published once
+type memoryStore struct {
+	mutex     sync.Mutex
+	publishAt time.Time
+	published bool
+	calls     []time.Time
+}
+
+func (s *memoryStore) PublishDue(now time.Time) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.calls = append(s.calls, now)
+	if s.published || now.Before(s.publishAt) {
+		return 0, nil
+	}
+	s.published = true
+
+	return 1, nil
+}
+
+func Test_Publisher_Tick(t *testing.T) {
+	publishAt := time.Date(2021, 5, 10, 9, 0, 0, 0, time.UTC)
+	store := &memoryStore{publishAt: publishAt}
+	publisher := &Publisher{Store: store, Clock: utils.FixedClock{At: publishAt.Add(-time.Second)}}
+
+	if published, _ := publisher.Tick(); published != 0 {
+		t.Fatalf("expected nothing due before %s, published %d", publishAt, published)
+	}
+
+	publisher.Clock = utils.FixedClock{At: publishAt}
+	if published, _ := publisher.Tick(); published != 1 {
+		t.Fatalf("expected the post to be published at %s, published %d", publishAt, published)
+	}
+	if published, _ := publisher.Tick(); published != 0 {
+		t.Fatalf("expected the post to be published once, published %d", published)
+	}
+}
+
+func Test_Publisher_Run(t *testing.T) {
+	now := time.Date(2021, 5, 10, 9, 0, 0, 0, time.UTC)
+	store := &memoryStore{publishAt: now}
+	publisher := &Publisher{Store: store, Clock: utils.FixedClock{At: now}, Interval: time.Millisecond, Logger: logger.New(logger.ErrorLevel)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		publisher.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.After(time.Second)
+	for {
+		store.mutex.Lock()
+		published := store.published
+		store.mutex.Unlock()
+		if published {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("expected the publisher to tick")
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected Run to stop with its context")
+	}
+	if !store.calls[0].Equal(now) {
+		t.Fatalf("expected the ticks to use the clock, got %s", store.calls[0])
+	}
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BreachedPasswordPrefixLength - Length of the SHA-1 prefix naming a bucket, the same
+// k-anonymity split as the Pwned Passwords range API
+const BreachedPasswordPrefixLength = 5
+
+// BreachedPasswords - Offline breached password list, one file per SHA-1 prefix holding
+// the remaining hash suffixes as SUFFIX:COUNT lines
+type BreachedPasswords struct {
+	Dir string
+}
+
+// BreachedPasswordHash - upper case hex SHA-1 of the password, split into bucket prefix and suffix
+func BreachedPasswordHash(password string) (string, string) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	return hash[:BreachedPasswordPrefixLength], hash[BreachedPasswordPrefixLength:]
+}
+
+// IsBreached - the password appears in the bucket of its hash prefix, a missing bucket means not breached
+func (b *BreachedPasswords) IsBreached(password string) (bool, error) {
+	prefix, suffix := BreachedPasswordHash(password)
+
+	bucket, err := os.Open(filepath.Join(b.Dir, prefix))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer bucket.Close()
+
+	scanner := bufio.NewScanner(bucket)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			line = line[:i]
+		}
+		if strings.EqualFold(line, suffix) {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_ClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8, 192.168.1.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		expected   string
+	}{
+		{"direct client", "203.0.113.7:4000", "", "203.0.113.7"},
+		{"untrusted peer can not forward", "203.0.113.7:4000", "198.51.100.1", "203.0.113.7"},
+		{"behind the proxy", "10.1.2.3:4000", "198.51.100.1", "198.51.100.1"},
+		{"spoofed entries are skipped", "10.1.2.3:4000", "1.1.1.1, 198.51.100.1, 192.168.1.5", "198.51.100.1"},
+		{"only proxies", "10.1.2.3:4000", "10.0.0.9", "10.1.2.3"},
+		{"garbage header", "10.1.2.3:4000", "not-an-ip", "10.1.2.3"},
+	}
+	for _, tc := range cases {
+		request := httptest.NewRequest("GET", "/", nil)
+		request.RemoteAddr = tc.remoteAddr
+		if tc.forwarded != "" {
+			request.Header.Set("X-Forwarded-For", tc.forwarded)
+		}
+		if ip := ClientIP(request, proxies); ip != tc.expected {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.expected, ip)
+		}
+	}
+}
+
+func Test_ParseTrustedProxies_Invalid(t *testing.T) {
+	if _, err := ParseTrustedProxies("10.0.0.0/8,proxy.local"); err == nil {
+		t.Fatal("expected an invalid entry to be rejected")
+	}
+}
@@ -0,0 +1,28 @@
+package utils
+
+import "time"
+
+// Clock - Source of the current time, tests replace it with a fixed clock
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+// Now - the wall clock time
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock - Clock backed by time.Now
+var SystemClock Clock = systemClock{}
+
+// FixedClock - Clock that always returns the same instant
+type FixedClock struct {
+	At time.Time
+}
+
+// Now - the fixed instant
+func (c FixedClock) Now() time.Time {
+	return c.At
+}
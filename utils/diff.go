@@ -0,0 +1,119 @@
+package utils
+
+import "strings"
+
+const (
+	// DiffEqual - the line is in both texts
+	DiffEqual = "equal"
+	// DiffInsert - the line was added to the new text
+	DiffInsert = "insert"
+	// DiffDelete - the line was removed from the old text
+	DiffDelete = "delete"
+)
+
+// diffMaxCells - the largest LCS table built for the lines that differ, about 8 MB of ints.
+// Bigger changes are shown as the old lines removed and the new ones added.
+const diffMaxCells = 1 << 20
+
+// DiffLine - A line of a line level diff
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// DiffLines - the line level diff turning before into after, built from the longest common
+// subsequence of lines once the common prefix and suffix are set aside
+func DiffLines(before string, after string) []DiffLine {
+	a, b := splitLines(before), splitLines(after)
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	diff := make([]DiffLine, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		diff = append(diff, DiffLine{Op: DiffEqual, Text: line})
+	}
+	diff = append(diff, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		diff = append(diff, DiffLine{Op: DiffEqual, Text: line})
+	}
+
+	return diff
+}
+
+// diffMiddle - walk the LCS table of the lines that differ, deletions come before insertions
+func diffMiddle(a []string, b []string) []DiffLine {
+	if (len(a)+1)*(len(b)+1) > diffMaxCells {
+		return replaceLines(a, b)
+	}
+
+	// lcs[i][j] - length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	diff := []DiffLine{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, DiffLine{Op: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, DiffLine{Op: DiffDelete, Text: a[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{Op: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, DiffLine{Op: DiffDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, DiffLine{Op: DiffInsert, Text: b[j]})
+	}
+
+	return diff
+}
+
+// replaceLines - every old line removed, then every new line added
+func replaceLines(a []string, b []string) []DiffLine {
+	diff := make([]DiffLine, 0, len(a)+len(b))
+	for _, line := range a {
+		diff = append(diff, DiffLine{Op: DiffDelete, Text: line})
+	}
+	for _, line := range b {
+		diff = append(diff, DiffLine{Op: DiffInsert, Text: line})
+	}
+
+	return diff
+}
+
+// splitLines - the lines of the text, an empty text has none
+func splitLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
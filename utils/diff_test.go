@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_DiffLines(t *testing.T) {
+	diff := DiffLines("title\nfirst\nsecond\nthird", "title\nfirst\n2nd\nthird\nfourth")
+
+	expected := []DiffLine{
+		{DiffEqual, "title"},
+		{DiffEqual, "first"},
+		{DiffDelete, "second"},
+		{DiffInsert, "2nd"},
+		{DiffEqual, "third"},
+		{DiffInsert, "fourth"},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Fatalf("unexpected diff %+v", diff)
+	}
+}
+
+func Test_DiffLines_EmptyTexts(t *testing.T) {
+	if diff := DiffLines("", ""); len(diff) != 0 {
+		t.Fatalf("expected no lines, got %+v", diff)
+	}
+
+	diff := DiffLines("", "a\r\nb")
+	if len(diff) != 2 || diff[0].Op != DiffInsert || diff[1].Text != "b" {
+		t.Fatalf("unexpected diff %+v", diff)
+	}
+}
+
+func Test_DiffLines_LargeChange(t *testing.T) {
+	before, after := make([]string, 2000), make([]string, 2000)
+	for i := range before {
+		before[i], after[i] = fmt.Sprintf("old %d", i), fmt.Sprintf("new %d", i)
+	}
+	after[1000] = before[1000]
+
+	// past the size of the LCS table the lines are replaced wholesale
+	diff := DiffLines("title\n"+strings.Join(before, "\n"), "title\n"+strings.Join(after, "\n"))
+	if len(diff) != 1+len(before)+len(after) {
+		t.Fatalf("expected %d lines, got %d", 1+len(before)+len(after), len(diff))
+	}
+	if diff[0].Op != DiffEqual || diff[1].Op != DiffDelete || diff[len(diff)-1] != (DiffLine{DiffInsert, "new 1999"}) {
+		t.Fatalf("unexpected diff around %+v", diff[:2])
+	}
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gobuffalo/envy"
+)
+
+// DurationFromEnv - Read a time.Duration from the environment, fallback when missing or invalid
+func DurationFromEnv(key string, fallback time.Duration) time.Duration {
+	value := envy.Get(key, "")
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return fallback
+	}
+
+	return duration
+}
+
+// IntFromEnv - Read an int from the environment, fallback when missing or invalid
+func IntFromEnv(key string, fallback int) int {
+	value, err := strconv.Atoi(envy.Get(key, ""))
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
@@ -1,11 +1,32 @@
 package utils
 
 import (
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"time"
 
+	"github.com/dgrijalva/jwt-go/v4"
 	"github.com/gobuffalo/envy"
+	"github.com/gofrs/uuid"
 )
 
+// JWTIssuer - Issuer claim of every token signed by the blog
+const JWTIssuer = "buffalo-cms.api.dev"
+
+// SigningKey - The key material of a JWT algorithm, identified by the kid header
+type SigningKey struct {
+	KeyID      string
+	Method     jwt.SigningMethod
+	PrivateKey interface{}
+	PublicKey  interface{}
+	RetireAt   *time.Time
+}
+
 // ReadJWTKey - Read the content of jwt sign key
 func ReadJWTKey() ([]byte, error) {
 	keyPath := envy.Get("JWT_KEY_PATH", "")
@@ -14,3 +35,205 @@ func ReadJWTKey() ([]byte, error) {
 
 	return content, error
 }
+
+// JWTAlgorithm - The signing algorithm, configurable with JWT_ALGORITHM (HS256, RS256, ES256 or EdDSA)
+func JWTAlgorithm() string {
+	return envy.Get("JWT_ALGORITHM", jwt.SigningMethodHS256.Alg())
+}
+
+// ParseSigningKey - Parse the key content for the given algorithm
+func ParseSigningKey(algorithm string, content []byte) (*SigningKey, error) {
+	switch algorithm {
+	case jwt.SigningMethodHS256.Alg():
+		return &SigningKey{Method: jwt.SigningMethodHS256, PrivateKey: content, PublicKey: content}, nil
+	case jwt.SigningMethodRS256.Alg():
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(content)
+		if err != nil {
+			return nil, err
+		}
+		return &SigningKey{Method: jwt.SigningMethodRS256, PrivateKey: privateKey, PublicKey: &privateKey.PublicKey}, nil
+	case jwt.SigningMethodES256.Alg():
+		privateKey, err := jwt.ParseECPrivateKeyFromPEM(content)
+		if err != nil {
+			return nil, err
+		}
+		if privateKey.Curve != elliptic.P256() {
+			return nil, errors.New("ES256 requires a P-256 private key")
+		}
+		return &SigningKey{Method: jwt.SigningMethodES256, PrivateKey: privateKey, PublicKey: &privateKey.PublicKey}, nil
+	case SigningMethodEd25519.Alg():
+		privateKey, err := parseEd25519PrivateKeyFromPEM(content)
+		if err != nil {
+			return nil, err
+		}
+		return &SigningKey{Method: SigningMethodEd25519, PrivateKey: privateKey, PublicKey: privateKey.Public()}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported JWT algorithm %q", algorithm)
+}
+
+// IsRetired - the grace period of a rotated out key is over
+func (k *SigningKey) IsRetired(now time.Time) bool {
+	return k.RetireAt != nil && !k.RetireAt.After(now)
+}
+
+// parseEd25519PrivateKeyFromPEM - Parse a PKCS8 encoded Ed25519 private key
+func parseEd25519PrivateKeyFromPEM(content []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("the Ed25519 key must be PEM encoded")
+	}
+
+	parsedKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, ok := parsedKey.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("the PEM key is not an Ed25519 private key")
+	}
+
+	return privateKey, nil
+}
+
+// AccessTokenTTL - Lifetime of an access token, configurable with JWT_ACCESS_TOKEN_TTL
+func AccessTokenTTL() time.Duration {
+	return DurationFromEnv("JWT_ACCESS_TOKEN_TTL", 15*time.Minute)
+}
+
+// RefreshTokenTTL - Lifetime of a refresh token, configurable with JWT_REFRESH_TOKEN_TTL
+func RefreshTokenTTL() time.Duration {
+	return DurationFromEnv("JWT_REFRESH_TOKEN_TTL", 720*time.Hour)
+}
+
+// AccessClaims - Claims of an access token, the granted scopes are space delimited and
+// the session ID ties the token to the log in it was issued for. Tokens issued to a third
+// party OAuth client carry its client ID.
+type AccessClaims struct {
+	jwt.StandardClaims
+	Scope     string `json:"scope,omitempty"`
+	SessionID string `json:"sid,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+}
+
+// Scopes - The scopes granted to the access token
+func (c *AccessClaims) Scopes() []string {
+	return ParseScopes(c.Scope)
+}
+
+// NewAccessToken - Sign a short lived access token for the given user ID, session and scopes,
+// every token carries its own random ID so it can be revoked on its own
+func NewAccessToken(userID string, sessionID string, scopes []string) (string, error) {
+	return NewClientAccessToken(userID, sessionID, "", scopes)
+}
+
+// NewClientAccessToken - Like NewAccessToken, for a token issued to an OAuth client
+func NewClientAccessToken(userID string, sessionID string, clientID string, scopes []string) (string, error) {
+	now := time.Now()
+	claims := &AccessClaims{
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: jwt.At(now.Add(AccessTokenTTL())),
+			IssuedAt:  jwt.At(now),
+			Issuer:    JWTIssuer,
+			ID:        uuid.Must(uuid.NewV4()).String(),
+			Subject:   userID,
+		},
+		Scope:     FormatScopes(scopes),
+		SessionID: sessionID,
+		ClientID:  clientID,
+	}
+
+	return SignToken(claims)
+}
+
+// SignToken - Sign the claims with the active key of the key ring and announce it in the kid header
+func SignToken(claims jwt.Claims) (string, error) {
+	ring, ringErr := CurrentKeyRing()
+	if ringErr != nil {
+		return "", ringErr
+	}
+
+	key := ring.SigningKey()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.KeyID
+
+	return token.SignedString(key.PrivateKey)
+}
+
+// ParseAccessToken - Verify the signature and expiry of an access token and return its claims,
+// the verification key is looked up by kid and only the algorithm of that key is accepted
+// so a token can not pick its own verification method
+func ParseAccessToken(tokenString string, ring *KeyRing) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, ring.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid access token")
+	}
+	if claims.ExpiresAt == nil || claims.IssuedAt == nil {
+		return nil, errors.New("access token without exp or iat claim")
+	}
+
+	return claims, nil
+}
+
+// keyFunc - Look up the verification key by kid, only the algorithm of that key is accepted
+func (r *KeyRing) keyFunc(token *jwt.Token) (interface{}, error) {
+	keyID, _ := token.Header["kid"].(string)
+	key, ok := r.VerificationKey(keyID, time.Now())
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", keyID)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("signing method %s is not allowed for key %q", token.Method.Alg(), keyID)
+	}
+
+	return key.PublicKey, nil
+}
+
+// PurposeClaims - Claims of a single purpose token, the audience names the purpose so
+// the token is never accepted where an access token is expected
+type PurposeClaims struct {
+	jwt.StandardClaims
+	Email string `json:"email,omitempty"`
+}
+
+// NewPurposeToken - Sign a short lived token that is only accepted for the given purpose
+func NewPurposeToken(purpose string, subject string, email string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := &PurposeClaims{
+		StandardClaims: jwt.StandardClaims{
+			Audience:  jwt.ClaimStrings{purpose},
+			ExpiresAt: jwt.At(now.Add(ttl)),
+			IssuedAt:  jwt.At(now),
+			Issuer:    JWTIssuer,
+			ID:        uuid.Must(uuid.NewV4()).String(),
+			Subject:   subject,
+		},
+		Email: email,
+	}
+
+	return SignToken(claims)
+}
+
+// ParsePurposeToken - Verify a token signed by NewPurposeToken for the expected purpose
+func ParsePurposeToken(tokenString string, purpose string) (*PurposeClaims, error) {
+	ring, ringErr := CurrentKeyRing()
+	if ringErr != nil {
+		return nil, ringErr
+	}
+
+	claims := &PurposeClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, ring.keyFunc, jwt.WithAudience(purpose))
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid || claims.ExpiresAt == nil {
+		return nil, errors.New("invalid purpose token")
+	}
+
+	return claims, nil
+}
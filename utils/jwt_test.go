@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go/v4"
+	"github.com/gobuffalo/envy"
+)
+
+func signTestToken(t *testing.T, key *SigningKey) string {
+	claims := &jwt.StandardClaims{Subject: "user", IssuedAt: jwt.Now(), ExpiresAt: jwt.At(time.Now().Add(time.Minute))}
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.KeyID
+
+	tokenString, err := token.SignedString(key.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tokenString
+}
+
+func newTestKeyRing(t *testing.T, algorithm string) (*KeyRingFile, *KeyRing) {
+	entry, err := GenerateKeyRingEntry(algorithm, time.Now())
+	if err != nil {
+		t.Fatalf("%s: %v", algorithm, err)
+	}
+	entry.Active = true
+
+	file := &KeyRingFile{Keys: []KeyRingEntry{entry}}
+	ring, err := file.KeyRing()
+	if err != nil {
+		t.Fatalf("%s: %v", algorithm, err)
+	}
+
+	return file, ring
+}
+
+func Test_SigningKey_RoundTrip(t *testing.T) {
+	for _, algorithm := range []string{"HS256", "RS256", "ES256", "EdDSA"} {
+		_, ring := newTestKeyRing(t, algorithm)
+
+		parsed, err := ParseAccessToken(signTestToken(t, ring.SigningKey()), ring)
+		if err != nil {
+			t.Fatalf("%s: %v", algorithm, err)
+		}
+		if parsed.Subject != "user" {
+			t.Fatalf("%s: unexpected subject %q", algorithm, parsed.Subject)
+		}
+
+		published := len(ring.JWKS(time.Now()).Keys) == 1
+		if published != (algorithm != "HS256") {
+			t.Fatalf("%s: unexpected JWK publication", algorithm)
+		}
+	}
+}
+
+func Test_ParseAccessToken_PinsAlgorithm(t *testing.T) {
+	_, ring := newTestKeyRing(t, "RS256")
+	rsaKey := ring.SigningKey()
+	// a HS256 token signed with the public key bytes must not pass as RS256
+	publicDER, _ := x509.MarshalPKIXPublicKey(rsaKey.PublicKey)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+	claims := &jwt.StandardClaims{Subject: "user", IssuedAt: jwt.Now(), ExpiresAt: jwt.At(time.Now().Add(time.Minute))}
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	forged.Header["kid"] = rsaKey.KeyID
+	forgedString, err := forged.SignedString(publicPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ParseAccessToken(forgedString, ring); err == nil {
+		t.Fatal("expected a token signed with another algorithm to be rejected")
+	}
+}
+
+func Test_KeyRingFile_Rotate(t *testing.T) {
+	file, ring := newTestKeyRing(t, "ES256")
+	oldToken := signTestToken(t, ring.SigningKey())
+
+	now := time.Now()
+	entry, err := GenerateKeyRingEntry("ES256", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Rotate(entry, time.Hour, now)
+
+	rotated, err := file.KeyRing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.SigningKey().KeyID != entry.KeyID {
+		t.Fatal("expected the new key to be promoted")
+	}
+	if _, err := ParseAccessToken(oldToken, rotated); err != nil {
+		t.Fatalf("expected the previous key to verify during the grace period: %v", err)
+	}
+	if len(rotated.JWKS(now.Add(2*time.Hour)).Keys) != 1 {
+		t.Fatal("expected the previous key to be retired after the grace period")
+	}
+
+	next, err := GenerateKeyRingEntry("ES256", now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Rotate(next, time.Hour, now.Add(2*time.Hour))
+	if len(file.Keys) != 2 {
+		t.Fatalf("expected retired keys to be dropped, got %d keys", len(file.Keys))
+	}
+}
+
+func Test_LegacyKeyRingEntry_FirstRotation(t *testing.T) {
+	keyFile, err := ioutil.TempFile("", "jwt-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(keyFile.Name())
+	if _, err := keyFile.WriteString("legacy-secret"); err != nil {
+		t.Fatal(err)
+	}
+	keyFile.Close()
+
+	envy.Temp(func() {
+		envy.Set("JWT_KEY_PATH", keyFile.Name())
+		envy.Set("JWT_ALGORITHM", "HS256")
+
+		legacyRing, err := legacyKeyRing()
+		if err != nil {
+			t.Fatal(err)
+		}
+		oldToken := signTestToken(t, legacyRing.SigningKey())
+
+		now := time.Now()
+		legacy, err := LegacyKeyRingEntry(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry, err := GenerateKeyRingEntry("HS256", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := &KeyRingFile{Keys: []KeyRingEntry{legacy}}
+		file.Rotate(entry, time.Hour, now)
+
+		rotated, err := file.KeyRing()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rotated.SigningKey().KeyID != entry.KeyID {
+			t.Fatal("expected the new key to be promoted")
+		}
+		if _, err := ParseAccessToken(oldToken, rotated); err != nil {
+			t.Fatalf("expected tokens of the legacy key to verify after the first rotation: %v", err)
+		}
+	})
+}
@@ -0,0 +1,332 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go/v4"
+	"github.com/gobuffalo/envy"
+	"github.com/gofrs/uuid"
+)
+
+// KeyRingEntry - A key as stored in the key ring file, HS256 secrets are base64 encoded
+// while the asymmetric keys are stored as PEM private keys
+type KeyRingEntry struct {
+	KeyID     string     `json:"kid"`
+	Algorithm string     `json:"alg"`
+	Key       string     `json:"key"`
+	Active    bool       `json:"active"`
+	CreatedAt time.Time  `json:"created_at"`
+	RetireAt  *time.Time `json:"retire_at,omitempty"`
+}
+
+// KeyRingFile - The document behind JWT_KEYRING_PATH
+type KeyRingFile struct {
+	Keys []KeyRingEntry `json:"keys"`
+}
+
+// KeyRing - One active signing key and any number of verify-only keys, looked up by kid
+type KeyRing struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+var keyRingCache struct {
+	sync.RWMutex
+	source  string
+	modTime time.Time
+	ring    *KeyRing
+}
+
+// KeyRingPath - Location of the key ring file, configurable with JWT_KEYRING_PATH
+func KeyRingPath() string {
+	return envy.Get("JWT_KEYRING_PATH", "")
+}
+
+// KeyGracePeriod - How long a rotated out key keeps verifying tokens, configurable with JWT_KEY_GRACE_PERIOD
+func KeyGracePeriod() time.Duration {
+	return DurationFromEnv("JWT_KEY_GRACE_PERIOD", 24*time.Hour)
+}
+
+// CurrentKeyRing - Return the cached key ring, the ring is only read again when the file behind
+// JWT_KEYRING_PATH changes. Without a key ring the single key of JWT_KEY_PATH is used.
+func CurrentKeyRing() (*KeyRing, error) {
+	path := KeyRingPath()
+	source := "ring:" + path
+	if path == "" {
+		path = envy.Get("JWT_KEY_PATH", "")
+		source = "key:" + JWTAlgorithm() + ":" + path
+	}
+
+	info, statErr := os.Stat(path)
+	if statErr != nil {
+		return nil, statErr
+	}
+
+	keyRingCache.RLock()
+	if keyRingCache.ring != nil && keyRingCache.source == source && keyRingCache.modTime.Equal(info.ModTime()) {
+		ring := keyRingCache.ring
+		keyRingCache.RUnlock()
+		return ring, nil
+	}
+	keyRingCache.RUnlock()
+
+	var ring *KeyRing
+	var loadErr error
+	if KeyRingPath() == "" {
+		ring, loadErr = legacyKeyRing()
+	} else {
+		ring, loadErr = loadKeyRing(path)
+	}
+	if loadErr != nil {
+		return nil, loadErr
+	}
+
+	keyRingCache.Lock()
+	keyRingCache.source = source
+	keyRingCache.modTime = info.ModTime()
+	keyRingCache.ring = ring
+	keyRingCache.Unlock()
+
+	return ring, nil
+}
+
+// legacyKeyRing - Wrap the single JWT_KEY_PATH key into a ring
+func legacyKeyRing() (*KeyRing, error) {
+	content, readErr := ReadJWTKey()
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	key, parseErr := ParseSigningKey(JWTAlgorithm(), content)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	key.KeyID = legacyKeyID(content)
+
+	return &KeyRing{active: key, keys: map[string]*SigningKey{key.KeyID: key}}, nil
+}
+
+// legacyKeyID - derive a stable kid from the key so every process agrees on it
+func legacyKeyID(content []byte) string {
+	return HashToken(string(content))[:16]
+}
+
+// LegacyKeyRingEntry - The single JWT_KEY_PATH key as an active ring entry under the kid the
+// servers already sign with, so tokens issued before the first rotation keep verifying
+func LegacyKeyRingEntry(now time.Time) (KeyRingEntry, error) {
+	content, readErr := ReadJWTKey()
+	if readErr != nil {
+		return KeyRingEntry{}, readErr
+	}
+
+	entry := KeyRingEntry{
+		KeyID:     legacyKeyID(content),
+		Algorithm: JWTAlgorithm(),
+		Key:       string(content),
+		Active:    true,
+		CreatedAt: now,
+	}
+	if entry.Algorithm == jwt.SigningMethodHS256.Alg() {
+		entry.Key = base64.StdEncoding.EncodeToString(content)
+	}
+
+	return entry, nil
+}
+
+// loadKeyRing - Parse every key of the key ring file
+func loadKeyRing(path string) (*KeyRing, error) {
+	file, readErr := ReadKeyRingFile(path)
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	return file.KeyRing()
+}
+
+// KeyRing - Parse the entries into a usable ring
+func (f *KeyRingFile) KeyRing() (*KeyRing, error) {
+	ring := &KeyRing{keys: map[string]*SigningKey{}}
+
+	for _, entry := range f.Keys {
+		content := []byte(entry.Key)
+		if entry.Algorithm == jwt.SigningMethodHS256.Alg() {
+			secret, decodeErr := base64.StdEncoding.DecodeString(entry.Key)
+			if decodeErr != nil {
+				return nil, fmt.Errorf("key %s: %v", entry.KeyID, decodeErr)
+			}
+			content = secret
+		}
+
+		key, parseErr := ParseSigningKey(entry.Algorithm, content)
+		if parseErr != nil {
+			return nil, fmt.Errorf("key %s: %v", entry.KeyID, parseErr)
+		}
+		key.KeyID = entry.KeyID
+		key.RetireAt = entry.RetireAt
+
+		ring.keys[entry.KeyID] = key
+		if entry.Active {
+			if ring.active != nil {
+				return nil, errors.New("the key ring has more than one active key")
+			}
+			ring.active = key
+		}
+	}
+
+	if ring.active == nil {
+		return nil, errors.New("the key ring has no active key")
+	}
+
+	return ring, nil
+}
+
+// SigningKey - The key new tokens are signed with
+func (r *KeyRing) SigningKey() *SigningKey {
+	return r.active
+}
+
+// VerificationKey - Find a key that has not been retired by its kid
+func (r *KeyRing) VerificationKey(keyID string, now time.Time) (*SigningKey, bool) {
+	key, ok := r.keys[keyID]
+	if !ok || key.IsRetired(now) {
+		return nil, false
+	}
+
+	return key, true
+}
+
+// JWKS - Publish the public half of every key that still verifies tokens
+func (r *KeyRing) JWKS(now time.Time) JWKSet {
+	keySet := JWKSet{Keys: []JWK{}}
+	for _, key := range r.keys {
+		if key.IsRetired(now) {
+			continue
+		}
+		if jwk, ok := key.JWK(); ok {
+			keySet.Keys = append(keySet.Keys, jwk)
+		}
+	}
+
+	return keySet
+}
+
+// ReadKeyRingFile - Read the key ring file, a missing file reads as an empty ring
+func ReadKeyRingFile(path string) (*KeyRingFile, error) {
+	file := &KeyRingFile{}
+	content, readErr := ioutil.ReadFile(path)
+	if os.IsNotExist(readErr) {
+		return file, nil
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	return file, json.Unmarshal(content, file)
+}
+
+// Write - Replace the key ring file atomically so running servers never read half a file
+func (f *KeyRingFile) Write(path string) error {
+	content, marshalErr := json.MarshalIndent(f, "", "  ")
+	if marshalErr != nil {
+		return marshalErr
+	}
+
+	tmpFile, tmpErr := ioutil.TempFile(filepath.Dir(path), ".keyring")
+	if tmpErr != nil {
+		return tmpErr
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(content); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), path)
+}
+
+// Rotate - Promote a new key, keep the previous active key for verification during the
+// grace period and drop keys whose grace period is over
+func (f *KeyRingFile) Rotate(entry KeyRingEntry, grace time.Duration, now time.Time) {
+	keys := []KeyRingEntry{}
+	for _, existing := range f.Keys {
+		if existing.RetireAt != nil && !existing.RetireAt.After(now) {
+			continue
+		}
+		if existing.Active {
+			retireAt := now.Add(grace)
+			existing.Active = false
+			existing.RetireAt = &retireAt
+		}
+		keys = append(keys, existing)
+	}
+
+	entry.Active = true
+	f.Keys = append(keys, entry)
+}
+
+// GenerateKeyRingEntry - Generate a fresh key for the algorithm
+func GenerateKeyRingEntry(algorithm string, now time.Time) (KeyRingEntry, error) {
+	entry := KeyRingEntry{
+		KeyID:     uuid.Must(uuid.NewV4()).String(),
+		Algorithm: algorithm,
+		CreatedAt: now,
+	}
+
+	var der []byte
+	var blockType string
+	var err error
+
+	switch algorithm {
+	case jwt.SigningMethodHS256.Alg():
+		secret := make([]byte, 64)
+		if _, err = rand.Read(secret); err != nil {
+			return entry, err
+		}
+		entry.Key = base64.StdEncoding.EncodeToString(secret)
+		return entry, nil
+	case jwt.SigningMethodRS256.Alg():
+		var key *rsa.PrivateKey
+		if key, err = rsa.GenerateKey(rand.Reader, 2048); err == nil {
+			der, blockType = x509.MarshalPKCS1PrivateKey(key), "RSA PRIVATE KEY"
+		}
+	case jwt.SigningMethodES256.Alg():
+		var key *ecdsa.PrivateKey
+		if key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err == nil {
+			der, err = x509.MarshalECPrivateKey(key)
+			blockType = "EC PRIVATE KEY"
+		}
+	case SigningMethodEd25519.Alg():
+		var key ed25519.PrivateKey
+		if _, key, err = ed25519.GenerateKey(rand.Reader); err == nil {
+			der, err = x509.MarshalPKCS8PrivateKey(key)
+			blockType = "PRIVATE KEY"
+		}
+	default:
+		return entry, fmt.Errorf("unsupported JWT algorithm %q", algorithm)
+	}
+	if err != nil {
+		return entry, err
+	}
+
+	entry.Key = string(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}))
+	return entry, nil
+}
@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gobuffalo/envy"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordMaxBytes - Upper bound of a password in bytes, bcrypt ignores everything after the
+// 72nd byte so a longer password would only be checked in part
+const PasswordMaxBytes = 72
+
+// ErrUnknownPasswordHash - the encoded hash was not produced by a supported hasher
+var ErrUnknownPasswordHash = errors.New("unknown password hash format")
+
+// PasswordHasher - Hashes passwords into self describing strings that carry the
+// algorithm and its parameters, so older hashes keep verifying after a change
+type PasswordHasher interface {
+	// Hash - hash the password with a random salt
+	Hash(password string) (string, error)
+	// Verify - compare the password against a hash produced by this hasher
+	Verify(encoded string, password string) (bool, error)
+	// Handles - the encoded hash was produced by this hasher
+	Handles(encoded string) bool
+	// NeedsRehash - the hash uses weaker parameters than the hasher is configured with
+	NeedsRehash(encoded string) bool
+}
+
+// Argon2idHasher - argon2id encoded in the PHC string format, $argon2id$v=19$m=...,t=...,p=...$salt$key
+type Argon2idHasher struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// argon2idParams - the parameters and the material of an encoded argon2id hash
+type argon2idParams struct {
+	Argon2idHasher
+	salt []byte
+	key  []byte
+}
+
+// Hash - hash the password with argon2id
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify - recompute the key with the parameters stored in the hash
+func (h Argon2idHasher) Verify(encoded string, password string) (bool, error) {
+	params, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	key := argon2.IDKey([]byte(password), params.salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+
+	return subtle.ConstantTimeCompare(key, params.key) == 1, nil
+}
+
+// Handles - the hash starts with the argon2id identifier
+func (h Argon2idHasher) Handles(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+// NeedsRehash - any parameter of the hash is weaker than configured
+func (h Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+
+	return params.Memory < h.Memory ||
+		params.Iterations < h.Iterations ||
+		params.Parallelism < h.Parallelism ||
+		params.KeyLength < h.KeyLength ||
+		uint32(len(params.salt)) < h.SaltLength
+}
+
+// decodeArgon2id - parse the PHC string of an argon2id hash
+func decodeArgon2id(encoded string) (*argon2idParams, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, err
+	}
+	if version != argon2.Version {
+		return nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	params := &argon2idParams{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, err
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, err
+	}
+	params.SaltLength = uint32(len(params.salt))
+	params.KeyLength = uint32(len(params.key))
+
+	return params, nil
+}
+
+// BcryptHasher - bcrypt with a configurable cost, the modular crypt format carries the cost
+type BcryptHasher struct {
+	Cost int
+}
+
+// Hash - hash the password with bcrypt
+func (h BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+
+	return string(hashed), err
+}
+
+// Verify - compare the password against the bcrypt hash
+func (h BcryptHasher) Verify(encoded string, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// Handles - the hash starts with one of the bcrypt identifiers
+func (h BcryptHasher) Handles(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// NeedsRehash - the hash was produced with a lower cost than configured
+func (h BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+
+	return err != nil || cost < h.Cost
+}
+
+// PasswordHashers - The hasher new passwords are hashed with and every hasher that still verifies
+type PasswordHashers struct {
+	Preferred PasswordHasher
+	Hashers   []PasswordHasher
+}
+
+// NewPasswordHashersFromEnv - Configure the hashers with PASSWORD_HASHER (argon2id or bcrypt),
+// ARGON2_MEMORY (KiB), ARGON2_ITERATIONS, ARGON2_PARALLELISM and BCRYPT_COST
+func NewPasswordHashersFromEnv() *PasswordHashers {
+	argon2id := Argon2idHasher{
+		Memory:      uint32(IntFromEnv("ARGON2_MEMORY", 64*1024)),
+		Iterations:  uint32(IntFromEnv("ARGON2_ITERATIONS", 3)),
+		Parallelism: uint8(IntFromEnv("ARGON2_PARALLELISM", 2)),
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+	bcryptHasher := BcryptHasher{Cost: IntFromEnv("BCRYPT_COST", bcrypt.DefaultCost)}
+
+	hashers := &PasswordHashers{Preferred: argon2id, Hashers: []PasswordHasher{argon2id, bcryptHasher}}
+	if envy.Get("PASSWORD_HASHER", "argon2id") == "bcrypt" {
+		hashers.Preferred = bcryptHasher
+	}
+
+	return hashers
+}
+
+// Hash - hash the password with the preferred hasher
+func (p *PasswordHashers) Hash(password string) (string, error) {
+	return p.Preferred.Hash(password)
+}
+
+// Verify - check the password with the hasher that produced the hash, needsRehash reports
+// a hash that was produced by another algorithm or with weaker parameters
+func (p *PasswordHashers) Verify(encoded string, password string) (ok bool, needsRehash bool, err error) {
+	for _, hasher := range p.Hashers {
+		if !hasher.Handles(encoded) {
+			continue
+		}
+		if ok, err = hasher.Verify(encoded, password); !ok || err != nil {
+			return false, false, err
+		}
+
+		return true, !p.Preferred.Handles(encoded) || p.Preferred.NeedsRehash(encoded), nil
+	}
+
+	return false, false, ErrUnknownPasswordHash
+}
+
+// Passwords - The password hashers configured from the environment
+var Passwords = NewPasswordHashersFromEnv()
@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/validate/v3"
+)
+
+// PasswordPolicy - Rules every new password has to pass
+type PasswordPolicy struct {
+	MinLength  int
+	MaxBytes   int
+	MinEntropy float64
+	Breached   *BreachedPasswords
+}
+
+// NewPasswordPolicyFromEnv - Configure the policy with PASSWORD_MIN_LENGTH, PASSWORD_MIN_ENTROPY (bits)
+// and BREACHED_PASSWORDS_DIR, the breached password check is skipped without a directory
+func NewPasswordPolicyFromEnv() *PasswordPolicy {
+	policy := &PasswordPolicy{
+		MinLength:  IntFromEnv("PASSWORD_MIN_LENGTH", 8),
+		MaxBytes:   PasswordMaxBytes,
+		MinEntropy: float64(IntFromEnv("PASSWORD_MIN_ENTROPY", 40)),
+	}
+	if dir := envy.Get("BREACHED_PASSWORDS_DIR", ""); dir != "" {
+		policy.Breached = &BreachedPasswords{Dir: dir}
+	}
+
+	return policy
+}
+
+// CurrentPasswordPolicy - The password policy configured from the environment
+var CurrentPasswordPolicy = NewPasswordPolicyFromEnv()
+
+// Check - every rule the password breaks, personal lists values of the user such as the name and email
+func (p *PasswordPolicy) Check(password string, personal ...string) ([]string, error) {
+	problems := []string{}
+
+	if len([]rune(password)) < p.MinLength {
+		problems = append(problems, fmt.Sprintf("The password must be at least %d characters long.", p.MinLength))
+	} else if len(password) > p.MaxBytes {
+		problems = append(problems, fmt.Sprintf("The password must not be longer than %d bytes, accented letters and symbols count as several.", p.MaxBytes))
+	} else if PasswordEntropy(password) < p.MinEntropy {
+		problems = append(problems, "The password is too easy to guess, use a longer password or mix in more kinds of characters.")
+	}
+
+	lowered := strings.ToLower(password)
+	for _, value := range personalValues(personal) {
+		if strings.Contains(lowered, value) {
+			problems = append(problems, "The password must not contain your name or email address.")
+			break
+		}
+	}
+
+	if p.Breached != nil {
+		breached, err := p.Breached.IsBreached(password)
+		if err != nil {
+			return problems, err
+		}
+		if breached {
+			problems = append(problems, "The password appeared in a data breach, please choose another one.")
+		}
+	}
+
+	return problems, nil
+}
+
+// personalValues - lower cased values and email local parts that are long enough to matter
+func personalValues(personal []string) []string {
+	values := []string{}
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		candidates := []string{value}
+		if at := strings.IndexByte(value, '@'); at > 0 {
+			candidates = append(candidates, value[:at])
+		}
+		candidates = append(candidates, strings.Fields(value)...)
+
+		for _, candidate := range candidates {
+			if len(candidate) >= 3 {
+				values = append(values, candidate)
+			}
+		}
+	}
+
+	return values
+}
+
+// PasswordEntropy - Estimate of the entropy in bits from the length and the kinds of characters used
+func PasswordEntropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	for _, char := range password {
+		switch {
+		case char > unicode.MaxASCII:
+			other = true
+		case unicode.IsLower(char):
+			lower = true
+		case unicode.IsUpper(char):
+			upper = true
+		case unicode.IsDigit(char):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	pool := 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.used {
+			pool += class.size
+		}
+	}
+	if pool == 0 {
+		return 0
+	}
+
+	return float64(len([]rune(password))) * math.Log2(float64(pool))
+}
+
+// PasswordPolicyValidator - validate.Validator running the password policy on a field
+type PasswordPolicyValidator struct {
+	Name     string
+	Field    string
+	Personal []string
+	Policy   *PasswordPolicy
+}
+
+// IsValid - add every broken rule to the field of the validator
+func (v *PasswordPolicyValidator) IsValid(errors *validate.Errors) {
+	policy := v.Policy
+	if policy == nil {
+		policy = CurrentPasswordPolicy
+	}
+
+	problems, err := policy.Check(v.Field, v.Personal...)
+	for _, problem := range problems {
+		errors.Add(v.Name, problem)
+	}
+	if err != nil {
+		errors.Add(v.Name, "The password could not be checked, please try again later.")
+	}
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_PasswordPolicy_Check(t *testing.T) {
+	dir, err := ioutil.TempDir("", "breached")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	prefix, suffix := BreachedPasswordHash("Tr0ub4dor&3")
+	if err := ioutil.WriteFile(filepath.Join(dir, prefix), []byte("0000000000000000000000000000000000A:1\n"+suffix+":42\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := &PasswordPolicy{MinLength: 8, MaxBytes: PasswordMaxBytes, MinEntropy: 40, Breached: &BreachedPasswords{Dir: dir}}
+
+	cases := map[string]bool{
+		"short":                   false,
+		"aaaaaaaa":                false,
+		"correct horse battery":   true,
+		"Tr0ub4dor&3":             false,
+		"alice-loves-the-blog-42": false,
+		strings.Repeat("correct horse battery staple ", 3): false,
+		strings.Repeat("ü", 40):                            false,
+	}
+	for password, valid := range cases {
+		problems, err := policy.Check(password, "Alice Liddell", "alice@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (len(problems) == 0) != valid {
+			t.Errorf("%q: expected valid=%v, got %v", password, valid, problems)
+		}
+	}
+}
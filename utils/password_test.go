@@ -0,0 +1,46 @@
+package utils
+
+import "testing"
+
+var testArgon2id = Argon2idHasher{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func Test_PasswordHashers_Verify(t *testing.T) {
+	hashers := &PasswordHashers{Preferred: testArgon2id, Hashers: []PasswordHasher{testArgon2id, BcryptHasher{Cost: 4}}}
+
+	encoded, err := hashers.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, needsRehash, err := hashers.Verify(encoded, "correct horse battery staple")
+	if err != nil || !ok || needsRehash {
+		t.Fatalf("expected a fresh hash to verify without rehash, got ok=%v rehash=%v err=%v", ok, needsRehash, err)
+	}
+
+	if ok, _, _ = hashers.Verify(encoded, "wrong"); ok {
+		t.Fatal("expected a wrong password to be rejected")
+	}
+}
+
+func Test_PasswordHashers_NeedsRehash(t *testing.T) {
+	bcryptHash, err := BcryptHasher{Cost: 4}.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	weakArgon2id, err := Argon2idHasher{Memory: 512, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashers := &PasswordHashers{Preferred: testArgon2id, Hashers: []PasswordHasher{testArgon2id, BcryptHasher{Cost: 4}}}
+	for name, encoded := range map[string]string{"bcrypt": bcryptHash, "weak argon2id": weakArgon2id} {
+		ok, needsRehash, err := hashers.Verify(encoded, "secret")
+		if err != nil || !ok || !needsRehash {
+			t.Errorf("%s: expected a rehash, got ok=%v rehash=%v err=%v", name, ok, needsRehash, err)
+		}
+	}
+
+	if _, _, err := hashers.Verify("plain text", "secret"); err != ErrUnknownPasswordHash {
+		t.Errorf("expected an unknown hash error, got %v", err)
+	}
+}
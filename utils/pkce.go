@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+// PKCEChallenge - Derive the S256 code challenge of a PKCE code verifier (RFC 7636)
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// VerifyPKCE - the code verifier matches the S256 challenge sent with the authorization request
+func VerifyPKCE(challenge string, verifier string) bool {
+	// RFC 7636 4.1, verifiers are 43 to 128 characters long
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(PKCEChallenge(verifier)), []byte(challenge)) == 1
+}
@@ -0,0 +1,19 @@
+package utils
+
+import "testing"
+
+func Test_VerifyPKCE(t *testing.T) {
+	// RFC 7636 appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	if PKCEChallenge(verifier) != challenge {
+		t.Fatalf("unexpected challenge %s", PKCEChallenge(verifier))
+	}
+	if !VerifyPKCE(challenge, verifier) {
+		t.Fatal("expected the verifier to match")
+	}
+	if VerifyPKCE(challenge, "short") || VerifyPKCE(challenge, verifier+"x") {
+		t.Fatal("expected a wrong verifier to be rejected")
+	}
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// ScopePostsRead - read the posts
+	ScopePostsRead = "posts:read"
+	// ScopePostsWrite - create, update and delete the own posts
+	ScopePostsWrite = "posts:write"
+	// ScopeAccountAdmin - manage the account itself, its tokens and its second factor
+	ScopeAccountAdmin = "account:admin"
+)
+
+// AllScopes - Every scope, granted to tokens obtained by logging in with a password
+var AllScopes = []string{ScopePostsRead, ScopePostsWrite, ScopeAccountAdmin}
+
+// DelegableScopes - Scopes third party OAuth clients may ask for, managing the account stays
+// with the user
+var DelegableScopes = []string{ScopePostsRead, ScopePostsWrite}
+
+// ParseScopes - Split a space delimited scope string as used by the scope claim
+func ParseScopes(scope string) []string {
+	return strings.Fields(scope)
+}
+
+// FormatScopes - Join the scopes into a space delimited scope string
+func FormatScopes(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// HasScope - the scope is part of the granted scopes
+func HasScope(granted []string, scope string) bool {
+	for _, grantedScope := range granted {
+		if grantedScope == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateScopes - every scope must be known
+func ValidateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if !HasScope(AllScopes, scope) {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+
+	return nil
+}
+
+// ValidateDelegableScopes - every scope must be one a third party client may ask for
+func ValidateDelegableScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if !HasScope(DelegableScopes, scope) {
+			return fmt.Errorf("scope %q can not be delegated", scope)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,19 @@
+package utils
+
+import "testing"
+
+func Test_ValidateScopes(t *testing.T) {
+	if err := ValidateScopes(ParseScopes("posts:read  posts:write")); err != nil {
+		t.Fatalf("expected known scopes to validate: %v", err)
+	}
+	if err := ValidateScopes([]string{"posts:admin"}); err == nil {
+		t.Fatal("expected an unknown scope to be rejected")
+	}
+}
+
+func Test_AccessClaims_Scopes(t *testing.T) {
+	claims := &AccessClaims{Scope: FormatScopes([]string{ScopePostsRead, ScopeAccountAdmin})}
+	if !HasScope(claims.Scopes(), ScopeAccountAdmin) || HasScope(claims.Scopes(), ScopePostsWrite) {
+		t.Fatalf("unexpected scopes %v", claims.Scopes())
+	}
+}
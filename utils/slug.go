@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// SlugMaxLength - slugs are cut at a word boundary below this length
+const SlugMaxLength = 80
+
+// slugTransliterations - letters that do not decompose into a Latin base letter and a mark
+var slugTransliterations = map[rune]string{
+	// Latin
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'ł': "l", 'þ': "th", 'ı': "i", 'ŋ': "ng",
+	// Greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th", 'ι': "i", 'κ': "k",
+	'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t",
+	'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh", 'з': "z", 'и': "i",
+	'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t",
+	'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "",
+	'э': "e", 'ю': "yu", 'я': "ya", 'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g",
+}
+
+// Slugify - a lower case, hyphen separated ASCII slug of the text. Accents are stripped, Greek
+// and Cyrillic are transliterated and every other character separates words. The result is
+// empty when nothing could be transliterated.
+func Slugify(text string) string {
+	builder := strings.Builder{}
+	pendingHyphen := false
+	write := func(part string) {
+		if part == "" {
+			return
+		}
+		if pendingHyphen && builder.Len() > 0 {
+			builder.WriteByte('-')
+		}
+		pendingHyphen = false
+		builder.WriteString(part)
+	}
+
+	for _, char := range strings.ToLower(text) {
+		if transliteration, ok := slugTransliterations[char]; ok {
+			write(transliteration)
+			continue
+		}
+		if char == '\'' || char == '’' {
+			// apostrophes join the word, "don't" becomes "dont"
+			continue
+		}
+
+		// NFD splits é into e and a combining accent, the accent is then dropped
+		base := []rune(norm.NFD.String(string(char)))[0]
+		if transliteration, ok := slugTransliterations[base]; ok {
+			write(transliteration)
+		} else if base < unicode.MaxASCII && (unicode.IsLetter(base) || unicode.IsDigit(base)) {
+			write(string(base))
+		} else {
+			pendingHyphen = true
+		}
+	}
+
+	return truncateSlug(builder.String())
+}
+
+// truncateSlug - cut the slug at the last hyphen below SlugMaxLength
+func truncateSlug(slug string) string {
+	if len(slug) <= SlugMaxLength {
+		return slug
+	}
+
+	slug = slug[:SlugMaxLength]
+	if cut := strings.LastIndexByte(slug, '-'); cut > 0 {
+		slug = slug[:cut]
+	}
+
+	return strings.Trim(slug, "-")
+}
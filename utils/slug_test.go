@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_Slugify(t *testing.T) {
+	cases := map[string]string{
+		"Hello, World!":                 "hello-world",
+		"  Crème brûlée à la française": "creme-brulee-a-la-francaise",
+		"Straße & Smørrebrød":           "strasse-smorrebrod",
+		"Привет, мир":                   "privet-mir",
+		"Йошкар-Ола, ёлка":              "yoshkar-ola-yolka",
+		"Καλημέρα κόσμε":                "kalimera-kosme",
+		"Don't panic: Go 1.15":          "dont-panic-go-1-15",
+		"日本語":                           "",
+	}
+	for text, expected := range cases {
+		if slug := Slugify(text); slug != expected {
+			t.Errorf("Slugify(%q) = %q, expected %q", text, slug, expected)
+		}
+	}
+}
+
+func Test_Slugify_Truncates(t *testing.T) {
+	slug := Slugify(strings.Repeat("word ", 40))
+	if len(slug) > SlugMaxLength || strings.HasSuffix(slug, "-") || !strings.HasPrefix(slug, "word-word") {
+		t.Fatalf("unexpected slug %q", slug)
+	}
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret - the SHA1 seed of the RFC 6238 test vectors
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func Test_TOTPCode_RFC6238Vectors(t *testing.T) {
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unix, expected := range vectors {
+		code, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != expected {
+			t.Fatalf("at %d expected %s, got %s", unix, expected, code)
+		}
+	}
+}
+
+func Test_ValidateTOTP_FixedClock(t *testing.T) {
+	clock := FixedClock{At: time.Unix(1111111111, 0)}
+
+	step, ok := ValidateTOTP(rfc6238Secret, "050471", clock.Now())
+	if !ok || step != TOTPStep(clock.Now()) {
+		t.Fatal("expected the current code to be accepted")
+	}
+
+	// one period of clock drift is tolerated, two are not
+	if _, ok := ValidateTOTP(rfc6238Secret, "050471", clock.Now().Add(30*time.Second)); !ok {
+		t.Fatal("expected the previous code to be accepted")
+	}
+	if _, ok := ValidateTOTP(rfc6238Secret, "050471", clock.Now().Add(90*time.Second)); ok {
+		t.Fatal("expected an old code to be rejected")
+	}
+}
+
+func Test_TOTPURI(t *testing.T) {
+	uri := TOTPURI("Blog", "editor@example.com", "SECRET")
+	if !strings.HasPrefix(uri, "otpauth://totp/Blog:editor@example.com?") || !strings.Contains(uri, "secret=SECRET") {
+		t.Fatalf("unexpected uri %s", uri)
+	}
+}